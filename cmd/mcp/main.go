@@ -1,18 +1,54 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
 	"os"
+	"time"
 
+	"github.com/shghadge/todo_mcp/internal/dates"
 	"github.com/shghadge/todo_mcp/internal/mcp"
+	"github.com/shghadge/todo_mcp/internal/models"
 	"github.com/shghadge/todo_mcp/internal/storage"
 )
 
 func main() {
-	// Initialize file-based storage
-	todoStorage := storage.NewFileStorage("todos.json")
+	backend := flag.String("storage", storage.BackendFile, "storage backend: file, sqlite or wal")
+	path := flag.String("path", "", "path to the storage file (default todos.json, todos.db or todos.log)")
+	trashRetention := flag.Duration("trash-retention", 0, "permanently delete todos that have been in the trash this long (0 keeps them)")
+	timezone := flag.String("timezone", "", "IANA time zone dates like \"tomorrow 5pm\" are resolved in (default local)")
+	now := flag.String("now", "", "fixed RFC 3339 time to resolve relative dates against instead of the clock")
+	workflowPath := flag.String("workflow", "", "path to a JSON file defining the workflow states and transitions (default pending and completed)")
+	maxAttachmentSize := flag.Int64("max-attachment-size", storage.DefaultMaxBlobSize, "largest file in bytes that can be attached to a todo")
+	walArchive := flag.Bool("wal-archive", false, "keep the compacted segments of the wal backend's log as <path>.<first>-<last> instead of discarding them")
+	flag.Parse()
+
+	parser, err := dates.Configure(*timezone, *now)
+	if err != nil {
+		log.Fatalf("Invalid date settings: %v", err)
+	}
+
+	workflow, err := models.LoadWorkflow(*workflowPath)
+	if err != nil {
+		log.Fatalf("Invalid workflow: %v", err)
+	}
+
+	// Initialize storage
+	todoStorage, err := storage.Open(*backend, *path, workflow, storage.WALOptions{Archive: *walArchive})
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	if *trashRetention > 0 {
+		go storage.PurgeTrashPeriodically(context.Background(), todoStorage, *trashRetention, min(*trashRetention, time.Hour))
+	}
+
+	// Attachments are stored next to the todos
+	blobs := storage.OpenBlobs(*backend, *path, *maxAttachmentSize)
+	go storage.SweepBlobsPeriodically(context.Background(), todoStorage, blobs, time.Hour)
 
 	// Create MCP server
-	server := mcp.NewMCPServer(todoStorage)
+	server := mcp.NewMCPServer(todoStorage, workflow, blobs, parser)
 
 	// Process input/output via stdio
 	server.ProcessInput(os.Stdin, os.Stdout)
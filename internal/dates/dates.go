@@ -0,0 +1,363 @@
+// Package dates resolves the date expressions accepted by the REST API and
+// the MCP tools: RFC 3339 times, calendar dates and the relative and
+// natural-language phrases people and LLMs use, such as "tomorrow 5pm",
+// "next friday" or "in 3 days".
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+// Parser resolves date expressions against a reference clock in a time
+// zone. Expressions that name a day but no time of day resolve to the start
+// of that day with Parse and to its end with ParseDue.
+//
+// Accepted expressions, ignoring case:
+//   - RFC 3339 times, e.g. 2026-10-23T17:00:00+02:00
+//   - calendar dates with an optional time, e.g. 2026-10-23, 2026-10-23 17:00,
+//     oct 23, 23 october 2026
+//   - now, today, tonight, tomorrow, yesterday
+//   - a weekday, meaning its next occurrence after today; "this friday",
+//     which counts today; and "next friday", the friday of next week
+//   - next week, next month and next year, meaning their first day
+//   - end of day, end of week (Sunday), end of month and end of year
+//   - in 3 days, 2 weeks from now, 90 minutes ago, with the units minute,
+//     hour, day, week, month and year
+//
+// Any day expression, including "in 3 days" and the other relative ones
+// counting days or longer, can be followed by a time of day, optionally
+// after "at": 17:00, 5pm, 5:30pm, noon or midnight. A time on its own means
+// today at that time.
+type Parser struct {
+	// Location is the time zone days and times of day are resolved in
+	Location *time.Location
+
+	// Clock returns the reference time relative expressions count from
+	Clock func() time.Time
+}
+
+// New returns a Parser resolving in loc, counting from clock. A nil loc
+// means the local time zone and a nil clock means time.Now.
+func New(loc *time.Location, clock func() time.Time) *Parser {
+	if loc == nil {
+		loc = time.Local
+	}
+	if clock == nil {
+		clock = time.Now
+	}
+	return &Parser{Location: loc, Clock: clock}
+}
+
+// Configure returns a Parser for the -timezone and -now command-line
+// flags: an IANA time zone name, empty for the local one, and a fixed
+// RFC 3339 reference time, empty for the system clock
+func Configure(timezone, now string) (*Parser, error) {
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", timezone, err)
+		}
+	}
+
+	var clock func() time.Time
+	if now != "" {
+		fixed, err := time.Parse(time.RFC3339, now)
+		if err != nil {
+			return nil, fmt.Errorf("reference time must be an RFC 3339 time: %w", err)
+		}
+		clock = func() time.Time { return fixed }
+	}
+
+	return New(loc, clock), nil
+}
+
+// Now returns the reference time in the parser's time zone
+func (p *Parser) Now() time.Time {
+	return p.Clock().In(p.Location)
+}
+
+// Parse resolves expr, where a day without a time of day means its start
+func (p *Parser) Parse(expr string) (time.Time, error) {
+	return p.parse(expr, false)
+}
+
+// ParseDue resolves expr as a deadline, where a day without a time of day
+// means its last second, so that "due friday" lasts all of Friday
+func (p *Parser) ParseDue(expr string) (time.Time, error) {
+	return p.parse(expr, true)
+}
+
+var (
+	// timeSuffix splits a trailing time of day off an expression
+	timeSuffix = regexp.MustCompile(`^(?:(.*?)\s+)?(?:at\s+)?(\d{1,2}(?::\d{2})?\s*[ap]\.?m\.?|\d{1,2}:\d{2}|noon|midnight)$`)
+
+	// relative matches "in 3 days", "3 days from now" and "3 days ago"
+	relative = regexp.MustCompile(`^(?:in\s+(\d+|an?)\s+([a-z]+)|(\d+|an?)\s+([a-z]+)\s+(from now|ago))$`)
+)
+
+// calendarLayouts are the calendar date formats accepted, with and without
+// a year. Month names match regardless of case.
+var calendarLayouts = []struct {
+	layout  string
+	hasYear bool
+}{
+	{"2006-01-02", true},
+	{"2006/01/02", true},
+	{"Jan 2 2006", true},
+	{"Jan 2, 2006", true},
+	{"January 2 2006", true},
+	{"January 2, 2006", true},
+	{"2 Jan 2006", true},
+	{"2 January 2006", true},
+	{"Jan 2", false},
+	{"January 2", false},
+	{"2 Jan", false},
+	{"2 January", false},
+}
+
+func (p *Parser) parse(expr string, due bool) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return time.Time{}, fmt.Errorf("%w: empty expression", ErrInvalidDate)
+	}
+	if t, err := time.Parse(time.RFC3339, expr); err == nil {
+		return t, nil
+	}
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	now := p.Now()
+
+	if t, _, ok := p.parseRelative(normalized, now); ok {
+		return t, nil
+	}
+
+	// Local date-times such as 2026-10-23T17:00, lowercased like the rest
+	for _, layout := range []string{"2006-01-02t15:04:05", "2006-01-02t15:04"} {
+		if t, err := time.ParseInLocation(layout, normalized, p.Location); err == nil {
+			return t, nil
+		}
+	}
+
+	dayExpr, clock := normalized, ""
+	if m := timeSuffix.FindStringSubmatch(normalized); m != nil {
+		dayExpr, clock = strings.TrimSuffix(m[1], " at"), m[2]
+		if dayExpr == "at" {
+			dayExpr = ""
+		}
+	}
+
+	day, end, ok := startOfDay(now), false, true
+	if dayExpr != "" {
+		day, end, ok = p.parseDay(dayExpr, now)
+	}
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: can't understand %q", ErrInvalidDate, expr)
+	}
+
+	// Times of day are built from their wall-clock parts rather than added
+	// to midnight, so that they hold on days a DST change makes shorter or
+	// longer
+	if clock != "" {
+		hour, minute, ok := parseClock(clock)
+		if !ok {
+			return time.Time{}, fmt.Errorf("%w: can't understand the time of day in %q", ErrInvalidDate, expr)
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, p.Location), nil
+	}
+
+	switch {
+	case dayExpr == "now":
+		return now, nil
+	case dayExpr == "tonight":
+		return time.Date(day.Year(), day.Month(), day.Day(), 20, 0, 0, 0, p.Location), nil
+	case end || due:
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return day, nil
+}
+
+// parseDay resolves an expression naming a day to that day's start. end
+// reports whether the expression means the end of the day, as "end of
+// week" does.
+func (p *Parser) parseDay(expr string, now time.Time) (day time.Time, end bool, ok bool) {
+	today := startOfDay(now)
+
+	switch expr {
+	case "now", "today", "tonight":
+		return today, false, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), false, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), false, true
+	case "next week":
+		return startOfWeek(today).AddDate(0, 0, 7), false, true
+	case "next month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), false, true
+	case "next year":
+		return time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location()), false, true
+	case "end of day":
+		return today, true, true
+	case "end of week":
+		return startOfWeek(today).AddDate(0, 0, 6), true, true
+	case "end of month":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), true, true
+	case "end of year":
+		return time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, today.Location()), true, true
+	}
+
+	prefix, name, _ := strings.Cut(expr, " ")
+	if name == "" {
+		prefix, name = "", expr
+	}
+	if weekday, ok := parseWeekday(name); ok {
+		switch prefix {
+		case "":
+			ahead := (int(weekday) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 {
+				ahead = 7
+			}
+			return today.AddDate(0, 0, ahead), false, true
+		case "this":
+			return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), false, true
+		case "next":
+			// Weeks start on Monday, so Sunday is the week's last day
+			return startOfWeek(today).AddDate(0, 0, 7+(int(weekday)+6)%7), false, true
+		}
+	}
+
+	for _, l := range calendarLayouts {
+		t, err := time.ParseInLocation(l.layout, expr, p.Location)
+		if err != nil {
+			continue
+		}
+		if !l.hasYear {
+			// A date without a year is its next occurrence
+			t = time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.Location)
+			if t.Before(today) {
+				t = t.AddDate(1, 0, 0)
+			}
+		}
+		return t, false, true
+	}
+
+	// "in 3 days" names a day when a time of day follows it, as in "in 3
+	// days at 5pm". Minutes and hours don't: "in 2 hours at 5pm" is rejected.
+	if t, unit, ok := p.parseRelative(expr, now); ok && unit != "minute" && unit != "hour" {
+		return startOfDay(t), false, true
+	}
+
+	return time.Time{}, false, false
+}
+
+// parseRelative resolves "in 3 days", "3 days from now" and "3 days ago"
+// against now. unit is the singular unit counted, such as "day".
+func (p *Parser) parseRelative(expr string, now time.Time) (t time.Time, unit string, ok bool) {
+	m := relative.FindStringSubmatch(expr)
+	if m == nil {
+		return time.Time{}, "", false
+	}
+
+	count, unit, sign := m[1], m[2], 1
+	if count == "" {
+		count, unit = m[3], m[4]
+		if m[5] == "ago" {
+			sign = -1
+		}
+	}
+
+	n := 1
+	if count != "a" && count != "an" {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil {
+			return time.Time{}, "", false
+		}
+	}
+	n *= sign
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute", "min":
+		return now.Add(time.Duration(n) * time.Minute), "minute", true
+	case "hour", "hr":
+		return now.Add(time.Duration(n) * time.Hour), "hour", true
+	case "day":
+		return now.AddDate(0, 0, n), "day", true
+	case "week":
+		return now.AddDate(0, 0, 7*n), "week", true
+	case "month":
+		return now.AddDate(0, n, 0), "month", true
+	case "year":
+		return now.AddDate(n, 0, 0), "year", true
+	}
+	return time.Time{}, "", false
+}
+
+// parseClock converts a time of day to its hour and minute
+func parseClock(clock string) (hour, minute int, ok bool) {
+	switch clock {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	clock = strings.ReplaceAll(strings.ReplaceAll(clock, ".", ""), " ", "")
+	meridiem := ""
+	if strings.HasSuffix(clock, "am") || strings.HasSuffix(clock, "pm") {
+		meridiem = clock[len(clock)-2:]
+		clock = clock[:len(clock)-2]
+	}
+
+	hourStr, minuteStr, hasMinutes := strings.Cut(clock, ":")
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	if hasMinutes {
+		if minute, err = strconv.Atoi(minuteStr); err != nil || minute > 59 {
+			return 0, 0, false
+		}
+	}
+
+	switch meridiem {
+	case "":
+		if hour > 23 {
+			return 0, 0, false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, true
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday of t's week
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
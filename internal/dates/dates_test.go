@@ -0,0 +1,135 @@
+package dates
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// Wednesday 2026-10-14 15:30 in Berlin
+	now := time.Date(2026, 10, 14, 13, 30, 0, 0, time.UTC)
+	p := New(berlin, func() time.Time { return now })
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"2026-10-20T09:00:00Z", time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"2026-10-20T09:00", at(10, 20, 9, 0)},
+		{"2026-10-20", at(10, 20, 0, 0)},
+		{"2026-10-20 17:45", at(10, 20, 17, 45)},
+		{"Oct 20", at(10, 20, 0, 0)},
+		{"20 October 2027", time.Date(2027, 10, 20, 0, 0, 0, 0, berlin)},
+		{"jan 5", time.Date(2027, 1, 5, 0, 0, 0, 0, berlin)},
+		{"now", now.In(berlin)},
+		{"today", at(10, 14, 0, 0)},
+		{"tonight", at(10, 14, 20, 0)},
+		{"Tomorrow", at(10, 15, 0, 0)},
+		{"tomorrow at 5pm", at(10, 15, 17, 0)},
+		{"tomorrow 9:30am", at(10, 15, 9, 30)},
+		{"yesterday noon", at(10, 13, 12, 0)},
+		{"5pm", at(10, 14, 17, 0)},
+		{"at 08:15", at(10, 14, 8, 15)},
+		{"friday", at(10, 16, 0, 0)},
+		{"wednesday", at(10, 21, 0, 0)},
+		{"this wednesday", at(10, 14, 0, 0)},
+		{"next friday", at(10, 23, 0, 0)},
+		{"next sunday", at(10, 25, 0, 0)},
+		{"next week", at(10, 19, 0, 0)},
+		{"next month", at(11, 1, 0, 0)},
+		{"end of week", at(10, 18, 23, 59).Add(59 * time.Second)},
+		{"end of month", at(10, 31, 23, 59).Add(59 * time.Second)},
+		{"in 3 days", at(10, 17, 15, 30)},
+		{"in 3 days at 5pm", at(10, 17, 17, 0)},
+		{"2 weeks from now noon", at(10, 28, 12, 0)},
+		{"in an hour", at(10, 14, 16, 30)},
+		{"2 weeks from now", at(10, 28, 15, 30)},
+		{"90 minutes ago", at(10, 14, 14, 0)},
+	}
+
+	for _, tt := range tests {
+		got, err := p.Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// Clocks in New York spring forward from 2:00 to 3:00 on 2026-03-08
+	// and fall back from 2:00 to 1:00 on 2026-11-01
+	for _, tt := range []struct {
+		now  time.Time
+		expr string
+		want time.Time
+	}{
+		{time.Date(2026, 3, 7, 12, 0, 0, 0, newYork), "tomorrow at 5pm", time.Date(2026, 3, 8, 17, 0, 0, 0, newYork)},
+		{time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), "tonight", time.Date(2026, 3, 8, 20, 0, 0, 0, newYork)},
+		{time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), "9am", time.Date(2026, 3, 8, 9, 0, 0, 0, newYork)},
+		{time.Date(2026, 10, 29, 12, 0, 0, 0, newYork), "in 3 days at 5pm", time.Date(2026, 11, 1, 17, 0, 0, 0, newYork)},
+		{time.Date(2026, 11, 1, 12, 0, 0, 0, newYork), "tonight", time.Date(2026, 11, 1, 20, 0, 0, 0, newYork)},
+	} {
+		p := New(newYork, func() time.Time { return tt.now })
+		got, err := p.Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) on %s failed: %v", tt.expr, tt.now.Format(time.DateOnly), err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) on %s = %v, want %v", tt.expr, tt.now.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	p := New(time.UTC, func() time.Time { return now })
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"friday", time.Date(2026, 10, 16, 23, 59, 59, 0, time.UTC)},
+		{"2026-10-20", time.Date(2026, 10, 20, 23, 59, 59, 0, time.UTC)},
+		{"friday 9am", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)},
+		{"in 2 days", time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)},
+		{"now", now},
+	}
+
+	for _, tt := range tests {
+		got, err := p.ParseDue(tt.expr)
+		if err != nil {
+			t.Errorf("ParseDue(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDue(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := New(time.UTC, nil)
+
+	for _, expr := range []string{"", "someday", "next fortnight", "in 3 eons", "in 2 hours at 5pm", "tomorrow 25:00", "friday 13pm", "2026-13-40"} {
+		if got, err := p.Parse(expr); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("Parse(%q) = %v, %v, want ErrInvalidDate", expr, got, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead is the room left in upload requests for the multipart
+// headers and boundaries around the file
+const multipartOverhead = 1 << 20
+
+// GetAttachments handles GET /todos/{id}/attachments
+func (h *TodoHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	todo, ok := h.attachmentTodo(w, r)
+	if !ok {
+		return
+	}
+
+	attachments := todo.Attachments
+	if attachments == nil {
+		attachments = []models.Attachment{}
+	}
+	setETag(w, todo)
+	h.sendSuccessResponse(w, http.StatusOK, "Attachments retrieved successfully", attachments)
+}
+
+// UploadAttachment handles POST /todos/{id}/attachments
+//
+// The file is sent as the "file" part of a multipart/form-data body. Its
+// media type is detected from its content and file name.
+func (h *TodoHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	// Read the todo first so that no blob is stored for a missing one
+	todo, ok := h.attachmentTodo(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.blobs.MaxSize()+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid upload", err.Error())
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid upload", "A file part is required")
+			return
+		}
+		if err != nil {
+			h.sendUploadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		blob, err := h.blobs.Put(part, part.FileName())
+		if err != nil {
+			h.sendUploadError(w, err)
+			return
+		}
+
+		attachment := models.Attachment{
+			Name:      part.FileName(),
+			MediaType: blob.MediaType,
+			Size:      blob.Size,
+			SHA256:    blob.Hash,
+			Author:    storage.ActorFromContext(r.Context()),
+			CreatedAt: time.Now(),
+		}
+		h.changeAttachments(w, r, todo.ID, http.StatusCreated, "Attachment uploaded successfully", func(todo *models.Todo) (*models.Attachment, error) {
+			return todo.AddAttachment(attachment), nil
+		})
+		return
+	}
+}
+
+// sendUploadError responds to an upload that couldn't be read or stored
+func (h *TodoHandler) sendUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, storage.ErrBlobTooLarge), errors.As(err, &tooLarge):
+		h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "Attachment too large", fmt.Sprintf("Attachments are limited to %d bytes", h.blobs.MaxSize()))
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid upload", err.Error())
+	}
+}
+
+// DownloadAttachment handles GET /todos/{id}/attachments/{attachment_id}
+//
+// It sends the attachment's content, supporting conditional and range
+// requests.
+func (h *TodoHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	todo, ok := h.attachmentTodo(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := h.attachmentID(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, err := todo.Attachment(attachmentID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Attachment not found", "Attachment with given ID does not exist")
+		return
+	}
+	file, err := h.blobs.Open(attachment.SHA256)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			h.sendErrorResponse(w, http.StatusNotFound, "Attachment content not found", "The attachment's content was removed")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to read attachment", err.Error())
+		return
+	}
+	defer file.Close()
+
+	// The content never changes for a hash, which makes it a strong ETag
+	w.Header().Set("Content-Type", attachment.MediaType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	http.ServeContent(w, r, attachment.Name, attachment.CreatedAt, file)
+}
+
+// DeleteAttachment handles DELETE /todos/{id}/attachments/{attachment_id}
+//
+// The content is removed later, once no todo has it attached.
+func (h *TodoHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := h.attachmentID(w, r)
+	if !ok {
+		return
+	}
+
+	h.changeAttachments(w, r, id, http.StatusOK, "Attachment deleted successfully", func(todo *models.Todo) (*models.Attachment, error) {
+		if !todo.RemoveAttachment(attachmentID) {
+			return nil, models.ErrAttachmentNotFound
+		}
+		return nil, nil
+	})
+}
+
+// attachmentTodo reads the todo named by the request path, responding
+// with an error if there is none
+func (h *TodoHandler) attachmentTodo(w http.ResponseWriter, r *http.Request) (*models.Todo, bool) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	todo, err := h.storage.GetByID(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return nil, false
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+		return nil, false
+	}
+	return todo, true
+}
+
+// attachmentID reads the attachment ID from the request path, responding
+// with 400 if it isn't a number
+func (h *TodoHandler) attachmentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "Attachment ID must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+// changeAttachments applies change to the todo's attachments and responds
+// with the attachment it returns. The todo is read again and the change
+// retried if another writer got in between.
+func (h *TodoHandler) changeAttachments(w http.ResponseWriter, r *http.Request, id, status int, message string, change func(*models.Todo) (*models.Attachment, error)) {
+	for {
+		todo, err := h.storage.GetByID(r.Context(), id)
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+			return
+		}
+
+		attachment, err := change(todo)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusNotFound, "Attachment not found", "Attachment with given ID does not exist")
+			return
+		}
+
+		err = h.storage.Update(r.Context(), id, todo)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update todo", err.Error())
+			return
+		}
+
+		setETag(w, todo)
+		if attachment == nil {
+			h.sendSuccessResponse(w, status, message, nil)
+			return
+		}
+		h.sendSuccessResponse(w, status, message, attachment)
+		return
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// GetChecklist handles GET /todos/{id}/checklist
+func (h *TodoHandler) GetChecklist(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	todo, err := h.storage.GetByID(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+		return
+	}
+
+	setETag(w, todo)
+	h.sendSuccessResponse(w, http.StatusOK, "Checklist retrieved successfully", todo.ChecklistSummary())
+}
+
+// AddChecklistItem handles POST /todos/{id}/checklist
+//
+// The item is added at the given position, counting from 1, or at the end.
+func (h *TodoHandler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	h.changeChecklist(w, r, id, http.StatusCreated, "Checklist item added successfully", func(todo *models.Todo) error {
+		_, err := todo.AddChecklistItem(req.Text, req.Position)
+		return err
+	})
+}
+
+// UpdateChecklistItem handles PUT /todos/{id}/checklist/{item_id}
+//
+// The body may change the item's text, check or uncheck it, and move it to
+// another position, counting from 1.
+func (h *TodoHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := h.checklistItemID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	if req.Text == nil && req.Done == nil && req.Position == nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid checklist item", "text, done or position is required")
+		return
+	}
+
+	h.changeChecklist(w, r, id, http.StatusOK, "Checklist item updated successfully", func(todo *models.Todo) error {
+		if req.Text != nil {
+			if _, err := todo.EditChecklistItem(itemID, *req.Text); err != nil {
+				return err
+			}
+		}
+		if req.Done != nil {
+			if _, err := todo.CheckChecklistItem(itemID, *req.Done); err != nil {
+				return err
+			}
+		}
+		if req.Position != nil {
+			if _, err := todo.MoveChecklistItem(itemID, *req.Position); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteChecklistItem handles DELETE /todos/{id}/checklist/{item_id}
+func (h *TodoHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := h.checklistItemID(w, r)
+	if !ok {
+		return
+	}
+
+	h.changeChecklist(w, r, id, http.StatusOK, "Checklist item deleted successfully", func(todo *models.Todo) error {
+		return todo.RemoveChecklistItem(itemID)
+	})
+}
+
+// checklistItemID reads the checklist item ID from the request path,
+// responding with 400 if it isn't a number
+func (h *TodoHandler) checklistItemID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["item_id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "Checklist item ID must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+// changeChecklist applies change to the todo's checklist and responds with
+// the checklist. The todo is read again and the change retried if another
+// writer got in between.
+func (h *TodoHandler) changeChecklist(w http.ResponseWriter, r *http.Request, id, status int, message string, change func(*models.Todo) error) {
+	for {
+		todo, err := h.storage.GetByID(r.Context(), id)
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+			return
+		}
+
+		if err := change(todo); err != nil {
+			if errors.Is(err, models.ErrChecklistItemNotFound) {
+				h.sendErrorResponse(w, http.StatusNotFound, "Checklist item not found", "Checklist item with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid checklist item", err.Error())
+			return
+		}
+
+		err = h.storage.Update(r.Context(), id, todo)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update todo", err.Error())
+			return
+		}
+
+		setETag(w, todo)
+		h.sendSuccessResponse(w, status, message, todo.ChecklistSummary())
+		return
+	}
+}
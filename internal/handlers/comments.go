@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// GetComments handles GET /todos/{id}/comments
+//
+// It lists the todo's comments, oldest first, leaving out deleted ones.
+func (h *TodoHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	activity, err := h.storage.Activity(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve comments", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Comments retrieved successfully", activity.Thread())
+}
+
+// AddComment handles POST /todos/{id}/comments
+//
+// The comment's author is the client, as identified for the history.
+func (h *TodoHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	author := storage.ActorFromContext(r.Context())
+	h.changeComments(w, r, id, http.StatusCreated, "Comment added successfully", func(activity *models.Activity) (*models.Comment, error) {
+		return activity.AddComment(time.Now(), author, req.Body)
+	})
+}
+
+// UpdateComment handles PUT /todos/{id}/comments/{comment_id}
+func (h *TodoHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	commentID, ok := h.commentID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	h.changeComments(w, r, id, http.StatusOK, "Comment updated successfully", func(activity *models.Activity) (*models.Comment, error) {
+		return activity.EditComment(commentID, time.Now(), req.Body)
+	})
+}
+
+// DeleteComment handles DELETE /todos/{id}/comments/{comment_id}
+func (h *TodoHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	commentID, ok := h.commentID(w, r)
+	if !ok {
+		return
+	}
+
+	h.changeComments(w, r, id, http.StatusOK, "Comment deleted successfully", func(activity *models.Activity) (*models.Comment, error) {
+		return nil, activity.DeleteComment(commentID, time.Now())
+	})
+}
+
+// commentID reads the comment ID from the request path, responding with
+// 400 if it isn't a number
+func (h *TodoHandler) commentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["comment_id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "Comment ID must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+// changeComments applies change to the todo's comments and responds with
+// the comment it returns. Comments are stored apart from the todo, so the
+// change never conflicts with edits of the todo itself.
+func (h *TodoHandler) changeComments(w http.ResponseWriter, r *http.Request, id, status int, message string, change func(*models.Activity) (*models.Comment, error)) {
+	var comment *models.Comment
+	_, err := h.storage.ChangeActivity(r.Context(), id, func(activity *models.Activity) error {
+		var err error
+		comment, err = change(activity)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTodoNotFound):
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+		case errors.Is(err, models.ErrCommentNotFound):
+			h.sendErrorResponse(w, http.StatusNotFound, "Comment not found", "Comment with given ID does not exist")
+		case errors.Is(err, models.ErrEmptyComment):
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid comment", err.Error())
+		default:
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update comments", err.Error())
+		}
+		return
+	}
+
+	if comment == nil {
+		h.sendSuccessResponse(w, status, message, nil)
+		return
+	}
+	h.sendSuccessResponse(w, status, message, comment)
+}
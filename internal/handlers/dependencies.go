@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// AddBlocker handles POST /todos/{id}/blockers
+//
+// The body names the todo that blocks this one as blocker_id. Adding a
+// blocker that would make the todo block itself fails with 400.
+func (h *TodoHandler) AddBlocker(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	var req models.AddBlockerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	if req.BlockerID < 1 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", "blocker_id is required")
+		return
+	}
+
+	h.changeBlockers(w, r, id, "Blocker added successfully", func(todo *models.Todo) {
+		todo.AddBlocker(req.BlockerID)
+	})
+}
+
+// RemoveBlocker handles DELETE /todos/{id}/blockers/{blocker_id}
+func (h *TodoHandler) RemoveBlocker(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+	blockerID, err := strconv.Atoi(vars["blocker_id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "Blocker ID must be a number")
+		return
+	}
+
+	h.changeBlockers(w, r, id, "Blocker removed successfully", func(todo *models.Todo) {
+		todo.RemoveBlocker(blockerID)
+	})
+}
+
+// changeBlockers applies change to the todo's blockers and responds with
+// the updated todo. The todo is read again and the change retried if
+// another writer got in between.
+func (h *TodoHandler) changeBlockers(w http.ResponseWriter, r *http.Request, id int, message string, change func(*models.Todo)) {
+	for {
+		todo, err := h.storage.GetByID(r.Context(), id)
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+			return
+		}
+
+		change(todo)
+		err = h.storage.Update(r.Context(), id, todo)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			if err == storage.ErrTodoNotFound {
+				h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+				return
+			}
+			if errors.Is(err, storage.ErrBlockerNotFound) || errors.Is(err, storage.ErrDependencyCycle) {
+				h.sendErrorResponse(w, http.StatusBadRequest, "Invalid blockers", err.Error())
+				return
+			}
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update todo", err.Error())
+			return
+		}
+
+		setETag(w, todo)
+		h.sendSuccessResponse(w, http.StatusOK, message, todo)
+		return
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// actorMiddleware names the REST client in the request context so that
+// storage records it in the history of the todos it changes. Clients
+// identify themselves with an X-Actor header, falling back to their
+// User-Agent and then their address.
+//
+// Undo and redo are scoped to the X-Actor alone, or without one to the
+// client's host: a User-Agent is shared by every client built with the
+// same tool, which would then undo each other's changes.
+func actorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		actor := r.Header.Get("X-Actor")
+		session := actor
+		if actor == "" {
+			actor = r.UserAgent()
+			session = "host " + host
+		}
+		if actor == "" {
+			actor = r.RemoteAddr
+		}
+
+		ctx := storage.WithSession(storage.WithActor(r.Context(), actor), session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetTodoHistory handles GET /todos/{id}/history
+func (h *TodoHandler) GetTodoHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	events, err := h.storage.History(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve history", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "History retrieved successfully", events)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// GetProjects handles GET /projects
+//
+// Archived projects are only listed with archived=true.
+func (h *TodoHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	includeArchived, err := parseBoolParam(r, "archived")
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+
+	projects, err := h.storage.ListProjects(r.Context(), includeArchived)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve projects", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Projects retrieved successfully", projects)
+}
+
+// CreateProject handles POST /projects
+func (h *TodoHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	project := &models.Project{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := h.storage.CreateProject(r.Context(), project); err != nil {
+		h.sendProjectError(w, "Failed to create project", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusCreated, "Project created successfully", project)
+}
+
+// GetProject handles GET /projects/{id}
+func (h *TodoHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	project, err := h.storage.GetProject(r.Context(), id)
+	if err != nil {
+		h.sendProjectError(w, "Failed to retrieve project", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Project retrieved successfully", project)
+}
+
+// UpdateProject handles PUT /projects/{id}
+//
+// Setting archived to true hides the project and its todos from listings;
+// setting it back to false shows them again.
+func (h *TodoHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	existing, err := h.storage.GetProject(r.Context(), id)
+	if err != nil {
+		h.sendProjectError(w, "Failed to retrieve project", err)
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	// Update fields if provided
+	updated := *existing
+	if req.Name != nil {
+		updated.Name = *req.Name
+	}
+	if req.Description != nil {
+		updated.Description = *req.Description
+	}
+	if req.Archived != nil {
+		updated.Archived = *req.Archived
+	}
+
+	if err := h.storage.UpdateProject(r.Context(), id, &updated); err != nil {
+		h.sendProjectError(w, "Failed to update project", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Project updated successfully", &updated)
+}
+
+// DeleteProject handles DELETE /projects/{id}
+//
+// Only a project without todos, trash included, can be deleted; archive
+// it instead to keep its todos.
+func (h *TodoHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	if err := h.storage.DeleteProject(r.Context(), id); err != nil {
+		h.sendProjectError(w, "Failed to delete project", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Project deleted successfully", nil)
+}
+
+// GetProjectTodos handles GET /projects/{id}/todos
+//
+// It takes the query parameters of GET /todos, other than project, and
+// lists the project's todos even if it is archived.
+func (h *TodoHandler) GetProjectTodos(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	if _, err := h.storage.GetProject(r.Context(), id); err != nil {
+		h.sendProjectError(w, "Failed to retrieve project", err)
+		return
+	}
+
+	query, err := h.parseTodoQuery(r.URL.Query())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+	query.ProjectID = id
+
+	page, err := h.storage.Query(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) || errors.Is(err, storage.ErrInvalidCursor) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todos", err.Error())
+		return
+	}
+
+	h.sendListResponse(w, "Todos retrieved successfully", page)
+}
+
+// parseBoolParam reads an optional true or false query parameter
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New(name + " must be 'true' or 'false'")
+	}
+	return b, nil
+}
+
+// sendProjectError maps the errors of project operations to responses
+func (h *TodoHandler) sendProjectError(w http.ResponseWriter, title string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidProject):
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", "Name is required")
+	case errors.Is(err, storage.ErrProjectNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Project not found", "Project with given ID does not exist")
+	case errors.Is(err, storage.ErrProjectExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Project exists", err.Error())
+	case errors.Is(err, storage.ErrProjectNotEmpty):
+		h.sendErrorResponse(w, http.StatusConflict, "Project not empty", "Move or purge the project's todos first, or archive it instead")
+	case errors.Is(err, storage.ErrVersionConflict):
+		h.sendErrorResponse(w, http.StatusConflict, "Conflict", "Project was modified concurrently, please retry")
+	default:
+		h.sendErrorResponse(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
@@ -3,20 +3,25 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/shghadge/todo_mcp/internal/dates"
+	"github.com/shghadge/todo_mcp/internal/models"
 	"github.com/shghadge/todo_mcp/internal/storage"
 
 	"github.com/gorilla/mux"
 )
 
-// SetupRoutes sets up HTTP routes for the todo application
-func SetupRoutes(storage storage.TodoStorage) *mux.Router {
+// SetupRoutes sets up HTTP routes for the todo application over a storage
+// following workflow, keeping the content of attachments in blobs and
+// resolving the dates in requests with parser
+func SetupRoutes(storage storage.TodoStorage, workflow *models.Workflow, blobs *storage.BlobStore, parser *dates.Parser) *mux.Router {
 	router := mux.NewRouter()
 
 	// Create todo handler
-	todoHandler := NewTodoHandler(storage)
+	todoHandler := NewTodoHandler(storage, workflow, blobs, parser)
 
 	// API v1 routes
 	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(actorMiddleware)
 
 	// Todo routes
 	api.HandleFunc("/todos", todoHandler.CreateTodo).Methods("POST")
@@ -24,6 +29,56 @@ func SetupRoutes(storage storage.TodoStorage) *mux.Router {
 	api.HandleFunc("/todos/{id:[0-9]+}", todoHandler.GetTodo).Methods("GET")
 	api.HandleFunc("/todos/{id:[0-9]+}", todoHandler.UpdateTodo).Methods("PUT")
 	api.HandleFunc("/todos/{id:[0-9]+}", todoHandler.DeleteTodo).Methods("DELETE")
+	api.HandleFunc("/todos/{id:[0-9]+}/history", todoHandler.GetTodoHistory).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/children", todoHandler.GetTodoChildren).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/blockers", todoHandler.AddBlocker).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/blockers/{blocker_id:[0-9]+}", todoHandler.RemoveBlocker).Methods("DELETE")
+	api.HandleFunc("/todos/{id:[0-9]+}/timer/start", todoHandler.StartTimer).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/timer/stop", todoHandler.StopTimer).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/time", todoHandler.GetTodoTime).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/time", todoHandler.LogTime).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/time/{entry_id:[0-9]+}", todoHandler.DeleteTimeEntry).Methods("DELETE")
+	api.HandleFunc("/todos/{id:[0-9]+}/comments", todoHandler.GetComments).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/comments", todoHandler.AddComment).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/comments/{comment_id:[0-9]+}", todoHandler.UpdateComment).Methods("PUT")
+	api.HandleFunc("/todos/{id:[0-9]+}/comments/{comment_id:[0-9]+}", todoHandler.DeleteComment).Methods("DELETE")
+	api.HandleFunc("/todos/{id:[0-9]+}/attachments", todoHandler.GetAttachments).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/attachments", todoHandler.UploadAttachment).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/attachments/{attachment_id:[0-9]+}", todoHandler.DownloadAttachment).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/attachments/{attachment_id:[0-9]+}", todoHandler.DeleteAttachment).Methods("DELETE")
+	api.HandleFunc("/todos/{id:[0-9]+}/checklist", todoHandler.GetChecklist).Methods("GET")
+	api.HandleFunc("/todos/{id:[0-9]+}/checklist", todoHandler.AddChecklistItem).Methods("POST")
+	api.HandleFunc("/todos/{id:[0-9]+}/checklist/{item_id:[0-9]+}", todoHandler.UpdateChecklistItem).Methods("PUT")
+	api.HandleFunc("/todos/{id:[0-9]+}/checklist/{item_id:[0-9]+}", todoHandler.DeleteChecklistItem).Methods("DELETE")
+
+	// Tag routes
+	api.HandleFunc("/tags", todoHandler.GetTags).Methods("GET")
+	api.HandleFunc("/tags/rename", todoHandler.RenameTag).Methods("POST")
+	api.HandleFunc("/tags/merge", todoHandler.MergeTags).Methods("POST")
+
+	// Project routes
+	api.HandleFunc("/projects", todoHandler.CreateProject).Methods("POST")
+	api.HandleFunc("/projects", todoHandler.GetProjects).Methods("GET")
+	api.HandleFunc("/projects/{id:[0-9]+}", todoHandler.GetProject).Methods("GET")
+	api.HandleFunc("/projects/{id:[0-9]+}", todoHandler.UpdateProject).Methods("PUT")
+	api.HandleFunc("/projects/{id:[0-9]+}", todoHandler.DeleteProject).Methods("DELETE")
+	api.HandleFunc("/projects/{id:[0-9]+}/todos", todoHandler.GetProjectTodos).Methods("GET")
+
+	// Time tracking routes
+	api.HandleFunc("/time", todoHandler.GetTimeReport).Methods("GET")
+
+	// Workflow routes
+	api.HandleFunc("/workflow", todoHandler.GetWorkflow).Methods("GET")
+
+	// Undo routes
+	api.HandleFunc("/undo", todoHandler.Undo).Methods("POST")
+	api.HandleFunc("/redo", todoHandler.Redo).Methods("POST")
+
+	// Trash routes
+	api.HandleFunc("/trash", todoHandler.GetTrash).Methods("GET")
+	api.HandleFunc("/trash", todoHandler.EmptyTrash).Methods("DELETE")
+	api.HandleFunc("/trash/{id:[0-9]+}/restore", todoHandler.RestoreTodo).Methods("POST")
+	api.HandleFunc("/trash/{id:[0-9]+}", todoHandler.PurgeTodo).Methods("DELETE")
 
 	fmt.Println("API endpoints:")
 	PrintRoutes(router)
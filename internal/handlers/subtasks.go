@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTodoChildren handles GET /todos/{id}/children
+//
+// It lists the todo's direct subtasks, each with its progress; with
+// tree=true each comes with its own subtasks at any depth.
+func (h *TodoHandler) GetTodoChildren(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	tree, err := parseBoolParam(r, "tree")
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+
+	todo, err := h.storage.GetByID(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+		return
+	}
+
+	root, ok := h.buildTodoTree(w, r, todo)
+	if !ok {
+		return
+	}
+	if !tree {
+		root.Flatten()
+	}
+
+	children := root.Subtasks
+	if children == nil {
+		children = make([]*models.TodoTree, 0)
+	}
+	h.sendSuccessResponse(w, http.StatusOK, "Subtasks retrieved successfully", children)
+}
+
+// sendTodoTree responds with the todo and its subtasks at any depth
+func (h *TodoHandler) sendTodoTree(w http.ResponseWriter, r *http.Request, todo *models.Todo) {
+	root, ok := h.buildTodoTree(w, r, todo)
+	if !ok {
+		return
+	}
+
+	setETag(w, todo)
+	h.sendSuccessResponse(w, http.StatusOK, "Todo retrieved successfully", root)
+}
+
+// buildTodoTree arranges the todo's subtasks into a tree, sending an error
+// response and returning false if they can't be read
+func (h *TodoHandler) buildTodoTree(w http.ResponseWriter, r *http.Request, todo *models.Todo) (*models.TodoTree, bool) {
+	subtasks, err := h.storage.Subtasks(r.Context(), todo.ID)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return nil, false
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve subtasks", err.Error())
+		return nil, false
+	}
+	return models.BuildTree(h.workflow, todo, subtasks), true
+}
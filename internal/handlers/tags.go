@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// GetTags handles GET /tags
+func (h *TodoHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.storage.Tags(r.Context())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve tags", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Tags retrieved successfully", tags)
+}
+
+// RenameTag handles POST /tags/rename
+func (h *TodoHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	var req models.RenameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	n, err := h.storage.RenameTag(r.Context(), req.From, req.To)
+	if err != nil {
+		h.sendTagError(w, "Failed to rename tag", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, fmt.Sprintf("Tag renamed on %d todos", n), nil)
+}
+
+// MergeTags handles POST /tags/merge
+func (h *TodoHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	n, err := h.storage.MergeTags(r.Context(), req.From, req.Into)
+	if err != nil {
+		h.sendTagError(w, "Failed to merge tags", err)
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, fmt.Sprintf("Tags merged on %d todos", n), nil)
+}
+
+// sendTagError maps the errors of tag renames and merges to responses
+func (h *TodoHandler) sendTagError(w http.ResponseWriter, title string, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTag):
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tags", err.Error())
+	case errors.Is(err, storage.ErrTagNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Tag not found", err.Error())
+	case errors.Is(err, storage.ErrTagExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Tag exists", err.Error())
+	default:
+		h.sendErrorResponse(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// StartTimer handles POST /todos/{id}/timer/start
+//
+// The optional body gives the entry a note. A todo runs at most one timer,
+// so starting a second fails with 409.
+func (h *TodoHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.StartTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	actor := storage.ActorFromContext(r.Context())
+	h.changeTime(w, r, id, http.StatusCreated, "Timer started", func(activity *models.Activity) error {
+		_, err := activity.StartTimer(time.Now(), actor, req.Note)
+		return err
+	})
+}
+
+// StopTimer handles POST /todos/{id}/timer/stop
+func (h *TodoHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	h.changeTime(w, r, id, http.StatusOK, "Timer stopped", func(activity *models.Activity) error {
+		_, err := activity.StopTimer(time.Now())
+		return err
+	})
+}
+
+// LogTime handles POST /todos/{id}/time
+//
+// The body gives the work's start and end dates, or its length in minutes
+// and its end, which defaults to now.
+func (h *TodoHandler) LogTime(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LogTimeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	start, end, err := h.timeEntrySpan(req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid time entry", err.Error())
+		return
+	}
+
+	actor := storage.ActorFromContext(r.Context())
+	h.changeTime(w, r, id, http.StatusCreated, "Time logged", func(activity *models.Activity) error {
+		_, err := activity.LogTime(start, end, actor, req.Note)
+		return err
+	})
+}
+
+// timeEntrySpan resolves the start and end of a logged time entry
+func (h *TodoHandler) timeEntrySpan(req models.LogTimeRequest) (start, end time.Time, err error) {
+	end = time.Now()
+	if req.End != "" {
+		if end, err = h.dates.Parse(req.End); err != nil {
+			return start, end, errors.New("end: " + err.Error())
+		}
+	}
+
+	switch {
+	case req.Start != "" && req.Minutes != 0:
+		return start, end, errors.New("give either start or minutes, not both")
+	case req.Start != "":
+		if start, err = h.dates.Parse(req.Start); err != nil {
+			return start, end, errors.New("start: " + err.Error())
+		}
+	case req.Minutes > 0:
+		start = end.Add(-time.Duration(req.Minutes) * time.Minute)
+	default:
+		return start, end, errors.New("start or a positive number of minutes is required")
+	}
+	return start, end, nil
+}
+
+// DeleteTimeEntry handles DELETE /todos/{id}/time/{entry_id}
+func (h *TodoHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+	entryID, err := strconv.Atoi(mux.Vars(r)["entry_id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "Entry ID must be a number")
+		return
+	}
+
+	h.changeTime(w, r, id, http.StatusOK, "Time entry deleted", func(activity *models.Activity) error {
+		if !activity.RemoveTimeEntry(entryID) {
+			return models.ErrTimeEntryNotFound
+		}
+		return nil
+	})
+}
+
+// GetTodoTime handles GET /todos/{id}/time
+//
+// It lists the todo's time entries with the total time spent, running
+// timer included, against its estimate.
+func (h *TodoHandler) GetTodoTime(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.todoID(w, r)
+	if !ok {
+		return
+	}
+
+	todo, err := h.storage.GetByID(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo", err.Error())
+		return
+	}
+	activity, err := h.storage.Activity(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve time entries", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Time retrieved successfully", activity.TimeSummary(todo.EstimateMinutes, time.Now()))
+}
+
+// GetTimeReport handles GET /time
+//
+// It reports the time spent on each todo between the dates since and
+// before, which default to the start of this week and a week after since.
+func (h *TodoHandler) GetTimeReport(w http.ResponseWriter, r *http.Request) {
+	since := models.StartOfWeek(h.dates.Now())
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = h.dates.Parse(value); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", "since: "+err.Error())
+			return
+		}
+	}
+	before := since.AddDate(0, 0, 7)
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		if before, err = h.dates.Parse(value); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", "before: "+err.Error())
+			return
+		}
+	}
+
+	todos, err := h.storage.GetAll(r.Context())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todos", err.Error())
+		return
+	}
+	activities, err := h.storage.Activities(r.Context())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve time entries", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Time report retrieved successfully", models.BuildTimeReport(todos, activities, since, before, time.Now()))
+}
+
+// todoID reads the todo ID from the request path, responding with 400 if
+// it isn't a number
+func (h *TodoHandler) todoID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+// changeTime applies change to the todo's time entries and responds with
+// its time summary. Time entries are stored apart from the todo, so the
+// change never conflicts with edits of the todo itself.
+func (h *TodoHandler) changeTime(w http.ResponseWriter, r *http.Request, id, status int, message string, change func(*models.Activity) error) {
+	// The todo is only read for the estimate the summary compares with
+	todo, err := h.storage.GetByID(r.Context(), id)
+	if err != nil {
+		h.sendTimeError(w, err)
+		return
+	}
+	activity, err := h.storage.ChangeActivity(r.Context(), id, change)
+	if err != nil {
+		h.sendTimeError(w, err)
+		return
+	}
+
+	h.sendSuccessResponse(w, status, message, activity.TimeSummary(todo.EstimateMinutes, time.Now()))
+}
+
+// sendTimeError responds to a failed change of a todo's time entries
+func (h *TodoHandler) sendTimeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTodoNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+	case errors.Is(err, models.ErrTimerRunning), errors.Is(err, models.ErrTimerNotRunning):
+		h.sendErrorResponse(w, http.StatusConflict, "Timer conflict", err.Error())
+	case errors.Is(err, models.ErrTimeEntryNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Time entry not found", err.Error())
+	case errors.Is(err, models.ErrInvalidTimeEntry):
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid time entry", err.Error())
+	default:
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update time entries", err.Error())
+	}
+}
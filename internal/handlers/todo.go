@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/shghadge/todo_mcp/internal/dates"
 	"github.com/shghadge/todo_mcp/internal/models"
 	"github.com/shghadge/todo_mcp/internal/storage"
 
@@ -14,13 +19,25 @@ import (
 
 // TodoHandler handles HTTP requests for todo operations
 type TodoHandler struct {
-	storage storage.TodoStorage
+	storage  storage.TodoStorage
+	journal  *storage.Journal
+	workflow *models.Workflow
+	blobs    *storage.BlobStore
+	dates    *dates.Parser
 }
 
-// NewTodoHandler creates a new todo handler
-func NewTodoHandler(storage storage.TodoStorage) *TodoHandler {
+// NewTodoHandler creates a new todo handler. Changes made through it are
+// journaled so that each client can undo them, statuses are checked against
+// workflow, which must be the one todoStorage follows, attachments are kept
+// in blobs, and the dates it receives are resolved by parser.
+func NewTodoHandler(todoStorage storage.TodoStorage, workflow *models.Workflow, blobs *storage.BlobStore, parser *dates.Parser) *TodoHandler {
+	journal := storage.NewJournal(todoStorage)
 	return &TodoHandler{
-		storage: storage,
+		storage:  journal,
+		journal:  journal,
+		workflow: models.WorkflowOrDefault(workflow),
+		blobs:    blobs,
+		dates:    parser,
 	}
 }
 
@@ -36,6 +53,17 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// ListResponse represents a success response holding one page of todos
+type ListResponse struct {
+	Message    string         `json:"message"`
+	Data       []*models.Todo `json:"data"`
+	Count      int            `json:"count"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// priorityMessage explains a rejected priority
+const priorityMessage = "Priority must be 'none', 'low', 'medium', 'high' or 'urgent'"
+
 // CreateTodo handles POST /todos
 func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateTodoRequest
@@ -49,47 +77,282 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Priority == "" {
+		req.Priority = models.PriorityNone
+	}
+	if !req.Priority.Valid() {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid priority", priorityMessage)
+		return
+	}
+
+	startAt, err := h.parseScheduleTime("start_at", req.StartAt)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid date", err.Error())
+		return
+	}
+	dueAt, err := h.parseScheduleTime("due_at", req.DueAt)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid date", err.Error())
+		return
+	}
+
+	tags, err := models.NormalizeTags(req.Tags)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tags", err.Error())
+		return
+	}
+
 	todo := &models.Todo{
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      models.StatusPending,
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          h.workflow.Initial,
+		Priority:        req.Priority,
+		StartAt:         startAt,
+		DueAt:           dueAt,
+		Tags:            tags,
+		ProjectID:       req.ProjectID,
+		ParentID:        req.ParentID,
+		BlockedBy:       models.NormalizeBlockers(req.BlockedBy),
+		Recurrence:      req.Recurrence,
+		EstimateMinutes: req.EstimateMinutes,
+	}
+	if err := todo.CheckSchedule(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid schedule", err.Error())
+		return
+	}
+	if todo.EstimateMinutes < 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid estimate", "estimate_minutes must not be negative")
+		return
+	}
+	if todo.Recurrence != nil {
+		if err := todo.Recurrence.Validate(); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid recurrence", err.Error())
+			return
+		}
 	}
 
-	if err := h.storage.Create(todo); err != nil {
+	if err := h.storage.Create(r.Context(), todo); err != nil {
+		if errors.Is(err, storage.ErrProjectNotFound) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid project", err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrParentNotFound) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid parent", err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrBlockerNotFound) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid blockers", err.Error())
+			return
+		}
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create todo", err.Error())
 		return
 	}
 
+	setETag(w, todo)
 	h.sendSuccessResponse(w, http.StatusCreated, "Todo created successfully", todo)
 }
 
+// parseScheduleTime resolves the date expression of a start or due date
+// field, where the empty string means no date. A due date given as a day
+// lasts until the end of that day.
+func (h *TodoHandler) parseScheduleTime(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parse := h.dates.Parse
+	if field == "due_at" {
+		parse = h.dates.ParseDue
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+	return &parsed, nil
+}
+
 // GetTodos handles GET /todos
+//
+// Supported query parameters:
+//   - status: one or more comma-separated statuses
+//   - priority: one or more comma-separated priorities
+//   - tags_any, tags_all, tags_none: comma-separated tags the todos carry
+//     at least one, all or none of
+//   - project: a project ID, or none for the todos in no project
+//   - include_archived: true to list the todos of archived projects, which
+//     are hidden unless project names them
+//   - parent: a todo ID for its direct subtasks, or none for top-level todos
+//   - ready: true for the todos not completed whose blockers all are
+//   - title, description: case-insensitive substring of that field
+//   - q: case-insensitive substring of the title or description
+//   - created_since, created_before, updated_since, updated_before: dates
+//   - due_since, due_before: dates bounding the due date
+//   - due: overdue, today or this_week, the open todos due in that window
+//   - sort: id, title, status, created_at, updated_at, priority or due_at;
+//     order: asc or desc
+//   - limit, cursor: page size and the next_cursor of the previous page
+//
+// Dates are RFC 3339 times or expressions like "yesterday" or "in 3 days",
+// resolved by the handler's date parser.
 func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
-	status := r.URL.Query().Get("status")
-
-	var todos []*models.Todo
-	var err error
-
-	if status != "" {
-		todoStatus := models.TodoStatus(status)
-		if todoStatus != models.StatusPending && todoStatus != models.StatusCompleted {
-			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid status", "Status must be 'pending' or 'completed'")
-			return
-		}
-		todos, err = h.storage.GetByStatus(todoStatus)
-	} else {
-		todos, err = h.storage.GetAll()
+	query, err := h.parseTodoQuery(r.URL.Query())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
 	}
 
+	page, err := h.storage.Query(r.Context(), query)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) || errors.Is(err, storage.ErrInvalidCursor) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+			return
+		}
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todos", err.Error())
 		return
 	}
 
-	h.sendSuccessResponse(w, http.StatusOK, "Todos retrieved successfully", todos)
+	h.sendListResponse(w, "Todos retrieved successfully", page)
+}
+
+// parseTodoQuery builds a storage query from GET /todos query parameters,
+// checking statuses against the handler's workflow and resolving dates with
+// its parser
+func (h *TodoHandler) parseTodoQuery(values url.Values) (storage.TodoQuery, error) {
+	query := storage.TodoQuery{
+		Title:       values.Get("title"),
+		Description: values.Get("description"),
+		Text:        values.Get("q"),
+		SortBy:      values.Get("sort"),
+		Cursor:      values.Get("cursor"),
+	}
+
+	if status := values.Get("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			todoStatus := models.TodoStatus(strings.TrimSpace(s))
+			if !h.workflow.Valid(todoStatus) {
+				return query, errors.New(h.statusMessage())
+			}
+			query.Statuses = append(query.Statuses, todoStatus)
+		}
+	}
+
+	if priority := values.Get("priority"); priority != "" {
+		for _, p := range strings.Split(priority, ",") {
+			todoPriority := models.Priority(strings.TrimSpace(p))
+			if !todoPriority.Valid() {
+				return query, fmt.Errorf(priorityMessage)
+			}
+			query.Priorities = append(query.Priorities, todoPriority)
+		}
+	}
+
+	tagLists := []struct {
+		param string
+		dest  *[]string
+	}{
+		{"tags_any", &query.TagsAny},
+		{"tags_all", &query.TagsAll},
+		{"tags_none", &query.TagsNone},
+	}
+	for _, l := range tagLists {
+		if value := values.Get(l.param); value != "" {
+			tags, err := models.NormalizeTags(strings.Split(value, ","))
+			if err != nil {
+				return query, fmt.Errorf("%s: %v", l.param, err)
+			}
+			*l.dest = tags
+		}
+	}
+
+	switch project := values.Get("project"); project {
+	case "":
+	case "none":
+		query.ProjectID = storage.NoProject
+	default:
+		id, err := strconv.Atoi(project)
+		if err != nil || id < 1 {
+			return query, fmt.Errorf("project must be a project ID or 'none'")
+		}
+		query.ProjectID = id
+	}
+
+	switch parent := values.Get("parent"); parent {
+	case "":
+	case "none":
+		query.ParentID = storage.NoParent
+	default:
+		id, err := strconv.Atoi(parent)
+		if err != nil || id < 1 {
+			return query, fmt.Errorf("parent must be a todo ID or 'none'")
+		}
+		query.ParentID = id
+	}
+
+	if value := values.Get("include_archived"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("include_archived must be 'true' or 'false'")
+		}
+		query.IncludeArchived = include
+	}
+
+	if value := values.Get("ready"); value != "" {
+		ready, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("ready must be 'true' or 'false'")
+		}
+		query.Ready = ready
+	}
+
+	if due := values.Get("due"); due != "" {
+		if err := query.SetDueView(due, h.dates.Now()); err != nil {
+			return query, err
+		}
+	}
+
+	times := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"created_since", &query.CreatedSince},
+		{"created_before", &query.CreatedBefore},
+		{"updated_since", &query.UpdatedSince},
+		{"updated_before", &query.UpdatedBefore},
+		{"due_since", &query.DueSince},
+		{"due_before", &query.DueBefore},
+	}
+	for _, t := range times {
+		if value := values.Get(t.param); value != "" {
+			parsed, err := h.dates.Parse(value)
+			if err != nil {
+				return query, fmt.Errorf("%s: %v", t.param, err)
+			}
+			*t.dest = parsed
+		}
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("order must be 'asc' or 'desc'")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("limit must be a non-negative number")
+		}
+		query.Limit = n
+	}
+
+	return query, query.Validate()
 }
 
 // GetTodo handles GET /todos/{id}
+//
+// With tree=true the todo comes with its subtasks at any depth and the
+// progress of each todo that has some.
 func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -98,7 +361,7 @@ func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todo, err := h.storage.GetByID(id)
+	todo, err := h.storage.GetByID(r.Context(), id)
 	if err != nil {
 		if err == storage.ErrTodoNotFound {
 			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
@@ -108,10 +371,47 @@ func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.sendSuccessResponse(w, http.StatusOK, "Todo retrieved successfully", todo)
+	tree, err := parseBoolParam(r, "tree")
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+	if tree {
+		h.sendTodoTree(w, r, todo)
+		return
+	}
+
+	activity, err := h.storage.Activity(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve time entries", err.Error())
+		return
+	}
+
+	setETag(w, todo)
+	h.sendSuccessResponse(w, http.StatusOK, "Todo retrieved successfully", todoWithTime{
+		Todo: todo,
+		Time: activity.TimeStatus(todo.EstimateMinutes, time.Now()),
+	})
+}
+
+// todoWithTime is a todo read on its own, shown with the time spent on it
+type todoWithTime struct {
+	*models.Todo
+	Time *models.TimeStatus `json:"time"`
 }
 
 // UpdateTodo handles PUT /todos/{id}
+//
+// An If-Match header holding the todo's ETag makes the update conditional:
+// it fails with 412 Precondition Failed if the todo has changed since. Without
+// one, an update racing with another writer fails with 409 Conflict.
+// Moving a todo to a status the workflow doesn't allow from its current one,
+// or completing a todo with open blockers, fails with 409 too, unless
+// force=true is given.
 func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -120,8 +420,18 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	force, err := parseBoolParam(r, "force")
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+	ctx := r.Context()
+	if force {
+		ctx = storage.WithForce(ctx)
+	}
+
 	// Get existing todo
-	existingTodo, err := h.storage.GetByID(id)
+	existingTodo, err := h.storage.GetByID(r.Context(), id)
 	if err != nil {
 		if err == storage.ErrTodoNotFound {
 			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
@@ -131,6 +441,11 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etagMatches(ifMatch, existingTodo) {
+		h.sendErrorResponse(w, http.StatusPreconditionFailed, "Precondition failed", "Todo has been modified since the given ETag")
+		return
+	}
+
 	var req models.UpdateTodoRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON", err.Error())
@@ -150,18 +465,125 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		updatedTodo.Description = *req.Description
 	}
 	if req.Status != nil {
-		if *req.Status != models.StatusPending && *req.Status != models.StatusCompleted {
-			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid status", "Status must be 'pending' or 'completed'")
+		if !h.workflow.Valid(*req.Status) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid status", h.statusMessage())
 			return
 		}
 		updatedTodo.Status = *req.Status
 	}
+	if req.Priority != nil {
+		if !req.Priority.Valid() {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid priority", priorityMessage)
+			return
+		}
+		updatedTodo.Priority = *req.Priority
+	}
+	if req.Tags != nil {
+		tags, err := models.NormalizeTags(*req.Tags)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tags", err.Error())
+			return
+		}
+		updatedTodo.Tags = tags
+	}
+	if req.ProjectID != nil {
+		if *req.ProjectID < 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid project", "project_id must be a project ID, or 0 for no project")
+			return
+		}
+		updatedTodo.ProjectID = *req.ProjectID
+	}
+	if req.ParentID != nil {
+		if *req.ParentID < 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid parent", "parent_id must be a todo ID, or 0 for a top-level todo")
+			return
+		}
+		updatedTodo.ParentID = *req.ParentID
+	}
+	if req.BlockedBy != nil {
+		updatedTodo.BlockedBy = models.NormalizeBlockers(*req.BlockedBy)
+	}
+	if req.EstimateMinutes != nil {
+		if *req.EstimateMinutes < 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid estimate", "estimate_minutes must not be negative")
+			return
+		}
+		updatedTodo.EstimateMinutes = *req.EstimateMinutes
+	}
+	if req.Recurrence != nil {
+		updatedTodo.Recurrence = nil
+		if req.Recurrence.Frequency != "" {
+			if err := req.Recurrence.Validate(); err != nil {
+				h.sendErrorResponse(w, http.StatusBadRequest, "Invalid recurrence", err.Error())
+				return
+			}
+			updatedTodo.Recurrence = req.Recurrence
+		}
+	}
+	schedule := []struct {
+		field string
+		value *string
+		dest  **time.Time
+	}{
+		{"start_at", req.StartAt, &updatedTodo.StartAt},
+		{"due_at", req.DueAt, &updatedTodo.DueAt},
+	}
+	for _, s := range schedule {
+		if s.value == nil {
+			continue
+		}
+		parsed, err := h.parseScheduleTime(s.field, *s.value)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid date", err.Error())
+			return
+		}
+		*s.dest = parsed
+	}
+	if err := updatedTodo.CheckSchedule(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid schedule", err.Error())
+		return
+	}
 
-	if err := h.storage.Update(id, &updatedTodo); err != nil {
+	// updatedTodo carries the version read above, so storage rejects the
+	// update if another writer got in between
+	if err := h.storage.Update(ctx, id, &updatedTodo); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			if r.Header.Get("If-Match") != "" {
+				h.sendErrorResponse(w, http.StatusPreconditionFailed, "Precondition failed", "Todo has been modified since the given ETag")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusConflict, "Conflict", "Todo was modified concurrently, please retry")
+			return
+		}
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
+			return
+		}
+		if errors.Is(err, storage.ErrProjectNotFound) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid project", err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrParentNotFound) || errors.Is(err, storage.ErrParentCycle) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid parent", err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrBlockerNotFound) || errors.Is(err, storage.ErrDependencyCycle) {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid blockers", err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrBlocked) {
+			h.sendErrorResponse(w, http.StatusConflict, "Todo is blocked", err.Error()+"; pass force=true to complete it anyway")
+			return
+		}
+		if errors.Is(err, models.ErrInvalidTransition) {
+			h.sendErrorResponse(w, http.StatusConflict, "Invalid transition", err.Error()+"; pass force=true to move it anyway")
+			return
+		}
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update todo", err.Error())
 		return
 	}
 
+	setETag(w, &updatedTodo)
 	h.sendSuccessResponse(w, http.StatusOK, "Todo updated successfully", &updatedTodo)
 }
 
@@ -174,7 +596,7 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.Delete(id); err != nil {
+	if err := h.storage.Delete(r.Context(), id); err != nil {
 		if err == storage.ErrTodoNotFound {
 			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID does not exist")
 			return
@@ -183,10 +605,28 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.sendSuccessResponse(w, http.StatusOK, "Todo deleted successfully", nil)
+	h.sendSuccessResponse(w, http.StatusOK, "Todo moved to the trash", nil)
 }
 
 // Helper methods
+
+// setETag sets the ETag header identifying the todo's current version
+func setETag(w http.ResponseWriter, todo *models.Todo) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, todo.Version))
+}
+
+// etagMatches reports whether an If-Match header value matches the todo's
+// current version. It accepts "*" and comma-separated lists of ETags.
+func etagMatches(ifMatch string, todo *models.Todo) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == fmt.Sprintf(`"%d"`, todo.Version) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *TodoHandler) sendErrorResponse(w http.ResponseWriter, statusCode int, error, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -196,6 +636,17 @@ func (h *TodoHandler) sendErrorResponse(w http.ResponseWriter, statusCode int, e
 	})
 }
 
+func (h *TodoHandler) sendListResponse(w http.ResponseWriter, message string, page *storage.TodoPage) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ListResponse{
+		Message:    message,
+		Data:       page.Todos,
+		Count:      len(page.Todos),
+		NextCursor: page.NextCursor,
+	})
+}
+
 func (h *TodoHandler) sendSuccessResponse(w http.ResponseWriter, statusCode int, message string, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
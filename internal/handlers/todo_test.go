@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/dates"
+	"github.com/shghadge/todo_mcp/internal/handlers"
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// racingStorage lets another writer change a todo once, between the
+// handler reading it and writing it back
+type racingStorage struct {
+	storage.TodoStorage
+	raced bool
+}
+
+func (s *racingStorage) Update(ctx context.Context, id int, todo *models.Todo) error {
+	if !s.raced {
+		s.raced = true
+		other, err := s.TodoStorage.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		other.Description = "changed elsewhere"
+		if err := s.TodoStorage.Update(ctx, id, other); err != nil {
+			return err
+		}
+	}
+	return s.TodoStorage.Update(ctx, id, todo)
+}
+
+// newStorage returns a fresh file storage holding one todo, and the todo
+func newStorage(t *testing.T) (storage.TodoStorage, *models.Todo) {
+	s := storage.WithContext(storage.NewFileStorage(filepath.Join(t.TempDir(), "todos.json"), nil))
+	todo := &models.Todo{Title: "Write report", Status: models.StatusPending}
+	if err := s.Create(context.Background(), todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return s, todo
+}
+
+// newRouter returns the routes over s
+func newRouter(t *testing.T, s storage.TodoStorage) http.Handler {
+	blobs := storage.NewBlobStore(filepath.Join(t.TempDir(), "blobs"), 1<<20)
+	return handlers.SetupRoutes(s, nil, blobs, dates.New(time.UTC, nil))
+}
+
+// updateTitle sends a PUT renaming the todo, with the given If-Match
+// header unless it is empty
+func updateTitle(router http.Handler, id int, ifMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/todos/"+strconv.Itoa(id), strings.NewReader(`{"title":"Write final report"}`))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetTodoSetsETag(t *testing.T) {
+	s, todo := newStorage(t)
+	router := newRouter(t, s)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/todos/"+strconv.Itoa(todo.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != `"1"` {
+		t.Errorf(`ETag = %s, want "1"`, got)
+	}
+}
+
+func TestUpdateTodoIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+		renamed bool
+	}{
+		{"matching", `"1"`, http.StatusOK, true},
+		{"matching in a list", `"7", "1"`, http.StatusOK, true},
+		{"wildcard", `*`, http.StatusOK, true},
+		{"missing", ``, http.StatusOK, true},
+		{"stale", `"2"`, http.StatusPreconditionFailed, false},
+		{"unquoted", `1`, http.StatusPreconditionFailed, false},
+		{"weak", `W/"1"`, http.StatusPreconditionFailed, false},
+		{"garbage", `not an etag`, http.StatusPreconditionFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, todo := newStorage(t)
+			router := newRouter(t, s)
+
+			rec := updateTitle(router, todo.ID, tt.ifMatch)
+			if rec.Code != tt.status {
+				t.Fatalf("PUT status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.renamed {
+				if got := rec.Header().Get("ETag"); got != `"2"` {
+					t.Errorf(`ETag after the update = %s, want "2"`, got)
+				}
+			}
+
+			stored, err := s.GetByID(context.Background(), todo.ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if renamed := stored.Title == "Write final report"; renamed != tt.renamed {
+				t.Errorf("stored title = %q, renamed %v, want %v", stored.Title, renamed, tt.renamed)
+			}
+		})
+	}
+}
+
+func TestUpdateTodoRace(t *testing.T) {
+	// A write racing the update fails it with 412 if the client sent an
+	// ETag, which the race made stale, and with 409 if it didn't
+	for _, tt := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"1"`, http.StatusPreconditionFailed},
+		{``, http.StatusConflict},
+	} {
+		fresh, todo := newStorage(t)
+		s := &racingStorage{TodoStorage: fresh}
+		router := newRouter(t, s)
+
+		rec := updateTitle(router, todo.ID, tt.ifMatch)
+		if rec.Code != tt.status {
+			t.Errorf("PUT with If-Match %q racing another write: status = %d, want %d", tt.ifMatch, rec.Code, tt.status)
+		}
+
+		stored, err := s.GetByID(context.Background(), todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if stored.Title != "Write report" || stored.Description != "changed elsewhere" {
+			t.Errorf("stored todo = %q, %q, want only the other write", stored.Title, stored.Description)
+		}
+	}
+}
+
+func TestUpdateTodoConflictWithMatchingETag(t *testing.T) {
+	// An up to date ETag doesn't turn a refused update into 412: completing
+	// a blocked todo is still a 409
+	s, blocker := newStorage(t)
+	todo := &models.Todo{Title: "Send report", Status: models.StatusPending, BlockedBy: []int{blocker.ID}}
+	if err := s.Create(context.Background(), todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	router := newRouter(t, s)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/todos/"+strconv.Itoa(todo.ID), strings.NewReader(`{"status":"completed"}`))
+	req.Header.Set("If-Match", `"1"`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("PUT completing a blocked todo: status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTrash handles GET /trash
+func (h *TodoHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	todos, err := h.storage.ListTrash(r.Context())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve trash", err.Error())
+		return
+	}
+
+	h.sendListResponse(w, "Trash retrieved successfully", &storage.TodoPage{Todos: todos})
+}
+
+// RestoreTodo handles POST /trash/{id}/restore
+func (h *TodoHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	todo, err := h.storage.Restore(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID is not in the trash")
+			return
+		}
+		if errors.Is(err, storage.ErrParentTrashed) {
+			h.sendErrorResponse(w, http.StatusConflict, "Parent in the trash", err.Error())
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to restore todo", err.Error())
+		return
+	}
+
+	setETag(w, todo)
+	h.sendSuccessResponse(w, http.StatusOK, "Todo restored successfully", todo)
+}
+
+// PurgeTodo handles DELETE /trash/{id}
+func (h *TodoHandler) PurgeTodo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid ID", "ID must be a number")
+		return
+	}
+
+	if err := h.storage.Purge(r.Context(), id); err != nil {
+		if err == storage.ErrTodoNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, "Todo not found", "Todo with given ID is not in the trash")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to purge todo", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Todo permanently deleted", nil)
+}
+
+// EmptyTrash handles DELETE /trash. With ?before=<date> only the todos
+// trashed before that time are deleted.
+func (h *TodoHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	var before time.Time
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		before, err = h.dates.Parse(value)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", "before: "+err.Error())
+			return
+		}
+	}
+
+	n, err := h.storage.PurgeTrash(r.Context(), before)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to empty trash", err.Error())
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, fmt.Sprintf("%d todos permanently deleted", n), nil)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// Undo handles POST /undo. It undoes the client's last change, or the last
+// ?count=N changes, as identified by the X-Actor header.
+func (h *TodoHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	h.replayJournal(w, r, "undo", "undone", h.journal.Undo)
+}
+
+// Redo handles POST /redo
+func (h *TodoHandler) Redo(w http.ResponseWriter, r *http.Request) {
+	h.replayJournal(w, r, "redo", "redone", h.journal.Redo)
+}
+
+func (h *TodoHandler) replayJournal(w http.ResponseWriter, r *http.Request, verb, done string, replay func(context.Context, int) ([]storage.JournalChange, error)) {
+	count := 1
+	if value := r.URL.Query().Get("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid query", "count must be a positive integer")
+			return
+		}
+		count = n
+	}
+
+	changes, err := replay(r.Context(), count)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNothingToUndo), errors.Is(err, storage.ErrNothingToRedo):
+			h.sendErrorResponse(w, http.StatusConflict, fmt.Sprintf("Nothing to %s", verb), err.Error())
+		case errors.Is(err, storage.ErrVersionConflict), errors.Is(err, storage.ErrTodoNotFound):
+			h.sendErrorResponse(w, http.StatusConflict, fmt.Sprintf("Failed to %s", verb),
+				fmt.Sprintf("%v (%d changes %s before the failure)", err, len(changes), done))
+		default:
+			h.sendErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Failed to %s", verb),
+				fmt.Sprintf("%v (%d changes %s before the failure)", err, len(changes), done))
+		}
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, fmt.Sprintf("%d changes %s", len(changes), done), changes)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// statusMessage explains a status rejected by the handler's workflow
+func (h *TodoHandler) statusMessage() string {
+	statuses := h.workflow.Statuses()
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = fmt.Sprintf("'%s'", status)
+	}
+	return "Status must be one of " + strings.Join(names, ", ")
+}
+
+// GetWorkflow handles GET /workflow
+//
+// It describes the states todos move through and the transitions allowed
+// between them.
+func (h *TodoHandler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	h.sendSuccessResponse(w, http.StatusOK, "Workflow retrieved successfully", h.workflow)
+}
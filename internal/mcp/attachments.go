@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// attachmentResourceURI returns the URI of the resource holding the content
+// of an attachment of the todo with the given ID
+func attachmentResourceURI(todoID, attachmentID int) string {
+	return fmt.Sprintf(ResourceTodoAttachment, todoID, attachmentID)
+}
+
+// parseAttachmentResourceURI returns the todo and attachment IDs in an
+// attachment resource URI
+func parseAttachmentResourceURI(uri string) (todoID, attachmentID int, ok bool) {
+	if _, err := fmt.Sscanf(uri, ResourceTodoAttachment, &todoID, &attachmentID); err != nil {
+		return 0, 0, false
+	}
+	// Sscanf ignores anything after the attachment ID
+	return todoID, attachmentID, uri == attachmentResourceURI(todoID, attachmentID)
+}
+
+// attachmentResources lists a resource for each attachment of the todos
+func (s *MCPServer) attachmentResources(ctx context.Context) ([]Resource, error) {
+	todos, err := s.storage.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving todos: %w", err)
+	}
+
+	var resources []Resource
+	for _, todo := range todos {
+		for _, attachment := range todo.Attachments {
+			resources = append(resources, Resource{
+				URI:         attachmentResourceURI(todo.ID, attachment.ID),
+				Name:        attachment.Name,
+				Description: fmt.Sprintf("File attached to todo %d: %s", todo.ID, todo.Title),
+				MimeType:    attachment.MediaType,
+			})
+		}
+	}
+	return resources, nil
+}
+
+// handleAttachmentResource reads the content of an attachment, base64
+// encoded as the resource's blob
+func (s *MCPServer) handleAttachmentResource(ctx context.Context, uri string, todoID, attachmentID int) (*ReadResourceResponse, error) {
+	todo, err := s.storage.GetByID(ctx, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving todo %d: %w", todoID, err)
+	}
+	attachment, err := todo.Attachment(attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("todo %d: %w", todoID, err)
+	}
+
+	file, err := s.blobs.Open(attachment.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("error reading attachment %s: %w", attachment.Name, err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading attachment %s: %w", attachment.Name, err)
+	}
+
+	return &ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      uri,
+				MimeType: attachment.MediaType,
+				Blob:     base64.StdEncoding.EncodeToString(content),
+			},
+		},
+	}, nil
+}
+
+// newAttachmentResponses converts the attachments of a todo to their
+// response format
+func newAttachmentResponses(todo *models.Todo) []AttachmentResponse {
+	if len(todo.Attachments) == 0 {
+		return nil
+	}
+	responses := make([]AttachmentResponse, len(todo.Attachments))
+	for i, attachment := range todo.Attachments {
+		responses[i] = AttachmentResponse{
+			ID:        attachment.ID,
+			Name:      attachment.Name,
+			MediaType: attachment.MediaType,
+			Size:      attachment.Size,
+			URI:       attachmentResourceURI(todo.ID, attachment.ID),
+		}
+	}
+	return responses
+}
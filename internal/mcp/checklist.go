@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleAddChecklistItem handles the add_checklist_item tool
+func (s *MCPServer) handleAddChecklistItem(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	text, _ := args["text"].(string)
+	position, _, err := intArg(args, "position")
+	if err != nil {
+		return checklistErrorResponse(0, err), nil
+	}
+
+	return s.changeChecklist(ctx, args, "Checklist item added", func(todo *models.Todo) error {
+		_, err := todo.AddChecklistItem(text, position)
+		return err
+	})
+}
+
+// handleToggleChecklistItem handles the toggle_checklist_item tool: it
+// checks or unchecks an item as given by done, or flips it without done
+func (s *MCPServer) handleToggleChecklistItem(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	itemID, err := checklistItemArg(args)
+	if err != nil {
+		return checklistErrorResponse(0, err), nil
+	}
+	done, set := args["done"].(bool)
+
+	return s.changeChecklist(ctx, args, "Checklist item updated", func(todo *models.Todo) error {
+		if !set {
+			item, err := todo.ChecklistItem(itemID)
+			if err != nil {
+				return err
+			}
+			done = !item.Done
+		}
+		_, err := todo.CheckChecklistItem(itemID, done)
+		return err
+	})
+}
+
+// handleMoveChecklistItem handles the move_checklist_item tool
+func (s *MCPServer) handleMoveChecklistItem(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	itemID, err := checklistItemArg(args)
+	var position int
+	if err == nil {
+		var set bool
+		position, set, err = intArg(args, "position")
+		if err == nil && !set {
+			err = errors.New("position is required")
+		}
+	}
+	if err != nil {
+		return checklistErrorResponse(0, err), nil
+	}
+
+	return s.changeChecklist(ctx, args, "Checklist item moved", func(todo *models.Todo) error {
+		_, err := todo.MoveChecklistItem(itemID, position)
+		return err
+	})
+}
+
+// handleRemoveChecklistItem handles the remove_checklist_item tool
+func (s *MCPServer) handleRemoveChecklistItem(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	itemID, err := checklistItemArg(args)
+	if err != nil {
+		return checklistErrorResponse(0, err), nil
+	}
+
+	return s.changeChecklist(ctx, args, "Checklist item removed", func(todo *models.Todo) error {
+		return todo.RemoveChecklistItem(itemID)
+	})
+}
+
+// checklistItemArg reads the required item_id argument
+func checklistItemArg(args map[string]interface{}) (int, error) {
+	itemID, set, err := intArg(args, "item_id")
+	if err == nil && !set {
+		err = errors.New("item_id is required")
+	}
+	return itemID, err
+}
+
+// changeChecklist applies change to the checklist of the todo named by
+// args, reading the todo again and retrying if another writer got in
+// between, and responds with the checklist
+func (s *MCPServer) changeChecklist(ctx context.Context, args map[string]interface{}, message string, change func(*models.Todo) error) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return checklistErrorResponse(0, err), nil
+	}
+
+	for {
+		todo, err := s.storage.GetByID(ctx, id)
+		if err != nil {
+			return checklistErrorResponse(id, err), nil
+		}
+
+		if err := change(todo); err != nil {
+			return checklistErrorResponse(id, err), nil
+		}
+		err = s.storage.Update(ctx, id, todo)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return checklistErrorResponse(id, err), nil
+		}
+
+		result, _ := json.MarshalIndent(todo.ChecklistSummary(), "", "  ")
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("%s:\n%s", message, string(result)),
+			}},
+		}, nil
+	}
+}
+
+// checklistErrorResponse reports a failed change to the checklist of a
+// todo
+func checklistErrorResponse(id int, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error: %v", err)
+	if errors.Is(err, storage.ErrTodoNotFound) {
+		text = fmt.Sprintf("Todo with ID %d not found", id)
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
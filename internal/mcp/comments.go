@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleAddComment handles the add_comment tool. Comments are appended to
+// the todo's thread, so agents can log progress without overwriting the
+// description.
+func (s *MCPServer) handleAddComment(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	body, _ := args["body"].(string)
+	author := storage.ActorFromContext(ctx)
+
+	var comment *models.Comment
+	_, err = s.storage.ChangeActivity(ctx, id, func(activity *models.Activity) error {
+		var err error
+		comment, err = activity.AddComment(time.Now(), author, body)
+		return err
+	})
+	if err != nil {
+		return commentErrorResponse(id, err), nil
+	}
+
+	result, _ := json.MarshalIndent(comment, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Comment added to todo %d:\n%s", id, string(result)),
+		}},
+	}, nil
+}
+
+// handleListComments handles the list_comments tool
+func (s *MCPServer) handleListComments(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	activity, err := s.storage.Activity(ctx, id)
+	if err != nil {
+		return commentErrorResponse(id, err), nil
+	}
+
+	thread := activity.Thread()
+	result, _ := json.MarshalIndent(CommentListResponse{Comments: thread, Count: len(thread)}, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// commentErrorResponse reports a failed change to the comments of a todo
+func commentErrorResponse(id int, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error: %v", err)
+	if errors.Is(err, storage.ErrTodoNotFound) {
+		text = fmt.Sprintf("Todo with ID %d not found", id)
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
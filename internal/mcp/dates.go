@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateEcho collects the date arguments a tool call resolved, so that the
+// response can show the agent which absolute times its expressions became
+type dateEcho []string
+
+// add records that the argument's expression resolved to t
+func (e *dateEcho) add(arg, expr string, t time.Time) {
+	*e = append(*e, fmt.Sprintf("%s: %q resolved to %s", arg, expr, t.Format(time.RFC3339)))
+}
+
+// String returns the text appended to a tool response, empty when no date
+// was resolved
+func (e dateEcho) String() string {
+	if len(e) == 0 {
+		return ""
+	}
+	return "\n\nResolved dates:\n  " + strings.Join(e, "\n  ")
+}
+
+// scheduleArg resolves the start_at or due_at argument and records it in
+// echo. set is false when the argument is absent, while an empty string
+// clears the date. A due date given as a day lasts until the end of it.
+func (s *MCPServer) scheduleArg(args map[string]interface{}, name string, echo *dateEcho) (t *time.Time, set bool, err error) {
+	value, ok := args[name].(string)
+	if !ok {
+		return nil, false, nil
+	}
+	if value == "" {
+		return nil, true, nil
+	}
+
+	parse := s.dates.Parse
+	if name == "due_at" {
+		parse = s.dates.ParseDue
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %v", name, err)
+	}
+	echo.add(name, value, parsed)
+	return &parsed, true, nil
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// blockersArg reads the list of todo IDs args[name], normalized with
+// models.NormalizeBlockers. set reports whether the argument was given.
+func blockersArg(args map[string]interface{}, name string) (ids []int, set bool, err error) {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return nil, false, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("%s must be an array of todo IDs", name)
+	}
+	raw := make([]int, len(items))
+	for i, item := range items {
+		id, ok := item.(float64)
+		if !ok || id < 1 || id != float64(int(id)) {
+			return nil, false, fmt.Errorf("%s must be an array of todo IDs", name)
+		}
+		raw[i] = int(id)
+	}
+	return models.NormalizeBlockers(raw), true, nil
+}
+
+// handleAddBlocker handles the add_blocker tool
+func (s *MCPServer) handleAddBlocker(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	return s.changeBlockers(ctx, args, "Blocker added", func(todo *models.Todo, blockerID int) {
+		todo.AddBlocker(blockerID)
+	})
+}
+
+// handleRemoveBlocker handles the remove_blocker tool
+func (s *MCPServer) handleRemoveBlocker(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	return s.changeBlockers(ctx, args, "Blocker removed", func(todo *models.Todo, blockerID int) {
+		todo.RemoveBlocker(blockerID)
+	})
+}
+
+// changeBlockers applies change to the blockers of the todo named by args,
+// reading the todo again and retrying if another writer got in between
+func (s *MCPServer) changeBlockers(ctx context.Context, args map[string]interface{}, message string, change func(*models.Todo, int)) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	blockerID, set, err := intArg(args, "blocker_id")
+	if err == nil && (!set || blockerID < 1) {
+		err = errors.New("blocker_id is required and must be a todo ID")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	for {
+		todo, err := s.storage.GetByID(ctx, id)
+		if err != nil {
+			return blockerErrorResponse(id, err), nil
+		}
+
+		change(todo, blockerID)
+		err = s.storage.Update(ctx, id, todo)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return blockerErrorResponse(id, err), nil
+		}
+
+		result, _ := json.MarshalIndent(newTodoResponse(todo), "", "  ")
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("%s:\n%s", message, string(result)),
+			}},
+		}, nil
+	}
+}
+
+// blockerErrorResponse reports a failed change to the blockers of a todo
+func blockerErrorResponse(id int, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error: %v", err)
+	if errors.Is(err, storage.ErrTodoNotFound) {
+		text = fmt.Sprintf("Todo with ID %d not found", id)
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
+
+// handleTodosReadyResource handles the ready todos resource: the pending
+// todos without open blockers, most urgent first
+func (s *MCPServer) handleTodosReadyResource(ctx context.Context) (*ReadResourceResponse, error) {
+	page, err := s.storage.Query(ctx, storage.TodoQuery{Ready: true, SortBy: storage.SortByPriority, Descending: true})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving ready todos: %w", err)
+	}
+	todos := page.Todos
+
+	// Convert to response format
+	todoListResp := TodoListResponse{
+		Todos: make([]TodoResponse, len(todos)),
+		Count: len(todos),
+	}
+
+	for i, todo := range todos {
+		todoListResp.Todos[i] = newTodoResponse(todo)
+	}
+
+	result, err := json.MarshalIndent(todoListResp, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling ready todos: %w", err)
+	}
+
+	return &ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      ResourceTodosReady,
+				MimeType: "application/json",
+				Text:     string(result),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleGetTodoHistory handles the get_todo_history tool
+func (s *MCPServer) handleGetTodoHistory(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	// Extract and validate ID
+	idInterface, ok := args["id"]
+	if !ok {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: id is required",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	var id int
+	switch v := idInterface.(type) {
+	case float64:
+		id = int(v)
+	case int:
+		id = v
+	case string:
+		var err error
+		id, err = strconv.Atoi(v)
+		if err != nil {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: id must be a valid integer",
+				}},
+				IsError: true,
+			}, nil
+		}
+	default:
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: id must be a number",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Get history
+	events, err := s.storage.History(ctx, id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: fmt.Sprintf("Todo with ID %d not found", id),
+				}},
+				IsError: true,
+			}, nil
+		}
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving history: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	historyResp := TodoHistoryResponse{
+		TodoID: id,
+		Events: events,
+		Count:  len(events),
+	}
+
+	result, _ := json.MarshalIndent(historyResp, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
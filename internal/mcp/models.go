@@ -3,6 +3,8 @@ package mcp
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
 )
 
 // MCP Protocol Types
@@ -44,6 +46,12 @@ type InitializeResponse struct {
 	ServerInfo      ServerInfo         `json:"serverInfo"`
 }
 
+// CancelledNotification represents the notifications/cancelled params
+type CancelledNotification struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
 // ClientCapabilities represents client capabilities
 type ClientCapabilities struct {
 	Sampling map[string]interface{} `json:"sampling,omitempty"`
@@ -170,30 +178,101 @@ const (
 	MethodReadResource   = "resources/read"
 	MethodPing           = "ping"
 	MethodLoggingMessage = "logging/message"
+	MethodCancelled      = "notifications/cancelled"
 )
 
 // Tool names for our todo application
 const (
-	ToolCreateTodo = "create_todo"
-	ToolGetTodo    = "get_todo"
-	ToolGetTodos   = "get_todos"
-	ToolUpdateTodo = "update_todo"
-	ToolDeleteTodo = "delete_todo"
+	ToolCreateTodo          = "create_todo"
+	ToolGetTodo             = "get_todo"
+	ToolGetTodos            = "get_todos"
+	ToolUpdateTodo          = "update_todo"
+	ToolDeleteTodo          = "delete_todo"
+	ToolGetTodoHistory      = "get_todo_history"
+	ToolUndo                = "undo"
+	ToolRedo                = "redo"
+	ToolListTrash           = "list_trash"
+	ToolRestore             = "restore_todo"
+	ToolEmptyTrash          = "empty_trash"
+	ToolListTags            = "list_tags"
+	ToolRenameTag           = "rename_tag"
+	ToolMergeTags           = "merge_tags"
+	ToolCreateProject       = "create_project"
+	ToolListProjects        = "list_projects"
+	ToolUpdateProject       = "update_project"
+	ToolDeleteProject       = "delete_project"
+	ToolAddSubtasks         = "add_subtasks"
+	ToolGetSubtasks         = "get_subtasks"
+	ToolAddBlocker          = "add_blocker"
+	ToolRemoveBlocker       = "remove_blocker"
+	ToolStartTimer          = "start_timer"
+	ToolStopTimer           = "stop_timer"
+	ToolLogTime             = "log_time"
+	ToolGetTime             = "get_time"
+	ToolTimeReport          = "time_report"
+	ToolAddComment          = "add_comment"
+	ToolListComments        = "list_comments"
+	ToolAddChecklistItem    = "add_checklist_item"
+	ToolToggleChecklistItem = "toggle_checklist_item"
+	ToolMoveChecklistItem   = "move_checklist_item"
+	ToolRemoveChecklistItem = "remove_checklist_item"
 )
 
 // Resource URIs for our todo application
 const (
-	ResourceTodosList      = "todo://todos"
+	ResourceTodosList = "todo://todos"
+
+	// ResourceTodosPending lists the todos that aren't done and
+	// ResourceTodosCompleted the ones that are, whatever their state
 	ResourceTodosPending   = "todo://todos/pending"
 	ResourceTodosCompleted = "todo://todos/completed"
+
+	// ResourceTodosStatus is followed by a state of the workflow, e.g.
+	// todo://todos/status/in_progress
+	ResourceTodosStatus = "todo://todos/status/"
+
+	// ResourceTodosPriority is followed by a priority level, e.g.
+	// todo://todos/priority/high
+	ResourceTodosPriority = "todo://todos/priority/"
+
+	// Due views: open todos past their due date, due today and due this
+	// week
+	ResourceTodosOverdue     = "todo://todos/overdue"
+	ResourceTodosDueToday    = "todo://todos/due/today"
+	ResourceTodosDueThisWeek = "todo://todos/due/this_week"
+
+	// ResourceTodosReady lists the pending todos without open blockers
+	ResourceTodosReady = "todo://todos/ready"
+
+	// ResourceTags lists every tag with the number of todos carrying it
+	ResourceTags = "todo://tags"
+
+	// ResourceProjects lists the projects, archived ones included
+	ResourceProjects = "todo://projects"
+
+	// ResourceWorkflow describes the workflow's states and transitions
+	ResourceWorkflow = "todo://workflow"
+
+	// ResourceTodoAttachment formats the URI of an attachment's content
+	// from the IDs of the todo and the attachment
+	ResourceTodoAttachment = "todo://todos/%d/attachments/%d"
 )
 
 // Todo-specific request/response types for tools
 
 // CreateTodoRequest represents parameters for creating a todo
 type CreateTodoRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description,omitempty"`
+	Title           string             `json:"title"`
+	Description     string             `json:"description,omitempty"`
+	Priority        string             `json:"priority,omitempty"` // defaults to "none"
+	StartAt         string             `json:"start_at,omitempty"` // RFC 3339 or a date expression
+	DueAt           string             `json:"due_at,omitempty"`   // RFC 3339 or a date expression
+	Tags            []string           `json:"tags,omitempty"`
+	ProjectID       int                `json:"project_id,omitempty"`
+	ParentID        int                `json:"parent_id,omitempty"`
+	BlockedBy       []int              `json:"blocked_by,omitempty"`
+	Recurrence      *models.Recurrence `json:"recurrence,omitempty"`
+	EstimateMinutes int                `json:"estimate_minutes,omitempty"`
 }
 
 // GetTodoRequest represents parameters for getting a todo
@@ -203,15 +282,48 @@ type GetTodoRequest struct {
 
 // GetTodosRequest represents parameters for getting todos
 type GetTodosRequest struct {
-	Status string `json:"status,omitempty"` // "pending", "completed", or empty for all
+	Status          []string `json:"status,omitempty"` // states of the workflow, or empty for all
+	Priority        string   `json:"priority,omitempty"`
+	Title           string   `json:"title,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Text            string   `json:"text,omitempty"`
+	CreatedSince    string   `json:"created_since,omitempty"`
+	CreatedBefore   string   `json:"created_before,omitempty"`
+	UpdatedSince    string   `json:"updated_since,omitempty"`
+	UpdatedBefore   string   `json:"updated_before,omitempty"`
+	DueSince        string   `json:"due_since,omitempty"`
+	DueBefore       string   `json:"due_before,omitempty"`
+	Due             string   `json:"due,omitempty"` // "overdue", "today" or "this_week"
+	TagsAny         []string `json:"tags_any,omitempty"`
+	TagsAll         []string `json:"tags_all,omitempty"`
+	TagsNone        []string `json:"tags_none,omitempty"`
+	ProjectID       int      `json:"project_id,omitempty"` // -1 for todos in no project
+	IncludeArchived bool     `json:"include_archived,omitempty"`
+	ParentID        int      `json:"parent_id,omitempty"` // -1 for top-level todos
+	Ready           bool     `json:"ready,omitempty"`     // only todos without open blockers
+	SortBy          string   `json:"sort_by,omitempty"`
+	Order           string   `json:"order,omitempty"` // "asc" or "desc"
+	Limit           int      `json:"limit,omitempty"`
+	Cursor          string   `json:"cursor,omitempty"`
 }
 
 // UpdateTodoRequest represents parameters for updating a todo
 type UpdateTodoRequest struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title,omitempty"`
-	Description string `json:"description,omitempty"`
-	Status      string `json:"status,omitempty"` // "pending" or "completed"
+	ID              int                `json:"id"`
+	Version         int                `json:"version,omitempty"` // expected current version, if any
+	Title           string             `json:"title,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	Status          string             `json:"status,omitempty"` // a state the workflow allows moving to
+	Priority        string             `json:"priority,omitempty"`
+	StartAt         string             `json:"start_at,omitempty"`         // date, or "" to clear
+	DueAt           string             `json:"due_at,omitempty"`           // date, or "" to clear
+	Tags            []string           `json:"tags,omitempty"`             // replaces all tags
+	ProjectID       *int               `json:"project_id,omitempty"`       // 0 removes it from its project
+	ParentID        *int               `json:"parent_id,omitempty"`        // 0 makes it top-level
+	BlockedBy       []int              `json:"blocked_by,omitempty"`       // replaces all blockers
+	Recurrence      *models.Recurrence `json:"recurrence,omitempty"`       // an empty frequency stops it recurring
+	EstimateMinutes *int               `json:"estimate_minutes,omitempty"` // 0 clears the estimate
+	Force           bool               `json:"force,omitempty"`            // complete it despite open blockers
 }
 
 // DeleteTodoRequest represents parameters for deleting a todo
@@ -219,18 +331,241 @@ type DeleteTodoRequest struct {
 	ID int `json:"id"`
 }
 
+// RestoreTodoRequest represents parameters for restoring a todo from the trash
+type RestoreTodoRequest struct {
+	ID int `json:"id"`
+}
+
+// EmptyTrashRequest represents parameters for emptying the trash
+type EmptyTrashRequest struct {
+	ID     int    `json:"id,omitempty"`     // purge just this todo
+	Before string `json:"before,omitempty"` // cutoff date, empty for all
+}
+
 // TodoResponse represents a todo in responses
 type TodoResponse struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                int                    `json:"id"`
+	Title             string                 `json:"title"`
+	Description       string                 `json:"description"`
+	Status            string                 `json:"status"`
+	Priority          string                 `json:"priority,omitempty"`
+	StartAt           *time.Time             `json:"start_at,omitempty"`
+	DueAt             *time.Time             `json:"due_at,omitempty"`
+	Tags              []string               `json:"tags,omitempty"`
+	ProjectID         int                    `json:"project_id,omitempty"`
+	ParentID          int                    `json:"parent_id,omitempty"`
+	BlockedBy         []int                  `json:"blocked_by,omitempty"`
+	Recurrence        *models.Recurrence     `json:"recurrence,omitempty"`
+	NextID            int                    `json:"next_id,omitempty"`
+	EstimateMinutes   int                    `json:"estimate_minutes,omitempty"`
+	Time              *models.TimeStatus     `json:"time,omitempty"` // only when the todo is read on its own
+	Attachments       []AttachmentResponse   `json:"attachments,omitempty"`
+	Checklist         []models.ChecklistItem `json:"checklist,omitempty"`
+	ChecklistProgress *models.Progress       `json:"checklist_progress,omitempty"`
+	Version           int                    `json:"version"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	DeletedAt         *time.Time             `json:"deleted_at,omitempty"`
+}
+
+// newTodoResponse converts a todo to its response format
+func newTodoResponse(todo *models.Todo) TodoResponse {
+	return TodoResponse{
+		ID:                todo.ID,
+		Title:             todo.Title,
+		Description:       todo.Description,
+		Status:            string(todo.Status),
+		Priority:          string(todo.Priority),
+		StartAt:           todo.StartAt,
+		DueAt:             todo.DueAt,
+		Tags:              todo.Tags,
+		ProjectID:         todo.ProjectID,
+		ParentID:          todo.ParentID,
+		BlockedBy:         todo.BlockedBy,
+		Recurrence:        todo.Recurrence,
+		NextID:            todo.NextID,
+		EstimateMinutes:   todo.EstimateMinutes,
+		Attachments:       newAttachmentResponses(todo),
+		Checklist:         todo.Checklist,
+		ChecklistProgress: todo.ChecklistProgress(),
+		Version:           todo.Version,
+		CreatedAt:         todo.CreatedAt,
+		UpdatedAt:         todo.UpdatedAt,
+		DeletedAt:         todo.DeletedAt,
+	}
+}
+
+// RenameTagRequest represents parameters for renaming a tag
+type RenameTagRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MergeTagsRequest represents parameters for merging two tags
+type MergeTagsRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+// TagListResponse represents the tags in use
+type TagListResponse struct {
+	Tags  []models.TagCount `json:"tags"`
+	Count int               `json:"count"`
+}
+
+// CreateProjectRequest represents parameters for creating a project
+type CreateProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListProjectsRequest represents parameters for listing projects
+type ListProjectsRequest struct {
+	IncludeArchived bool `json:"include_archived,omitempty"`
+}
+
+// UpdateProjectRequest represents parameters for updating a project
+type UpdateProjectRequest struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Archived    *bool  `json:"archived,omitempty"`
+}
+
+// DeleteProjectRequest represents parameters for deleting a project
+type DeleteProjectRequest struct {
+	ID int `json:"id"`
+}
+
+// ProjectListResponse represents a list of projects
+type ProjectListResponse struct {
+	Projects []*models.Project `json:"projects"`
+	Count    int               `json:"count"`
+}
+
+// AddSubtasksRequest represents parameters for breaking a todo down
+type AddSubtasksRequest struct {
+	ParentID int      `json:"parent_id"`
+	Titles   []string `json:"titles"`
+}
+
+// GetSubtasksRequest represents parameters for getting a todo's subtasks
+type GetSubtasksRequest struct {
+	ID   int  `json:"id"`
+	Tree bool `json:"tree,omitempty"` // include subtasks at any depth
+}
+
+// BlockerRequest represents parameters for adding or removing a blocker
+type BlockerRequest struct {
+	ID        int `json:"id"`
+	BlockerID int `json:"blocker_id"`
+}
+
+// TimerRequest represents parameters for starting or stopping a timer
+type TimerRequest struct {
+	ID   int    `json:"id"`
+	Note string `json:"note,omitempty"` // start_timer only
+}
+
+// LogTimeRequest represents parameters for logging time on a todo
+type LogTimeRequest struct {
+	ID      int    `json:"id"`
+	Start   string `json:"start,omitempty"` // date, or give minutes instead
+	End     string `json:"end,omitempty"`   // date, defaults to now
+	Minutes int    `json:"minutes,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// TimeReportRequest represents parameters for a time report
+type TimeReportRequest struct {
+	Since  string `json:"since,omitempty"`  // date, defaults to the start of this week
+	Before string `json:"before,omitempty"` // date, defaults to a week after since
+}
+
+// AddCommentRequest represents parameters for commenting on a todo
+type AddCommentRequest struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+}
+
+// ListCommentsRequest represents parameters for listing a todo's comments
+type ListCommentsRequest struct {
+	ID int `json:"id"`
+}
+
+// CommentListResponse represents the comment thread of a todo
+type CommentListResponse struct {
+	Comments []models.Comment `json:"comments"`
+	Count    int              `json:"count"`
+}
+
+// AddChecklistItemRequest represents parameters for adding a checklist item
+type AddChecklistItemRequest struct {
+	ID       int    `json:"id"`
+	Text     string `json:"text"`
+	Position int    `json:"position,omitempty"` // counting from 1, defaults to the end
+}
+
+// ChecklistItemRequest represents parameters for toggling, moving or
+// removing a checklist item
+type ChecklistItemRequest struct {
+	ID       int   `json:"id"`
+	ItemID   int   `json:"item_id"`
+	Done     *bool `json:"done,omitempty"`     // toggle_checklist_item only, flips the item without it
+	Position int   `json:"position,omitempty"` // move_checklist_item only, counting from 1
+}
+
+// AttachmentResponse represents a file attached to a todo, whose content
+// is read from the resource at URI
+type AttachmentResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+	URI       string `json:"uri"`
+}
+
+// TodoTreeResponse represents a todo with its progress and subtasks
+type TodoTreeResponse struct {
+	TodoResponse
+	Progress *models.Progress   `json:"progress,omitempty"`
+	Subtasks []TodoTreeResponse `json:"subtasks,omitempty"`
+}
+
+// newTodoTreeResponse converts a todo tree to its response format
+func newTodoTreeResponse(tree *models.TodoTree) TodoTreeResponse {
+	resp := TodoTreeResponse{
+		TodoResponse: newTodoResponse(tree.Todo),
+		Progress:     tree.Progress,
+	}
+	for _, subtask := range tree.Subtasks {
+		resp.Subtasks = append(resp.Subtasks, newTodoTreeResponse(subtask))
+	}
+	return resp
+}
+
+// TodoHistoryResponse represents the change history of a todo
+type TodoHistoryResponse struct {
+	TodoID int                 `json:"todo_id"`
+	Events []*models.TodoEvent `json:"events"`
+	Count  int                 `json:"count"`
+}
+
+// JournalChangeResponse represents a change undone or redone
+type JournalChangeResponse struct {
+	Action string       `json:"action"`
+	Todo   TodoResponse `json:"todo"`
+}
+
+// UndoResponse represents the result of the undo and redo tools
+type UndoResponse struct {
+	Changes []JournalChangeResponse `json:"changes"`
+	Count   int                     `json:"count"`
 }
 
 // TodoListResponse represents a list of todos
 type TodoListResponse struct {
-	Todos []TodoResponse `json:"todos"`
-	Count int            `json:"count"`
+	Todos      []TodoResponse `json:"todos"`
+	Count      int            `json:"count"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// intArg reads the integer args[name], accepting numbers and numeric
+// strings. set reports whether the argument was given at all.
+func intArg(args map[string]interface{}, name string) (n int, set bool, err error) {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		n = int(v)
+		if float64(n) != v {
+			return 0, false, fmt.Errorf("%s must be an integer", name)
+		}
+	case int:
+		n = v
+	case string:
+		if n, err = strconv.Atoi(v); err != nil {
+			return 0, false, fmt.Errorf("%s must be a valid integer", name)
+		}
+	default:
+		return 0, false, fmt.Errorf("%s must be a number", name)
+	}
+	return n, true, nil
+}
+
+// handleCreateProject handles the create_project tool
+func (s *MCPServer) handleCreateProject(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	name, _ := args["name"].(string)
+	description, _ := args["description"].(string)
+
+	project := &models.Project{
+		Name:        name,
+		Description: description,
+	}
+	if err := s.storage.CreateProject(ctx, project); err != nil {
+		return projectErrorResponse("creating", err), nil
+	}
+
+	result, _ := json.MarshalIndent(project, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Project created successfully:\n%s", string(result)),
+		}},
+	}, nil
+}
+
+// handleListProjects handles the list_projects tool
+func (s *MCPServer) handleListProjects(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	includeArchived, _ := args["include_archived"].(bool)
+
+	projects, err := s.storage.ListProjects(ctx, includeArchived)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving projects: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	result, _ := json.MarshalIndent(ProjectListResponse{Projects: projects, Count: len(projects)}, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// handleUpdateProject handles the update_project tool
+func (s *MCPServer) handleUpdateProject(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	existing, err := s.storage.GetProject(ctx, id)
+	if err != nil {
+		return projectErrorResponse("retrieving", err), nil
+	}
+
+	// Update fields if provided
+	updated := *existing
+	if name, ok := args["name"].(string); ok && name != "" {
+		updated.Name = name
+	}
+	if description, ok := args["description"].(string); ok {
+		updated.Description = description
+	}
+	if archived, ok := args["archived"].(bool); ok {
+		updated.Archived = archived
+	}
+
+	if err := s.storage.UpdateProject(ctx, id, &updated); err != nil {
+		return projectErrorResponse("updating", err), nil
+	}
+
+	result, _ := json.MarshalIndent(&updated, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Project updated successfully:\n%s", string(result)),
+		}},
+	}, nil
+}
+
+// handleDeleteProject handles the delete_project tool
+func (s *MCPServer) handleDeleteProject(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	if err := s.storage.DeleteProject(ctx, id); err != nil {
+		return projectErrorResponse("deleting", err), nil
+	}
+
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Project with ID %d deleted successfully", id),
+		}},
+	}, nil
+}
+
+// projectErrorResponse reports a failed project operation
+func projectErrorResponse(action string, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error %s project: %v", action, err)
+	switch {
+	case errors.Is(err, storage.ErrInvalidProject):
+		text = "Error: name is required and must be a non-empty string"
+	case errors.Is(err, storage.ErrProjectNotFound), errors.Is(err, storage.ErrProjectExists):
+		text = "Error: " + err.Error()
+	case errors.Is(err, storage.ErrProjectNotEmpty):
+		text = "Error: " + err.Error() + "; move or purge its todos first, or archive it with update_project"
+	case errors.Is(err, storage.ErrVersionConflict):
+		text = "Error: project was modified concurrently; retry"
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
+
+// handleProjectsResource handles the projects resource
+func (s *MCPServer) handleProjectsResource(ctx context.Context) (*ReadResourceResponse, error) {
+	projects, err := s.storage.ListProjects(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving projects: %w", err)
+	}
+
+	result, err := json.MarshalIndent(ProjectListResponse{Projects: projects, Count: len(projects)}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling projects: %w", err)
+	}
+
+	return &ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      ResourceProjects,
+				MimeType: "application/json",
+				Text:     string(result),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// recurrenceArg reads the recurrence rule args[name]. set reports whether
+// the argument was given; a rule with an empty frequency comes back as nil
+// with set true, which stops a todo recurring.
+func recurrenceArg(args map[string]interface{}, name string) (rule *models.Recurrence, set bool, err error) {
+	value, ok := args[name]
+	if !ok {
+		return nil, false, nil
+	}
+	if value == nil {
+		return nil, true, nil
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("%s must be an object", name)
+	}
+	// Round-trip through JSON so the rule is decoded like REST bodies are
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", name, err)
+	}
+	rule = &models.Recurrence{}
+	if err := json.Unmarshal(data, rule); err != nil {
+		return nil, false, fmt.Errorf("%s must be a recurrence rule: %w", name, err)
+	}
+	if rule.Frequency == "" {
+		return nil, true, nil
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, false, err
+	}
+	return rule, true, nil
+}
+
+// recurrenceSchema returns the input schema of a recurrence rule argument
+func recurrenceSchema(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"properties": map[string]interface{}{
+			"frequency": map[string]interface{}{
+				"type":        "string",
+				"enum":        models.Frequencies,
+				"description": "How the todo item comes back: daily, weekly or monthly on a schedule anchored at the due date, or after_completion to come back interval days after each completion",
+			},
+			"interval": map[string]interface{}{
+				"type":        "integer",
+				"description": "Days, weeks or months between occurrences (optional, defaults to 1)",
+			},
+			"weekdays": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string", "enum": []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}},
+				"description": "Days of the week a weekly todo comes back on (optional, defaults to the weekday it is due)",
+			},
+			"month_day": map[string]interface{}{
+				"type":        "integer",
+				"description": "Day of the month a monthly todo comes back on, or the month's last day if it is shorter (required for monthly)",
+			},
+		},
+	}
+}
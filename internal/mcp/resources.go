@@ -1,15 +1,17 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
 )
 
 // handleTodosListResource handles the todos list resource
-func (s *MCPServer) handleTodosListResource() (*ReadResourceResponse, error) {
-	todos, err := s.storage.GetAll()
+func (s *MCPServer) handleTodosListResource(ctx context.Context) (*ReadResourceResponse, error) {
+	todos, err := s.storage.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving todos: %w", err)
 	}
@@ -21,14 +23,7 @@ func (s *MCPServer) handleTodosListResource() (*ReadResourceResponse, error) {
 	}
 
 	for i, todo := range todos {
-		todoListResp.Todos[i] = TodoResponse{
-			ID:          todo.ID,
-			Title:       todo.Title,
-			Description: todo.Description,
-			Status:      string(todo.Status),
-			CreatedAt:   todo.CreatedAt,
-			UpdatedAt:   todo.UpdatedAt,
-		}
+		todoListResp.Todos[i] = newTodoResponse(todo)
 	}
 
 	result, err := json.MarshalIndent(todoListResp, "", "  ")
@@ -47,12 +42,14 @@ func (s *MCPServer) handleTodosListResource() (*ReadResourceResponse, error) {
 	}, nil
 }
 
-// handleTodosPendingResource handles the pending todos resource
-func (s *MCPServer) handleTodosPendingResource() (*ReadResourceResponse, error) {
-	todos, err := s.storage.GetByStatus(models.StatusPending)
+// handleTodosPendingResource handles the pending todos resource: the ones
+// in a state that isn't done
+func (s *MCPServer) handleTodosPendingResource(ctx context.Context) (*ReadResourceResponse, error) {
+	page, err := s.storage.Query(ctx, storage.TodoQuery{Open: true})
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving pending todos: %w", err)
 	}
+	todos := page.Todos
 
 	// Convert to response format
 	todoListResp := TodoListResponse{
@@ -61,14 +58,7 @@ func (s *MCPServer) handleTodosPendingResource() (*ReadResourceResponse, error)
 	}
 
 	for i, todo := range todos {
-		todoListResp.Todos[i] = TodoResponse{
-			ID:          todo.ID,
-			Title:       todo.Title,
-			Description: todo.Description,
-			Status:      string(todo.Status),
-			CreatedAt:   todo.CreatedAt,
-			UpdatedAt:   todo.UpdatedAt,
-		}
+		todoListResp.Todos[i] = newTodoResponse(todo)
 	}
 
 	result, err := json.MarshalIndent(todoListResp, "", "  ")
@@ -87,12 +77,14 @@ func (s *MCPServer) handleTodosPendingResource() (*ReadResourceResponse, error)
 	}, nil
 }
 
-// handleTodosCompletedResource handles the completed todos resource
-func (s *MCPServer) handleTodosCompletedResource() (*ReadResourceResponse, error) {
-	todos, err := s.storage.GetByStatus(models.StatusCompleted)
+// handleTodosCompletedResource handles the completed todos resource: the
+// ones in any done state
+func (s *MCPServer) handleTodosCompletedResource(ctx context.Context) (*ReadResourceResponse, error) {
+	page, err := s.storage.Query(ctx, storage.TodoQuery{Statuses: s.workflow.DoneStatuses()})
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving completed todos: %w", err)
 	}
+	todos := page.Todos
 
 	// Convert to response format
 	todoListResp := TodoListResponse{
@@ -101,14 +93,7 @@ func (s *MCPServer) handleTodosCompletedResource() (*ReadResourceResponse, error
 	}
 
 	for i, todo := range todos {
-		todoListResp.Todos[i] = TodoResponse{
-			ID:          todo.ID,
-			Title:       todo.Title,
-			Description: todo.Description,
-			Status:      string(todo.Status),
-			CreatedAt:   todo.CreatedAt,
-			UpdatedAt:   todo.UpdatedAt,
-		}
+		todoListResp.Todos[i] = newTodoResponse(todo)
 	}
 
 	result, err := json.MarshalIndent(todoListResp, "", "  ")
@@ -126,3 +111,100 @@ func (s *MCPServer) handleTodosCompletedResource() (*ReadResourceResponse, error
 		},
 	}, nil
 }
+
+// priorityResourceURI returns the URI of the resource listing the todos
+// with the given priority
+func priorityResourceURI(priority models.Priority) string {
+	return ResourceTodosPriority + string(priority)
+}
+
+// priorityResourceHandler returns the handler of the resource listing the
+// todos with the given priority
+func (s *MCPServer) priorityResourceHandler(priority models.Priority) ResourceHandler {
+	return func(ctx context.Context) (*ReadResourceResponse, error) {
+		page, err := s.storage.Query(ctx, storage.TodoQuery{Priorities: []models.Priority{priority}})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving %s priority todos: %w", priority, err)
+		}
+		todos := page.Todos
+
+		// Convert to response format
+		todoListResp := TodoListResponse{
+			Todos: make([]TodoResponse, len(todos)),
+			Count: len(todos),
+		}
+
+		for i, todo := range todos {
+			todoListResp.Todos[i] = newTodoResponse(todo)
+		}
+
+		result, err := json.MarshalIndent(todoListResp, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s priority todos: %w", priority, err)
+		}
+
+		return &ReadResourceResponse{
+			Contents: []ResourceContent{
+				{
+					URI:      priorityResourceURI(priority),
+					MimeType: "application/json",
+					Text:     string(result),
+				},
+			},
+		}, nil
+	}
+}
+
+// dueResources are the resources listing the open todos of each due view
+var dueResources = []struct {
+	uri         string
+	view        string
+	name        string
+	description string
+}{
+	{ResourceTodosOverdue, storage.DueOverdue, "Overdue Todos", "Get open todo items past their due date"},
+	{ResourceTodosDueToday, storage.DueToday, "Todos Due Today", "Get open todo items due today"},
+	{ResourceTodosDueThisWeek, storage.DueThisWeek, "Todos Due This Week", "Get open todo items due this week, Monday to Sunday"},
+}
+
+// dueResourceHandler returns the handler of the resource at uri listing the
+// open todos of a due view, soonest due first
+func (s *MCPServer) dueResourceHandler(uri, view string) ResourceHandler {
+	return func(ctx context.Context) (*ReadResourceResponse, error) {
+		query := storage.TodoQuery{SortBy: storage.SortByDueAt}
+		if err := query.SetDueView(view, s.dates.Now()); err != nil {
+			return nil, err
+		}
+
+		page, err := s.storage.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving %s todos: %w", view, err)
+		}
+		todos := page.Todos
+
+		// Convert to response format
+		todoListResp := TodoListResponse{
+			Todos: make([]TodoResponse, len(todos)),
+			Count: len(todos),
+		}
+
+		for i, todo := range todos {
+			todoListResp.Todos[i] = newTodoResponse(todo)
+		}
+
+		result, err := json.MarshalIndent(todoListResp, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s todos: %w", view, err)
+		}
+
+		return &ReadResourceResponse{
+			Contents: []ResourceContent{
+				{
+					URI:      uri,
+					MimeType: "application/json",
+					Text:     string(result),
+				},
+			},
+		}, nil
+	}
+}
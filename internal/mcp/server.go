@@ -1,35 +1,69 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/shghadge/todo_mcp/internal/dates"
+	"github.com/shghadge/todo_mcp/internal/models"
 	"github.com/shghadge/todo_mcp/internal/storage"
 )
 
 // MCPServer represents the MCP server
 type MCPServer struct {
 	storage     storage.TodoStorage
+	journal     *storage.Journal
+	workflow    *models.Workflow
+	blobs       *storage.BlobStore
+	dates       *dates.Parser
 	tools       map[string]ToolHandler
 	resources   map[string]ResourceHandler
 	initialized bool
+
+	// clientName is the client's name from initialize, recorded as the
+	// actor of the changes its tool calls make
+	clientName string
+
+	// session scopes undo and redo to this connection, so that two
+	// sessions of the same client don't undo each other's changes
+	session string
+
+	// pending holds the cancel functions of requests that have been read
+	// but not yet answered, keyed by request ID
+	pendingMutex sync.Mutex
+	pending      map[string]context.CancelFunc
 }
 
 // ToolHandler represents a function that handles tool calls
-type ToolHandler func(args map[string]interface{}) (*CallToolResponse, error)
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error)
 
 // ResourceHandler represents a function that handles resource requests
-type ResourceHandler func() (*ReadResourceResponse, error)
+type ResourceHandler func(ctx context.Context) (*ReadResourceResponse, error)
 
-// NewMCPServer creates a new MCP server
-func NewMCPServer(storage storage.TodoStorage) *MCPServer {
+// NewMCPServer creates a new MCP server. Changes made through it are
+// journaled so that the session can undo them, statuses are checked against
+// workflow, which must be the one todoStorage follows, the content of
+// attachments is read from blobs, and the dates in tool arguments are
+// resolved by parser.
+func NewMCPServer(todoStorage storage.TodoStorage, workflow *models.Workflow, blobs *storage.BlobStore, parser *dates.Parser) *MCPServer {
+	journal := storage.NewJournal(todoStorage)
 	server := &MCPServer{
-		storage:     storage,
+		storage:     journal,
+		journal:     journal,
+		workflow:    models.WorkflowOrDefault(workflow),
+		blobs:       blobs,
+		dates:       parser,
 		tools:       make(map[string]ToolHandler),
 		resources:   make(map[string]ResourceHandler),
 		initialized: false,
+		pending:     make(map[string]context.CancelFunc),
+		session:     newSession(),
 	}
 
 	server.registerTools()
@@ -38,8 +72,9 @@ func NewMCPServer(storage storage.TodoStorage) *MCPServer {
 	return server
 }
 
-// HandleRequest handles an incoming JSON-RPC request
-func (s *MCPServer) HandleRequest(request *JSONRPCRequest) *JSONRPCResponse {
+// HandleRequest handles an incoming JSON-RPC request. The context is passed
+// down to storage so a cancelled request stops its storage calls.
+func (s *MCPServer) HandleRequest(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
 	var result interface{}
 	var err error
 
@@ -49,14 +84,18 @@ func (s *MCPServer) HandleRequest(request *JSONRPCRequest) *JSONRPCResponse {
 	case MethodInitialized:
 		// No response needed for initialized notification
 		return nil
+	case MethodCancelled:
+		// Cancellation is handled as requests are read; see ProcessInput
+		return nil
 	case MethodListTools:
 		result, err = s.handleListTools()
 	case MethodCallTool:
-		result, err = s.handleCallTool(request.Params)
+		ctx = storage.WithSession(storage.WithActor(ctx, s.actor()), s.session)
+		result, err = s.handleCallTool(ctx, request.Params)
 	case MethodListResources:
-		result, err = s.handleListResources()
+		result, err = s.handleListResources(ctx)
 	case MethodReadResource:
-		result, err = s.handleReadResource(request.Params)
+		result, err = s.handleReadResource(ctx, request.Params)
 	case MethodPing:
 		result = map[string]interface{}{"message": "pong"}
 	default:
@@ -88,10 +127,105 @@ func (s *MCPServer) HandleRequest(request *JSONRPCRequest) *JSONRPCResponse {
 	}
 }
 
-// ProcessInput processes input from stdin (for stdio transport)
+// queuedRequest is a request waiting to be handled along with its context
+type queuedRequest struct {
+	ctx     context.Context
+	request *JSONRPCRequest
+}
+
+// requestQueue is an unbounded FIFO of requests. Pushing never blocks, so
+// the reader keeps reading cancellations however far the handler is
+// behind.
+type requestQueue struct {
+	mutex    sync.Mutex
+	ready    sync.Cond
+	requests []queuedRequest
+	closed   bool
+}
+
+func newRequestQueue() *requestQueue {
+	q := &requestQueue{}
+	q.ready.L = &q.mutex
+	return q
+}
+
+// push adds a request to the end of the queue
+func (q *requestQueue) push(queued queuedRequest) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.requests = append(q.requests, queued)
+	q.ready.Signal()
+}
+
+// close lets pop return false once the queued requests are taken
+func (q *requestQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.ready.Signal()
+}
+
+// pop waits for the next request, reporting false once the queue is
+// closed and empty
+func (q *requestQueue) pop() (queuedRequest, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.requests) == 0 && !q.closed {
+		q.ready.Wait()
+	}
+	if len(q.requests) == 0 {
+		return queuedRequest{}, false
+	}
+	queued := q.requests[0]
+	q.requests[0] = queuedRequest{}
+	q.requests = q.requests[1:]
+	return queued, true
+}
+
+// ProcessInput processes input from stdin (for stdio transport). Requests
+// are handled one at a time in the order they arrive, while reading carries
+// on so that a notifications/cancelled message can cancel a request that
+// is queued or still running.
 func (s *MCPServer) ProcessInput(input io.Reader, output io.Writer) {
 	decoder := json.NewDecoder(input)
 	encoder := json.NewEncoder(output)
+	var encoderMutex sync.Mutex
+
+	send := func(response *JSONRPCResponse) {
+		encoderMutex.Lock()
+		defer encoderMutex.Unlock()
+		if err := encoder.Encode(response); err != nil {
+			log.Printf("Error encoding response: %v", err)
+		}
+	}
+
+	queue := newRequestQueue()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			queued, ok := queue.pop()
+			if !ok {
+				return
+			}
+
+			// A request cancelled while it waited isn't started at all
+			var response *JSONRPCResponse
+			if queued.ctx.Err() == nil {
+				response = s.HandleRequest(queued.ctx, queued.request)
+			}
+			cancelled := queued.ctx.Err() != nil
+			s.finishRequest(queued.request.ID)
+
+			// Cancelled requests get no response, as the protocol asks
+			if response != nil && !cancelled {
+				send(response)
+			}
+		}
+	}()
 
 	for {
 		var request JSONRPCRequest
@@ -100,24 +234,103 @@ func (s *MCPServer) ProcessInput(input io.Reader, output io.Writer) {
 				break
 			}
 			// Send error response
-			response := &JSONRPCResponse{
+			send(&JSONRPCResponse{
 				JSONRPC: "2.0",
 				ID:      nil,
 				Error: &JSONRPCError{
 					Code:    ParseError,
 					Message: "Parse error",
 				},
-			}
-			encoder.Encode(response)
+			})
 			continue
 		}
 
-		response := s.HandleRequest(&request)
-		if response != nil {
-			if err := encoder.Encode(response); err != nil {
-				log.Printf("Error encoding response: %v", err)
-			}
+		if request.Method == MethodCancelled {
+			s.cancelRequest(request.Params)
+			continue
 		}
+
+		ctx, ok := s.startRequest(request.ID)
+		if !ok {
+			// Answering the duplicate would be ambiguous, and tracking it
+			// would lose the way to cancel the request already using the ID
+			send(&JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      request.ID,
+				Error: &JSONRPCError{
+					Code:    InvalidRequest,
+					Message: "Invalid request",
+					Data:    "a request with this ID is still in progress",
+				},
+			})
+			continue
+		}
+		queue.push(queuedRequest{ctx: ctx, request: &request})
+	}
+
+	queue.close()
+	<-done
+}
+
+// requestKey returns the key under which a request ID is tracked: its JSON
+// encoding, so that the number 1 and the string "1" are different IDs
+func requestKey(id interface{}) string {
+	key, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Sprintf("%T:%v", id, id)
+	}
+	return string(key)
+}
+
+// startRequest returns a cancellable context for a request with an ID.
+// Notifications have no ID and can't be cancelled. It reports false, and
+// tracks nothing, if a request with the same ID is still pending.
+func (s *MCPServer) startRequest(id interface{}) (context.Context, bool) {
+	if id == nil {
+		return context.Background(), true
+	}
+
+	key := requestKey(id)
+	s.pendingMutex.Lock()
+	defer s.pendingMutex.Unlock()
+	if _, exists := s.pending[key]; exists {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.pending[key] = cancel
+	return ctx, true
+}
+
+// finishRequest releases the context of a handled request
+func (s *MCPServer) finishRequest(id interface{}) {
+	if id == nil {
+		return
+	}
+
+	s.pendingMutex.Lock()
+	cancel, exists := s.pending[requestKey(id)]
+	delete(s.pending, requestKey(id))
+	s.pendingMutex.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// cancelRequest handles a notifications/cancelled message
+func (s *MCPServer) cancelRequest(params json.RawMessage) {
+	var notification CancelledNotification
+	if err := json.Unmarshal(params, &notification); err != nil || notification.RequestID == nil {
+		log.Printf("Invalid cancellation notification: %s", string(params))
+		return
+	}
+
+	s.pendingMutex.Lock()
+	cancel, exists := s.pending[requestKey(notification.RequestID)]
+	s.pendingMutex.Unlock()
+
+	if exists {
+		cancel()
 	}
 }
 
@@ -129,6 +342,8 @@ func (s *MCPServer) handleInitialize(params json.RawMessage) (*InitializeRespons
 	}
 
 	s.initialized = true
+	s.clientName = req.ClientInfo.Name
+	s.session = newSession()
 
 	return &InitializeResponse{
 		ProtocolVersion: "2024-11-05",
@@ -149,6 +364,23 @@ func (s *MCPServer) handleInitialize(params json.RawMessage) (*InitializeRespons
 	}, nil
 }
 
+// sessions counts the MCP sessions started by this process
+var sessions atomic.Uint64
+
+// newSession names a new session, which starts with empty undo and redo
+// stacks
+func newSession() string {
+	return fmt.Sprintf("mcp session %d", sessions.Add(1))
+}
+
+// actor returns the name recorded in todo history for this client
+func (s *MCPServer) actor() string {
+	if s.clientName == "" {
+		return "mcp"
+	}
+	return s.clientName
+}
+
 // handleListTools handles the tools/list request
 func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 	tools := []Tool{
@@ -166,6 +398,42 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 						"type":        "string",
 						"description": "Optional description of the todo item",
 					},
+					"priority": map[string]interface{}{
+						"type":        "string",
+						"description": "Priority of the todo item (default 'none')",
+						"enum":        models.Priorities,
+					},
+					"start_at": map[string]interface{}{
+						"type":        "string",
+						"description": "When work on the todo item starts: an RFC 3339 time or an expression like 'tomorrow 9am' or 'next monday' (optional)",
+					},
+					"due_at": map[string]interface{}{
+						"type":        "string",
+						"description": "When the todo item is due: an RFC 3339 time or an expression like 'friday 5pm' or 'in 3 days', where a day without a time means the end of that day; not before start_at (optional)",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Tags of the todo item; case is ignored (optional)",
+					},
+					"project_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the project the todo item belongs to (optional)",
+					},
+					"parent_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the todo item this one is a subtask of (optional)",
+					},
+					"blocked_by": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "IDs of the todo items that must be completed before this one (optional)",
+					},
+					"recurrence": recurrenceSchema("Rule making the todo item come back once completed: a new pending copy is created, due on the next date of the schedule (optional)"),
+					"estimate_minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many minutes the work is expected to take, to compare with the time tracked (optional)",
+					},
 				},
 				"required": []string{"title"},
 			},
@@ -186,14 +454,109 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 		},
 		{
 			Name:        ToolGetTodos,
-			Description: "Get all todo items, optionally filtered by status",
+			Description: "Get todo items, optionally filtered, sorted and paginated. Dates are RFC 3339 times or expressions like 'yesterday' or 'in 3 days'",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"status": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": s.workflow.Statuses()},
+						"description": "Only todos in any of these statuses (optional)",
+					},
+					"priority": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by priority (optional)",
+						"enum":        models.Priorities,
+					},
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos whose title contains this text, ignoring case (optional)",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos whose description contains this text, ignoring case (optional)",
+					},
+					"text": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos whose title or description contains this text, ignoring case (optional)",
+					},
+					"created_since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos created at or after this date (optional)",
+					},
+					"created_before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos created before this date (optional)",
+					},
+					"updated_since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos updated at or after this date (optional)",
+					},
+					"updated_before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos updated before this date (optional)",
+					},
+					"due_since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos due at or after this date (optional)",
+					},
+					"due_before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only todos due before this date (optional)",
+					},
+					"tags_any": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only todos with at least one of these tags (optional)",
+					},
+					"tags_all": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only todos with all of these tags (optional)",
+					},
+					"tags_none": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only todos with none of these tags (optional)",
+					},
+					"project_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only todos in this project, or -1 for todos in no project (optional)",
+					},
+					"include_archived": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return todos of archived projects, which are otherwise left out unless project_id names one (optional)",
+					},
+					"parent_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only the direct subtasks of this todo, or -1 for top-level todos (optional)",
+					},
+					"ready": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only todos that aren't completed and whose blockers are all completed: the ones to work on next (optional)",
+					},
+					"due": map[string]interface{}{
+						"type":        "string",
+						"description": "Only open todos that are overdue, due today or due this week (optional)",
+						"enum":        storage.DueViews,
+					},
+					"sort_by": map[string]interface{}{
+						"type":        "string",
+						"description": "Field to sort by (default 'id')",
+						"enum":        storage.SortFields,
+					},
+					"order": map[string]interface{}{
+						"type":        "string",
+						"description": "Sort order (default 'asc')",
+						"enum":        []string{"asc", "desc"},
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of todos to return; the response's next_cursor fetches the next page (optional)",
+					},
+					"cursor": map[string]interface{}{
 						"type":        "string",
-						"description": "Filter by status: 'pending' or 'completed' (optional)",
-						"enum":        []string{"pending", "completed"},
+						"description": "next_cursor from a previous call with the same arguments, to continue from there (optional)",
 					},
 				},
 			},
@@ -208,6 +571,10 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 						"type":        "integer",
 						"description": "The ID of the todo item to update",
 					},
+					"version": map[string]interface{}{
+						"type":        "integer",
+						"description": "Version of the todo the update is based on; the update fails if the todo has changed since. Without it the fields given overwrite the todo's current state (optional)",
+					},
 					"title": map[string]interface{}{
 						"type":        "string",
 						"description": "New title for the todo item",
@@ -218,8 +585,48 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 					},
 					"status": map[string]interface{}{
 						"type":        "string",
-						"description": "New status for the todo item",
-						"enum":        []string{"pending", "completed"},
+						"description": "New status for the todo item; a todo item can move from " + s.transitionsDescription(),
+						"enum":        s.workflow.Statuses(),
+					},
+					"priority": map[string]interface{}{
+						"type":        "string",
+						"description": "New priority for the todo item",
+						"enum":        models.Priorities,
+					},
+					"start_at": map[string]interface{}{
+						"type":        "string",
+						"description": "New start time, as for create_todo, or an empty string to clear it",
+					},
+					"due_at": map[string]interface{}{
+						"type":        "string",
+						"description": "New due time, as for create_todo, or an empty string to clear it",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "New tags, replacing all current ones; an empty list removes them",
+					},
+					"project_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the project to move the todo item to, or 0 to remove it from its project",
+					},
+					"parent_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the todo item to make this one a subtask of, or 0 to make it top-level; completing a todo item completes its open subtasks too",
+					},
+					"blocked_by": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "IDs of the todo items that must be completed before this one, replacing the current ones; [] removes them all",
+					},
+					"recurrence": recurrenceSchema("Rule making the todo item come back once completed, replacing the current one; {} stops it recurring"),
+					"estimate_minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many minutes the work is expected to take, or 0 to clear the estimate",
+					},
+					"force": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Complete the todo item even though some of its blockers are still open, or move it to a status its current one doesn't lead to (optional)",
 					},
 				},
 				"required": []string{"id"},
@@ -227,7 +634,7 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 		},
 		{
 			Name:        ToolDeleteTodo,
-			Description: "Delete a todo item by ID",
+			Description: "Move a todo item and its subtasks to the trash by ID; they can be restored with restore_todo",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -239,13 +646,491 @@ func (s *MCPServer) handleListTools() (*ListToolsResponse, error) {
 				"required": []string{"id"},
 			},
 		},
+		{
+			Name:        ToolGetTodoHistory,
+			Description: "Get the change history of a todo item: who created, updated, deleted or restored it, when, and which fields changed",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolUndo,
+			Description: "Undo the most recent changes made in this session, newest first: creates, updates, deletes and restores",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many changes to undo (default 1)",
+						"minimum":     1,
+					},
+				},
+			},
+		},
+		{
+			Name:        ToolRedo,
+			Description: "Redo changes undone in this session, most recently undone first",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many changes to redo (default 1)",
+						"minimum":     1,
+					},
+				},
+			},
+		},
+		{
+			Name:        ToolListTrash,
+			Description: "List the todo items in the trash",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        ToolRestore,
+			Description: "Restore a todo item from the trash by ID, along with the subtasks trashed with it",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the trashed todo item to restore",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolEmptyTrash,
+			Description: "Permanently delete todo items from the trash: one by ID, those trashed before a time, or all of them",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only delete the trashed todo item with this ID (optional)",
+					},
+					"before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete todo items trashed before this time: an RFC 3339 time or an expression like '30 days ago' (optional)",
+					},
+				},
+			},
+		},
+		{
+			Name:        ToolListTags,
+			Description: "List every tag in use with the number of todo items carrying it",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        ToolRenameTag,
+			Description: "Rename a tag on every todo item carrying it, including those in the trash; fails if the new name is already in use, see merge_tags",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "The tag to rename",
+					},
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "The new name of the tag",
+					},
+				},
+				"required": []string{"from", "to"},
+			},
+		},
+		{
+			Name:        ToolMergeTags,
+			Description: "Merge one tag into another: every todo item tagged 'from' is tagged 'into' instead",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "The tag to merge away",
+					},
+					"into": map[string]interface{}{
+						"type":        "string",
+						"description": "The tag to merge it into",
+					},
+				},
+				"required": []string{"from", "into"},
+			},
+		},
+		{
+			Name:        ToolCreateProject,
+			Description: "Create a new project to group todo items in",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "The name of the project, unique ignoring case",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional description of the project",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        ToolListProjects,
+			Description: "List projects",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"include_archived": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also list archived projects (optional)",
+					},
+				},
+			},
+		},
+		{
+			Name:        ToolUpdateProject,
+			Description: "Update a project; archiving it hides its todo items from get_todos unless asked for",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the project to update",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "New name for the project",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "New description for the project",
+					},
+					"archived": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether the project is archived",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolDeleteProject,
+			Description: "Delete a project; fails while any todo item, including those in the trash, belongs to it",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the project to delete",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolAddSubtasks,
+			Description: "Break a todo item down by adding pending subtasks to it; they join its project. Returns the todo item with its subtasks and progress",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"parent_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item to break down",
+					},
+					"titles": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The titles of the subtasks, in order",
+					},
+				},
+				"required": []string{"parent_id", "titles"},
+			},
+		},
+		{
+			Name:        ToolGetSubtasks,
+			Description: "Get a todo item with its subtasks and how many of them are completed",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"tree": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include the subtasks of subtasks at any depth, not just the direct ones (optional)",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolAddBlocker,
+			Description: "Mark a todo item as blocked by another one, which must be completed first; fails if the blocker already depends on the todo item",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the blocked todo item",
+					},
+					"blocker_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item blocking it",
+					},
+				},
+				"required": []string{"id", "blocker_id"},
+			},
+		},
+		{
+			Name:        ToolRemoveBlocker,
+			Description: "Stop a todo item from being blocked by another one",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the blocked todo item",
+					},
+					"blocker_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item to remove from its blockers",
+					},
+				},
+				"required": []string{"id", "blocker_id"},
+			},
+		},
+		{
+			Name:        ToolStartTimer,
+			Description: "Start timing work on a todo item; each todo item runs at most one timer",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item being worked on",
+					},
+					"note": map[string]interface{}{
+						"type":        "string",
+						"description": "What the work is about (optional)",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolStopTimer,
+			Description: "Stop the running timer of a todo item, adding the time to the time tracked on it",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolLogTime,
+			Description: "Record time spent on a todo item after the fact, given either its start or its length in minutes. Dates are RFC 3339 times or expressions like 'today 9am'",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item worked on",
+					},
+					"start": map[string]interface{}{
+						"type":        "string",
+						"description": "When the work started (give this or minutes)",
+					},
+					"minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many minutes the work took (give this or start)",
+					},
+					"end": map[string]interface{}{
+						"type":        "string",
+						"description": "When the work ended (optional, defaults to now)",
+					},
+					"note": map[string]interface{}{
+						"type":        "string",
+						"description": "What the work was about (optional)",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolGetTime,
+			Description: "Get the time entries of a todo item with the total time spent, running timer included, against its estimate",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolTimeReport,
+			Description: "Report the time spent on each todo item within a period, by default this week, most time first",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"since": map[string]interface{}{
+						"type":        "string",
+						"description": "Start of the period, e.g. 'last monday' (optional, defaults to the start of this week)",
+					},
+					"before": map[string]interface{}{
+						"type":        "string",
+						"description": "End of the period (optional, defaults to a week after since)",
+					},
+				},
+			},
+		},
+		{
+			Name:        ToolAddComment,
+			Description: "Add a comment to the thread of a todo item, e.g. to log progress without overwriting its description",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"body": map[string]interface{}{
+						"type":        "string",
+						"description": "The text of the comment",
+					},
+				},
+				"required": []string{"id", "body"},
+			},
+		},
+		{
+			Name:        ToolListComments,
+			Description: "List the comments on a todo item, oldest first",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        ToolAddChecklistItem,
+			Description: "Add a step to the checklist of a todo item, for work too small to be a subtask",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"text": map[string]interface{}{
+						"type":        "string",
+						"description": "What the step is",
+					},
+					"position": map[string]interface{}{
+						"type":        "integer",
+						"description": "Where to insert the step, 1 being first (optional, defaults to the end)",
+					},
+				},
+				"required": []string{"id", "text"},
+			},
+		},
+		{
+			Name:        ToolToggleChecklistItem,
+			Description: "Check or uncheck a step of the checklist of a todo item",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"item_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the checklist item",
+					},
+					"done": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether the step is done (optional, flips it if omitted)",
+					},
+				},
+				"required": []string{"id", "item_id"},
+			},
+		},
+		{
+			Name:        ToolMoveChecklistItem,
+			Description: "Move a step of the checklist of a todo item to another position",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"item_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the checklist item",
+					},
+					"position": map[string]interface{}{
+						"type":        "integer",
+						"description": "The step's new position, 1 being first",
+					},
+				},
+				"required": []string{"id", "item_id", "position"},
+			},
+		},
+		{
+			Name:        ToolRemoveChecklistItem,
+			Description: "Remove a step from the checklist of a todo item",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the todo item",
+					},
+					"item_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the checklist item",
+					},
+				},
+				"required": []string{"id", "item_id"},
+			},
+		},
 	}
 
 	return &ListToolsResponse{Tools: tools}, nil
 }
 
 // handleCallTool handles tool calls
-func (s *MCPServer) handleCallTool(params json.RawMessage) (*CallToolResponse, error) {
+func (s *MCPServer) handleCallTool(ctx context.Context, params json.RawMessage) (*CallToolResponse, error) {
 	var req CallToolRequest
 	if err := json.Unmarshal(params, &req); err != nil {
 		return nil, fmt.Errorf("invalid call tool request: %w", err)
@@ -256,11 +1141,11 @@ func (s *MCPServer) handleCallTool(params json.RawMessage) (*CallToolResponse, e
 		return nil, fmt.Errorf("unknown tool: %s", req.Name)
 	}
 
-	return handler(req.Arguments)
+	return handler(ctx, req.Arguments)
 }
 
 // handleListResources handles the resources/list request
-func (s *MCPServer) handleListResources() (*ListResourcesResponse, error) {
+func (s *MCPServer) handleListResources(ctx context.Context) (*ListResourcesResponse, error) {
 	resources := []Resource{
 		{
 			URI:         ResourceTodosList,
@@ -271,22 +1156,81 @@ func (s *MCPServer) handleListResources() (*ListResourcesResponse, error) {
 		{
 			URI:         ResourceTodosPending,
 			Name:        "Pending Todos",
-			Description: "Get all pending todo items",
+			Description: "Get all todo items that aren't done yet, whatever their status",
 			MimeType:    "application/json",
 		},
 		{
 			URI:         ResourceTodosCompleted,
 			Name:        "Completed Todos",
-			Description: "Get all completed todo items",
+			Description: "Get all done todo items, whatever their status",
 			MimeType:    "application/json",
 		},
 	}
 
+	for _, state := range s.workflow.States {
+		description := fmt.Sprintf("Get all todo items with status %s", state.Name)
+		if state.Description != "" {
+			description += ": " + state.Description
+		}
+		resources = append(resources, Resource{
+			URI:         statusResourceURI(state.Name),
+			Name:        fmt.Sprintf("Todos with Status %s", state.Name),
+			Description: description,
+			MimeType:    "application/json",
+		})
+	}
+
+	for _, priority := range models.Priorities {
+		resources = append(resources, Resource{
+			URI:         priorityResourceURI(priority),
+			Name:        fmt.Sprintf("%s Priority Todos", strings.ToUpper(string(priority[:1]))+string(priority[1:])),
+			Description: fmt.Sprintf("Get all todo items with %s priority", priority),
+			MimeType:    "application/json",
+		})
+	}
+
+	for _, due := range dueResources {
+		resources = append(resources, Resource{
+			URI:         due.uri,
+			Name:        due.name,
+			Description: due.description,
+			MimeType:    "application/json",
+		})
+	}
+
+	resources = append(resources, Resource{
+		URI:         ResourceTodosReady,
+		Name:        "Ready Todos",
+		Description: "Get pending todo items whose blockers are all completed, most urgent first: the ones to work on next",
+		MimeType:    "application/json",
+	}, Resource{
+		URI:         ResourceTags,
+		Name:        "Tags",
+		Description: "Get every tag in use with the number of todo items carrying it",
+		MimeType:    "application/json",
+	}, Resource{
+		URI:         ResourceProjects,
+		Name:        "Projects",
+		Description: "Get all projects, including archived ones",
+		MimeType:    "application/json",
+	}, Resource{
+		URI:         ResourceWorkflow,
+		Name:        "Workflow",
+		Description: "Get the statuses todo items move through, which of them are done, and the moves allowed between them",
+		MimeType:    "application/json",
+	})
+
+	attachments, err := s.attachmentResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources = append(resources, attachments...)
+
 	return &ListResourcesResponse{Resources: resources}, nil
 }
 
 // handleReadResource handles resource read requests
-func (s *MCPServer) handleReadResource(params json.RawMessage) (*ReadResourceResponse, error) {
+func (s *MCPServer) handleReadResource(ctx context.Context, params json.RawMessage) (*ReadResourceResponse, error) {
 	var req ReadResourceRequest
 	if err := json.Unmarshal(params, &req); err != nil {
 		return nil, fmt.Errorf("invalid read resource request: %w", err)
@@ -294,10 +1238,13 @@ func (s *MCPServer) handleReadResource(params json.RawMessage) (*ReadResourceRes
 
 	handler, exists := s.resources[req.URI]
 	if !exists {
+		if todoID, attachmentID, ok := parseAttachmentResourceURI(req.URI); ok {
+			return s.handleAttachmentResource(ctx, req.URI, todoID, attachmentID)
+		}
 		return nil, fmt.Errorf("unknown resource: %s", req.URI)
 	}
 
-	return handler()
+	return handler(ctx)
 }
 
 // registerTools registers all tool handlers
@@ -307,6 +1254,34 @@ func (s *MCPServer) registerTools() {
 	s.tools[ToolGetTodos] = s.handleGetTodos
 	s.tools[ToolUpdateTodo] = s.handleUpdateTodo
 	s.tools[ToolDeleteTodo] = s.handleDeleteTodo
+	s.tools[ToolGetTodoHistory] = s.handleGetTodoHistory
+	s.tools[ToolUndo] = s.handleUndo
+	s.tools[ToolRedo] = s.handleRedo
+	s.tools[ToolListTrash] = s.handleListTrash
+	s.tools[ToolRestore] = s.handleRestoreTodo
+	s.tools[ToolEmptyTrash] = s.handleEmptyTrash
+	s.tools[ToolListTags] = s.handleListTags
+	s.tools[ToolRenameTag] = s.handleRenameTag
+	s.tools[ToolMergeTags] = s.handleMergeTags
+	s.tools[ToolCreateProject] = s.handleCreateProject
+	s.tools[ToolListProjects] = s.handleListProjects
+	s.tools[ToolUpdateProject] = s.handleUpdateProject
+	s.tools[ToolDeleteProject] = s.handleDeleteProject
+	s.tools[ToolAddSubtasks] = s.handleAddSubtasks
+	s.tools[ToolGetSubtasks] = s.handleGetSubtasks
+	s.tools[ToolAddBlocker] = s.handleAddBlocker
+	s.tools[ToolRemoveBlocker] = s.handleRemoveBlocker
+	s.tools[ToolStartTimer] = s.handleStartTimer
+	s.tools[ToolStopTimer] = s.handleStopTimer
+	s.tools[ToolLogTime] = s.handleLogTime
+	s.tools[ToolGetTime] = s.handleGetTime
+	s.tools[ToolTimeReport] = s.handleTimeReport
+	s.tools[ToolAddComment] = s.handleAddComment
+	s.tools[ToolListComments] = s.handleListComments
+	s.tools[ToolAddChecklistItem] = s.handleAddChecklistItem
+	s.tools[ToolToggleChecklistItem] = s.handleToggleChecklistItem
+	s.tools[ToolMoveChecklistItem] = s.handleMoveChecklistItem
+	s.tools[ToolRemoveChecklistItem] = s.handleRemoveChecklistItem
 }
 
 // registerResources registers all resource handlers
@@ -314,4 +1289,17 @@ func (s *MCPServer) registerResources() {
 	s.resources[ResourceTodosList] = s.handleTodosListResource
 	s.resources[ResourceTodosPending] = s.handleTodosPendingResource
 	s.resources[ResourceTodosCompleted] = s.handleTodosCompletedResource
+	for _, status := range s.workflow.Statuses() {
+		s.resources[statusResourceURI(status)] = s.statusResourceHandler(status)
+	}
+	for _, priority := range models.Priorities {
+		s.resources[priorityResourceURI(priority)] = s.priorityResourceHandler(priority)
+	}
+	for _, due := range dueResources {
+		s.resources[due.uri] = s.dueResourceHandler(due.uri, due.view)
+	}
+	s.resources[ResourceTodosReady] = s.handleTodosReadyResource
+	s.resources[ResourceTags] = s.handleTagsResource
+	s.resources[ResourceProjects] = s.handleProjectsResource
+	s.resources[ResourceWorkflow] = s.handleWorkflowResource
 }
@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleAddSubtasks handles the add_subtasks tool
+func (s *MCPServer) handleAddSubtasks(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	parentID, set, err := intArg(args, "parent_id")
+	if err == nil && !set {
+		err = errors.New("parent_id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	rawTitles, ok := args["titles"].([]interface{})
+	if !ok || len(rawTitles) == 0 {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: titles is required and must be a non-empty list of strings",
+			}},
+			IsError: true,
+		}, nil
+	}
+	titles := make([]string, len(rawTitles))
+	for i, raw := range rawTitles {
+		title, ok := raw.(string)
+		if !ok || strings.TrimSpace(title) == "" {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: every title must be a non-empty string",
+				}},
+				IsError: true,
+			}, nil
+		}
+		titles[i] = title
+	}
+
+	parent, err := s.storage.GetByID(ctx, parentID)
+	if err != nil {
+		return subtaskErrorResponse(parentID, err), nil
+	}
+
+	// Subtasks join their parent's project so that archiving it hides them
+	// all together
+	for _, title := range titles {
+		subtask := &models.Todo{
+			Title:     title,
+			Status:    s.workflow.Initial,
+			Priority:  models.PriorityNone,
+			ProjectID: parent.ProjectID,
+			ParentID:  parent.ID,
+		}
+		if err := s.storage.Create(ctx, subtask); err != nil {
+			return subtaskErrorResponse(parentID, err), nil
+		}
+	}
+
+	tree, err := s.todoTree(ctx, parent, false)
+	if err != nil {
+		return subtaskErrorResponse(parentID, err), nil
+	}
+
+	result, _ := json.MarshalIndent(tree, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Added %d subtasks to todo %d:\n%s", len(titles), parentID, string(result)),
+		}},
+	}, nil
+}
+
+// handleGetSubtasks handles the get_subtasks tool
+func (s *MCPServer) handleGetSubtasks(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	full, _ := args["tree"].(bool)
+
+	todo, err := s.storage.GetByID(ctx, id)
+	if err != nil {
+		return subtaskErrorResponse(id, err), nil
+	}
+	tree, err := s.todoTree(ctx, todo, full)
+	if err != nil {
+		return subtaskErrorResponse(id, err), nil
+	}
+
+	result, _ := json.MarshalIndent(tree, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// todoTree returns the todo with its direct subtasks, or with full its
+// subtasks at any depth, in response format
+func (s *MCPServer) todoTree(ctx context.Context, todo *models.Todo, full bool) (TodoTreeResponse, error) {
+	subtasks, err := s.storage.Subtasks(ctx, todo.ID)
+	if err != nil {
+		return TodoTreeResponse{}, err
+	}
+
+	tree := models.BuildTree(s.workflow, todo, subtasks)
+	if !full {
+		tree.Flatten()
+	}
+	return newTodoTreeResponse(tree), nil
+}
+
+// subtaskErrorResponse reports a failed subtask operation on a todo
+func subtaskErrorResponse(id int, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error: %v", err)
+	if errors.Is(err, storage.ErrTodoNotFound) {
+		text = fmt.Sprintf("Todo with ID %d not found", id)
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
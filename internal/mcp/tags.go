@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// tagsArg reads the list of tags in args[name], normalized. set reports
+// whether the argument was given at all, so an empty list can clear tags.
+func tagsArg(args map[string]interface{}, name string) (tags []string, set bool, err error) {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return nil, false, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("%s must be an array of strings", name)
+	}
+	raw := make([]string, len(items))
+	for i, item := range items {
+		if raw[i], ok = item.(string); !ok {
+			return nil, false, fmt.Errorf("%s must be an array of strings", name)
+		}
+	}
+
+	tags, err = models.NormalizeTags(raw)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+	return tags, true, nil
+}
+
+// handleListTags handles the list_tags tool
+func (s *MCPServer) handleListTags(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	tags, err := s.storage.Tags(ctx)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving tags: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	result, _ := json.MarshalIndent(TagListResponse{Tags: tags, Count: len(tags)}, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// handleRenameTag handles the rename_tag tool
+func (s *MCPServer) handleRenameTag(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	from, _ := args["from"].(string)
+	to, _ := args["to"].(string)
+	if from == "" || to == "" {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: from and to are required and must be non-empty strings",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	changed, err := s.storage.RenameTag(ctx, from, to)
+	if err != nil {
+		return tagErrorResponse("renaming", err), nil
+	}
+
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Tag %q renamed to %q on %d todo(s)", from, to, changed),
+		}},
+	}, nil
+}
+
+// handleMergeTags handles the merge_tags tool
+func (s *MCPServer) handleMergeTags(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	from, _ := args["from"].(string)
+	into, _ := args["into"].(string)
+	if from == "" || into == "" {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: from and into are required and must be non-empty strings",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	changed, err := s.storage.MergeTags(ctx, from, into)
+	if err != nil {
+		return tagErrorResponse("merging", err), nil
+	}
+
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Tag %q merged into %q on %d todo(s)", from, into, changed),
+		}},
+	}, nil
+}
+
+// tagErrorResponse reports a failed rename or merge
+func tagErrorResponse(action string, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error %s tag: %v", action, err)
+	switch {
+	case errors.Is(err, models.ErrInvalidTag), errors.Is(err, storage.ErrTagNotFound):
+		text = "Error: " + err.Error()
+	case errors.Is(err, storage.ErrTagExists):
+		text = "Error: " + err.Error() + "; use merge_tags to fold one tag into another"
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
+
+// handleTagsResource handles the tags resource
+func (s *MCPServer) handleTagsResource(ctx context.Context) (*ReadResourceResponse, error) {
+	tags, err := s.storage.Tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tags: %w", err)
+	}
+
+	result, err := json.MarshalIndent(TagListResponse{Tags: tags, Count: len(tags)}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling tags: %w", err)
+	}
+
+	return &ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      ResourceTags,
+				MimeType: "application/json",
+				Text:     string(result),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,246 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// dateArg resolves the date argument args[name] and records it in echo.
+// set is false when the argument is absent or empty.
+func (s *MCPServer) dateArg(args map[string]interface{}, name string, echo *dateEcho) (t time.Time, set bool, err error) {
+	value, ok := args[name].(string)
+	if !ok || value == "" {
+		return time.Time{}, false, nil
+	}
+	t, err = s.dates.Parse(value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s: %v", name, err)
+	}
+	echo.add(name, value, t)
+	return t, true, nil
+}
+
+// handleStartTimer handles the start_timer tool
+func (s *MCPServer) handleStartTimer(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	note, _ := args["note"].(string)
+	actor := storage.ActorFromContext(ctx)
+	return s.changeTime(ctx, args, "Timer started", nil, func(activity *models.Activity) error {
+		_, err := activity.StartTimer(time.Now(), actor, note)
+		return err
+	})
+}
+
+// handleStopTimer handles the stop_timer tool
+func (s *MCPServer) handleStopTimer(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	return s.changeTime(ctx, args, "Timer stopped", nil, func(activity *models.Activity) error {
+		_, err := activity.StopTimer(time.Now())
+		return err
+	})
+}
+
+// handleLogTime handles the log_time tool: work given by its start and
+// end, or by its length in minutes and its end, which defaults to now
+func (s *MCPServer) handleLogTime(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	var echo dateEcho
+	start, end, err := s.timeEntrySpan(args, &echo)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	note, _ := args["note"].(string)
+	actor := storage.ActorFromContext(ctx)
+	return s.changeTime(ctx, args, "Time logged", echo, func(activity *models.Activity) error {
+		_, err := activity.LogTime(start, end, actor, note)
+		return err
+	})
+}
+
+// timeEntrySpan resolves the start and end of a logged time entry
+func (s *MCPServer) timeEntrySpan(args map[string]interface{}, echo *dateEcho) (start, end time.Time, err error) {
+	end, set, err := s.dateArg(args, "end", echo)
+	if err != nil {
+		return start, end, err
+	}
+	if !set {
+		end = time.Now()
+	}
+	start, startSet, err := s.dateArg(args, "start", echo)
+	if err != nil {
+		return start, end, err
+	}
+	minutes, minutesSet, err := intArg(args, "minutes")
+	if err != nil {
+		return start, end, err
+	}
+
+	switch {
+	case startSet && minutesSet:
+		return start, end, errors.New("give either start or minutes, not both")
+	case startSet:
+		return start, end, nil
+	case minutes > 0:
+		return end.Add(-time.Duration(minutes) * time.Minute), end, nil
+	default:
+		return start, end, errors.New("start or a positive number of minutes is required")
+	}
+}
+
+// changeTime applies change to the time entries of the todo named by
+// args and responds with the todo's time summary. Time entries are stored
+// apart from the todo, so the change never conflicts with edits of the
+// todo itself.
+func (s *MCPServer) changeTime(ctx context.Context, args map[string]interface{}, message string, echo dateEcho, change func(*models.Activity) error) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// The todo is only read for the estimate the summary compares with
+	todo, err := s.storage.GetByID(ctx, id)
+	if err != nil {
+		return timeErrorResponse(id, err), nil
+	}
+	activity, err := s.storage.ChangeActivity(ctx, id, change)
+	if err != nil {
+		return timeErrorResponse(id, err), nil
+	}
+
+	result, _ := json.MarshalIndent(activity.TimeSummary(todo.EstimateMinutes, time.Now()), "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("%s:\n%s%s", message, string(result), echo),
+		}},
+	}, nil
+}
+
+// timeErrorResponse reports a failed change to the time entries of a todo
+func timeErrorResponse(id int, err error) *CallToolResponse {
+	text := fmt.Sprintf("Error: %v", err)
+	if errors.Is(err, storage.ErrTodoNotFound) {
+		text = fmt.Sprintf("Todo with ID %d not found", id)
+	}
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: text,
+		}},
+		IsError: true,
+	}
+}
+
+// handleGetTime handles the get_time tool
+func (s *MCPServer) handleGetTime(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	id, set, err := intArg(args, "id")
+	if err == nil && !set {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	todo, err := s.storage.GetByID(ctx, id)
+	if err != nil {
+		return timeErrorResponse(id, err), nil
+	}
+	activity, err := s.storage.Activity(ctx, id)
+	if err != nil {
+		return timeErrorResponse(id, err), nil
+	}
+
+	result, _ := json.MarshalIndent(activity.TimeSummary(todo.EstimateMinutes, time.Now()), "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// handleTimeReport handles the time_report tool: the time spent on each
+// todo between since and before, by default this week
+func (s *MCPServer) handleTimeReport(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	var echo dateEcho
+	since, set, err := s.dateArg(args, "since", &echo)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if !set {
+		since = models.StartOfWeek(s.dates.Now())
+	}
+	before, set, err := s.dateArg(args, "before", &echo)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if !set {
+		before = since.AddDate(0, 0, 7)
+	}
+
+	todos, err := s.storage.GetAll(ctx)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving todos: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+	activities, err := s.storage.Activities(ctx)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving time entries: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	result, _ := json.MarshalIndent(models.BuildTimeReport(todos, activities, since, before, time.Now()), "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result) + echo.String(),
+		}},
+	}, nil
+}
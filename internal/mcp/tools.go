@@ -1,16 +1,22 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/shghadge/todo_mcp/internal/models"
 	"github.com/shghadge/todo_mcp/internal/storage"
 )
 
+// priorityMessage explains a rejected priority
+const priorityMessage = "priority must be 'none', 'low', 'medium', 'high' or 'urgent'"
+
 // handleCreateTodo handles the create_todo tool
-func (s *MCPServer) handleCreateTodo(args map[string]interface{}) (*CallToolResponse, error) {
+func (s *MCPServer) handleCreateTodo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
 	// Extract and validate arguments
 	title, ok := args["title"].(string)
 	if !ok || title == "" {
@@ -28,14 +34,146 @@ func (s *MCPServer) handleCreateTodo(args map[string]interface{}) (*CallToolResp
 		description = desc
 	}
 
+	priority := models.PriorityNone
+	if priorityStr, ok := args["priority"].(string); ok && priorityStr != "" {
+		priority = models.Priority(priorityStr)
+		if !priority.Valid() {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + priorityMessage,
+				}},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	var echo dateEcho
+	startAt, _, err := s.scheduleArg(args, "start_at", &echo)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	dueAt, _, err := s.scheduleArg(args, "due_at", &echo)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	tags, _, err := tagsArg(args, "tags")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	projectID, _, err := intArg(args, "project_id")
+	if err == nil && projectID < 0 {
+		err = fmt.Errorf("project_id must be a positive integer")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	parentID, _, err := intArg(args, "parent_id")
+	if err == nil && parentID < 0 {
+		err = fmt.Errorf("parent_id must be a positive integer")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	blockedBy, _, err := blockersArg(args, "blocked_by")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	recurrence, _, err := recurrenceArg(args, "recurrence")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	estimate, _, err := intArg(args, "estimate_minutes")
+	if err == nil && estimate < 0 {
+		err = errors.New("estimate_minutes must not be negative")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
 	// Create todo
 	todo := &models.Todo{
-		Title:       title,
-		Description: description,
-		Status:      models.StatusPending,
+		Title:           title,
+		Description:     description,
+		Status:          s.workflow.Initial,
+		Priority:        priority,
+		StartAt:         startAt,
+		DueAt:           dueAt,
+		Tags:            tags,
+		ProjectID:       projectID,
+		ParentID:        parentID,
+		BlockedBy:       blockedBy,
+		Recurrence:      recurrence,
+		EstimateMinutes: estimate,
+	}
+	if err := todo.CheckSchedule(); err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
 	}
 
-	if err := s.storage.Create(todo); err != nil {
+	if err := s.storage.Create(ctx, todo); err != nil {
+		if errors.Is(err, storage.ErrProjectNotFound) || errors.Is(err, storage.ErrParentNotFound) || errors.Is(err, storage.ErrBlockerNotFound) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + err.Error(),
+				}},
+				IsError: true,
+			}, nil
+		}
 		return &CallToolResponse{
 			Content: []Content{{
 				Type: "text",
@@ -46,26 +184,19 @@ func (s *MCPServer) handleCreateTodo(args map[string]interface{}) (*CallToolResp
 	}
 
 	// Convert to response format
-	todoResp := TodoResponse{
-		ID:          todo.ID,
-		Title:       todo.Title,
-		Description: todo.Description,
-		Status:      string(todo.Status),
-		CreatedAt:   todo.CreatedAt,
-		UpdatedAt:   todo.UpdatedAt,
-	}
+	todoResp := newTodoResponse(todo)
 
 	result, _ := json.MarshalIndent(todoResp, "", "  ")
 	return &CallToolResponse{
 		Content: []Content{{
 			Type: "text",
-			Text: fmt.Sprintf("Todo created successfully:\n%s", string(result)),
+			Text: fmt.Sprintf("Todo created successfully:\n%s%s", string(result), echo),
 		}},
 	}, nil
 }
 
 // handleGetTodo handles the get_todo tool
-func (s *MCPServer) handleGetTodo(args map[string]interface{}) (*CallToolResponse, error) {
+func (s *MCPServer) handleGetTodo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
 	// Extract and validate ID
 	idInterface, ok := args["id"]
 	if !ok {
@@ -107,7 +238,7 @@ func (s *MCPServer) handleGetTodo(args map[string]interface{}) (*CallToolRespons
 	}
 
 	// Get todo
-	todo, err := s.storage.GetByID(id)
+	todo, err := s.storage.GetByID(ctx, id)
 	if err != nil {
 		if err == storage.ErrTodoNotFound {
 			return &CallToolResponse{
@@ -127,16 +258,21 @@ func (s *MCPServer) handleGetTodo(args map[string]interface{}) (*CallToolRespons
 		}, nil
 	}
 
-	// Convert to response format
-	todoResp := TodoResponse{
-		ID:          todo.ID,
-		Title:       todo.Title,
-		Description: todo.Description,
-		Status:      string(todo.Status),
-		CreatedAt:   todo.CreatedAt,
-		UpdatedAt:   todo.UpdatedAt,
+	activity, err := s.storage.Activity(ctx, id)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving time entries: %v", err),
+			}},
+			IsError: true,
+		}, nil
 	}
 
+	// Convert to response format
+	todoResp := newTodoResponse(todo)
+	todoResp.Time = activity.TimeStatus(todo.EstimateMinutes, time.Now())
+
 	result, _ := json.MarshalIndent(todoResp, "", "  ")
 	return &CallToolResponse{
 		Content: []Content{{
@@ -147,27 +283,19 @@ func (s *MCPServer) handleGetTodo(args map[string]interface{}) (*CallToolRespons
 }
 
 // handleGetTodos handles the get_todos tool
-func (s *MCPServer) handleGetTodos(args map[string]interface{}) (*CallToolResponse, error) {
-	var todos []*models.Todo
-	var err error
-
-	// Check if status filter is provided
-	if statusStr, ok := args["status"].(string); ok && statusStr != "" {
-		status := models.TodoStatus(statusStr)
-		if status != models.StatusPending && status != models.StatusCompleted {
-			return &CallToolResponse{
-				Content: []Content{{
-					Type: "text",
-					Text: "Error: status must be 'pending' or 'completed'",
-				}},
-				IsError: true,
-			}, nil
-		}
-		todos, err = s.storage.GetByStatus(status)
-	} else {
-		todos, err = s.storage.GetAll()
+func (s *MCPServer) handleGetTodos(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	query, err := s.parseTodoQuery(args)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error: %v", err),
+			}},
+			IsError: true,
+		}, nil
 	}
 
+	page, err := s.storage.Query(ctx, query)
 	if err != nil {
 		return &CallToolResponse{
 			Content: []Content{{
@@ -177,22 +305,17 @@ func (s *MCPServer) handleGetTodos(args map[string]interface{}) (*CallToolRespon
 			IsError: true,
 		}, nil
 	}
+	todos := page.Todos
 
 	// Convert to response format
 	todoListResp := TodoListResponse{
-		Todos: make([]TodoResponse, len(todos)),
-		Count: len(todos),
+		Todos:      make([]TodoResponse, len(todos)),
+		Count:      len(todos),
+		NextCursor: page.NextCursor,
 	}
 
 	for i, todo := range todos {
-		todoListResp.Todos[i] = TodoResponse{
-			ID:          todo.ID,
-			Title:       todo.Title,
-			Description: todo.Description,
-			Status:      string(todo.Status),
-			CreatedAt:   todo.CreatedAt,
-			UpdatedAt:   todo.UpdatedAt,
-		}
+		todoListResp.Todos[i] = newTodoResponse(todo)
 	}
 
 	result, _ := json.MarshalIndent(todoListResp, "", "  ")
@@ -204,8 +327,155 @@ func (s *MCPServer) handleGetTodos(args map[string]interface{}) (*CallToolRespon
 	}, nil
 }
 
+// statusesArg reads an array of states of the server's workflow. A single
+// string, as the argument used to be, counts as an array of one.
+func (s *MCPServer) statusesArg(args map[string]interface{}, name string) ([]models.TodoStatus, error) {
+	var items []interface{}
+	switch value := args[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if value == "" {
+			return nil, nil
+		}
+		items = []interface{}{value}
+	case []interface{}:
+		items = value
+	default:
+		return nil, fmt.Errorf("%s must be an array of strings", name)
+	}
+
+	statuses := make([]models.TodoStatus, 0, len(items))
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", name)
+		}
+		status := models.TodoStatus(str)
+		if !s.workflow.Valid(status) {
+			return nil, errors.New(s.statusMessage())
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// parseTodoQuery builds a storage query from get_todos arguments,
+// resolving dates with the server's parser
+func (s *MCPServer) parseTodoQuery(args map[string]interface{}) (storage.TodoQuery, error) {
+	var query storage.TodoQuery
+
+	statuses, err := s.statusesArg(args, "status")
+	if err != nil {
+		return query, err
+	}
+	query.Statuses = statuses
+
+	if priorityStr, ok := args["priority"].(string); ok && priorityStr != "" {
+		priority := models.Priority(priorityStr)
+		if !priority.Valid() {
+			return query, fmt.Errorf(priorityMessage)
+		}
+		query.Priorities = []models.Priority{priority}
+	}
+
+	if due, ok := args["due"].(string); ok && due != "" {
+		if err := query.SetDueView(due, s.dates.Now()); err != nil {
+			return query, err
+		}
+	}
+
+	tagFilters := []struct {
+		arg  string
+		dest *[]string
+	}{
+		{"tags_any", &query.TagsAny},
+		{"tags_all", &query.TagsAll},
+		{"tags_none", &query.TagsNone},
+	}
+	for _, f := range tagFilters {
+		tags, _, err := tagsArg(args, f.arg)
+		if err != nil {
+			return query, err
+		}
+		*f.dest = tags
+	}
+
+	projectID, _, err := intArg(args, "project_id")
+	if err != nil {
+		return query, err
+	}
+	query.ProjectID = projectID
+	query.IncludeArchived, _ = args["include_archived"].(bool)
+
+	parentID, _, err := intArg(args, "parent_id")
+	if err != nil {
+		return query, err
+	}
+	query.ParentID = parentID
+	query.Ready, _ = args["ready"].(bool)
+
+	query.Title, _ = args["title"].(string)
+	query.Description, _ = args["description"].(string)
+	query.Text, _ = args["text"].(string)
+	query.SortBy, _ = args["sort_by"].(string)
+	query.Cursor, _ = args["cursor"].(string)
+
+	times := []struct {
+		arg  string
+		dest *time.Time
+	}{
+		{"created_since", &query.CreatedSince},
+		{"created_before", &query.CreatedBefore},
+		{"updated_since", &query.UpdatedSince},
+		{"updated_before", &query.UpdatedBefore},
+		{"due_since", &query.DueSince},
+		{"due_before", &query.DueBefore},
+	}
+	for _, t := range times {
+		if value, ok := args[t.arg].(string); ok && value != "" {
+			parsed, err := s.dates.Parse(value)
+			if err != nil {
+				return query, fmt.Errorf("%s: %v", t.arg, err)
+			}
+			*t.dest = parsed
+		}
+	}
+
+	if order, ok := args["order"].(string); ok {
+		switch order {
+		case "", "asc":
+		case "desc":
+			query.Descending = true
+		default:
+			return query, fmt.Errorf("order must be 'asc' or 'desc'")
+		}
+	}
+
+	if limit, ok := args["limit"]; ok {
+		var n int
+		switch v := limit.(type) {
+		case float64:
+			n = int(v)
+			if float64(n) != v {
+				return query, fmt.Errorf("limit must be a non-negative integer")
+			}
+		case int:
+			n = v
+		default:
+			return query, fmt.Errorf("limit must be a non-negative integer")
+		}
+		if n < 0 {
+			return query, fmt.Errorf("limit must be a non-negative integer")
+		}
+		query.Limit = n
+	}
+
+	return query, query.Validate()
+}
+
 // handleUpdateTodo handles the update_todo tool
-func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResponse, error) {
+func (s *MCPServer) handleUpdateTodo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
 	// Extract and validate ID
 	idInterface, ok := args["id"]
 	if !ok {
@@ -247,7 +517,7 @@ func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResp
 	}
 
 	// Get existing todo
-	existingTodo, err := s.storage.GetByID(id)
+	existingTodo, err := s.storage.GetByID(ctx, id)
 	if err != nil {
 		if err == storage.ErrTodoNotFound {
 			return &CallToolResponse{
@@ -267,7 +537,19 @@ func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResp
 		}, nil
 	}
 
-	// Create updated todo
+	// Reject the update up front if the caller based it on an older version
+	if version, ok := args["version"].(float64); ok && int(version) != existingTodo.Version {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error: version conflict: todo %d is at version %d, not %d; fetch it again and retry", id, existingTodo.Version, int(version)),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Create updated todo; it keeps the version read above, so storage
+	// rejects the update if another writer got in between
 	updatedTodo := *existingTodo
 
 	// Update fields if provided
@@ -281,11 +563,11 @@ func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResp
 
 	if statusStr, ok := args["status"].(string); ok && statusStr != "" {
 		status := models.TodoStatus(statusStr)
-		if status != models.StatusPending && status != models.StatusCompleted {
+		if !s.workflow.Valid(status) {
 			return &CallToolResponse{
 				Content: []Content{{
 					Type: "text",
-					Text: "Error: status must be 'pending' or 'completed'",
+					Text: "Error: " + s.statusMessage(),
 				}},
 				IsError: true,
 			}, nil
@@ -293,8 +575,186 @@ func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResp
 		updatedTodo.Status = status
 	}
 
+	if priorityStr, ok := args["priority"].(string); ok && priorityStr != "" {
+		priority := models.Priority(priorityStr)
+		if !priority.Valid() {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + priorityMessage,
+				}},
+				IsError: true,
+			}, nil
+		}
+		updatedTodo.Priority = priority
+	}
+
+	schedule := []struct {
+		arg  string
+		dest **time.Time
+	}{
+		{"start_at", &updatedTodo.StartAt},
+		{"due_at", &updatedTodo.DueAt},
+	}
+	var echo dateEcho
+	for _, field := range schedule {
+		value, set, err := s.scheduleArg(args, field.arg, &echo)
+		if err != nil {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + err.Error(),
+				}},
+				IsError: true,
+			}, nil
+		}
+		if set {
+			*field.dest = value
+		}
+	}
+	if err := updatedTodo.CheckSchedule(); err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	if tags, set, err := tagsArg(args, "tags"); err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	} else if set {
+		updatedTodo.Tags = tags
+	}
+
+	projectID, set, err := intArg(args, "project_id")
+	if err == nil && projectID < 0 {
+		err = fmt.Errorf("project_id must be a positive integer, or 0 for no project")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if set {
+		updatedTodo.ProjectID = projectID
+	}
+
+	parentID, set, err := intArg(args, "parent_id")
+	if err == nil && parentID < 0 {
+		err = fmt.Errorf("parent_id must be a positive integer, or 0 for a top-level todo")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if set {
+		updatedTodo.ParentID = parentID
+	}
+
+	blockedBy, set, err := blockersArg(args, "blocked_by")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if set {
+		updatedTodo.BlockedBy = blockedBy
+	}
+
+	recurrence, set, err := recurrenceArg(args, "recurrence")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if set {
+		updatedTodo.Recurrence = recurrence
+	}
+
+	estimate, set, err := intArg(args, "estimate_minutes")
+	if err == nil && estimate < 0 {
+		err = errors.New("estimate_minutes must not be negative")
+	}
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: " + err.Error(),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if set {
+		updatedTodo.EstimateMinutes = estimate
+	}
+	if force, _ := args["force"].(bool); force {
+		ctx = storage.WithForce(ctx)
+	}
+
 	// Update in storage
-	if err := s.storage.Update(id, &updatedTodo); err != nil {
+	if err := s.storage.Update(ctx, id, &updatedTodo); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: fmt.Sprintf("Error: version conflict: todo %d was modified concurrently; fetch it again and retry", id),
+				}},
+				IsError: true,
+			}, nil
+		}
+		if errors.Is(err, storage.ErrBlocked) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + err.Error() + "; set force to true to complete it anyway",
+				}},
+				IsError: true,
+			}, nil
+		}
+		if errors.Is(err, models.ErrInvalidTransition) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + err.Error() + "; set force to true to move it anyway",
+				}},
+				IsError: true,
+			}, nil
+		}
+		if errors.Is(err, storage.ErrProjectNotFound) || errors.Is(err, storage.ErrParentNotFound) || errors.Is(err, storage.ErrParentCycle) ||
+			errors.Is(err, storage.ErrBlockerNotFound) || errors.Is(err, storage.ErrDependencyCycle) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: " + err.Error(),
+				}},
+				IsError: true,
+			}, nil
+		}
 		return &CallToolResponse{
 			Content: []Content{{
 				Type: "text",
@@ -305,26 +765,19 @@ func (s *MCPServer) handleUpdateTodo(args map[string]interface{}) (*CallToolResp
 	}
 
 	// Convert to response format
-	todoResp := TodoResponse{
-		ID:          updatedTodo.ID,
-		Title:       updatedTodo.Title,
-		Description: updatedTodo.Description,
-		Status:      string(updatedTodo.Status),
-		CreatedAt:   updatedTodo.CreatedAt,
-		UpdatedAt:   updatedTodo.UpdatedAt,
-	}
+	todoResp := newTodoResponse(&updatedTodo)
 
 	result, _ := json.MarshalIndent(todoResp, "", "  ")
 	return &CallToolResponse{
 		Content: []Content{{
 			Type: "text",
-			Text: fmt.Sprintf("Todo updated successfully:\n%s", string(result)),
+			Text: fmt.Sprintf("Todo updated successfully:\n%s%s", string(result), echo),
 		}},
 	}, nil
 }
 
 // handleDeleteTodo handles the delete_todo tool
-func (s *MCPServer) handleDeleteTodo(args map[string]interface{}) (*CallToolResponse, error) {
+func (s *MCPServer) handleDeleteTodo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
 	// Extract and validate ID
 	idInterface, ok := args["id"]
 	if !ok {
@@ -366,7 +819,7 @@ func (s *MCPServer) handleDeleteTodo(args map[string]interface{}) (*CallToolResp
 	}
 
 	// Delete todo
-	if err := s.storage.Delete(id); err != nil {
+	if err := s.storage.Delete(ctx, id); err != nil {
 		if err == storage.ErrTodoNotFound {
 			return &CallToolResponse{
 				Content: []Content{{
@@ -388,7 +841,7 @@ func (s *MCPServer) handleDeleteTodo(args map[string]interface{}) (*CallToolResp
 	return &CallToolResponse{
 		Content: []Content{{
 			Type: "text",
-			Text: fmt.Sprintf("Todo with ID %d deleted successfully", id),
+			Text: fmt.Sprintf("Todo with ID %d moved to the trash; use restore_todo to bring it back", id),
 		}},
 	}, nil
 }
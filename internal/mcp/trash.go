@@ -0,0 +1,201 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleListTrash handles the list_trash tool
+func (s *MCPServer) handleListTrash(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	todos, err := s.storage.ListTrash(ctx)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error retrieving trash: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Convert to response format
+	todoListResp := TodoListResponse{
+		Todos: make([]TodoResponse, len(todos)),
+		Count: len(todos),
+	}
+
+	for i, todo := range todos {
+		todoListResp.Todos[i] = newTodoResponse(todo)
+	}
+
+	result, _ := json.MarshalIndent(todoListResp, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: string(result),
+		}},
+	}, nil
+}
+
+// handleRestoreTodo handles the restore_todo tool
+func (s *MCPServer) handleRestoreTodo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	// Extract and validate ID
+	idInterface, ok := args["id"]
+	if !ok {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: id is required",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	var id int
+	switch v := idInterface.(type) {
+	case float64:
+		id = int(v)
+	case int:
+		id = v
+	case string:
+		var err error
+		id, err = strconv.Atoi(v)
+		if err != nil {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: id must be a valid integer",
+				}},
+				IsError: true,
+			}, nil
+		}
+	default:
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: "Error: id must be a number",
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Restore todo
+	todo, err := s.storage.Restore(ctx, id)
+	if err != nil {
+		if err == storage.ErrTodoNotFound {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: fmt.Sprintf("Todo with ID %d is not in the trash", id),
+				}},
+				IsError: true,
+			}, nil
+		}
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error restoring todo: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Convert to response format
+	todoResp := newTodoResponse(todo)
+
+	result, _ := json.MarshalIndent(todoResp, "", "  ")
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Todo restored successfully:\n%s", string(result)),
+		}},
+	}, nil
+}
+
+// handleEmptyTrash handles the empty_trash tool
+func (s *MCPServer) handleEmptyTrash(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	// Purge a single todo when an ID is given
+	if idInterface, ok := args["id"]; ok {
+		var id int
+		switch v := idInterface.(type) {
+		case float64:
+			id = int(v)
+		case int:
+			id = v
+		default:
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: id must be a number",
+				}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := s.storage.Purge(ctx, id); err != nil {
+			if err == storage.ErrTodoNotFound {
+				return &CallToolResponse{
+					Content: []Content{{
+						Type: "text",
+						Text: fmt.Sprintf("Todo with ID %d is not in the trash", id),
+					}},
+					IsError: true,
+				}, nil
+			}
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: fmt.Sprintf("Error purging todo: %v", err),
+				}},
+				IsError: true,
+			}, nil
+		}
+
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Todo with ID %d permanently deleted", id),
+			}},
+		}, nil
+	}
+
+	var echo dateEcho
+	var before time.Time
+	if value, ok := args["before"].(string); ok && value != "" {
+		var err error
+		before, err = s.dates.Parse(value)
+		if err != nil {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: before: " + err.Error(),
+				}},
+				IsError: true,
+			}, nil
+		}
+		echo.add("before", value, before)
+	}
+
+	n, err := s.storage.PurgeTrash(ctx, before)
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error emptying trash: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("%d todos permanently deleted%s", n, echo),
+		}},
+	}, nil
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// handleUndo handles the undo tool
+func (s *MCPServer) handleUndo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	return s.replayJournal(ctx, args, "undo", "undone", s.journal.Undo)
+}
+
+// handleRedo handles the redo tool
+func (s *MCPServer) handleRedo(ctx context.Context, args map[string]interface{}) (*CallToolResponse, error) {
+	return s.replayJournal(ctx, args, "redo", "redone", s.journal.Redo)
+}
+
+// replayJournal runs an undo or redo of the number of changes given in args
+func (s *MCPServer) replayJournal(ctx context.Context, args map[string]interface{}, verb, done string, replay func(context.Context, int) ([]storage.JournalChange, error)) (*CallToolResponse, error) {
+	count := 1
+	if countInterface, ok := args["count"]; ok {
+		v, ok := countInterface.(float64)
+		if !ok || v < 1 || v != float64(int(v)) {
+			return &CallToolResponse{
+				Content: []Content{{
+					Type: "text",
+					Text: "Error: count must be a positive integer",
+				}},
+				IsError: true,
+			}, nil
+		}
+		count = int(v)
+	}
+
+	changes, err := replay(ctx, count)
+	if errors.Is(err, storage.ErrNothingToUndo) || errors.Is(err, storage.ErrNothingToRedo) {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Nothing to %s in this session", verb),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	// Convert to response format
+	undoResp := UndoResponse{
+		Changes: make([]JournalChangeResponse, len(changes)),
+		Count:   len(changes),
+	}
+
+	for i, change := range changes {
+		undoResp.Changes[i] = JournalChangeResponse{
+			Action: string(change.Action),
+			Todo:   newTodoResponse(change.Todo),
+		}
+	}
+
+	result, _ := json.MarshalIndent(undoResp, "", "  ")
+	if err != nil {
+		return &CallToolResponse{
+			Content: []Content{{
+				Type: "text",
+				Text: fmt.Sprintf("Error: %v\nChanges done before the failure:\n%s", err, string(result)),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	return &CallToolResponse{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("%d changes %s:\n%s", len(changes), done, string(result)),
+		}},
+	}, nil
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// statusMessage explains a status rejected by the server's workflow
+func (s *MCPServer) statusMessage() string {
+	statuses := s.workflow.Statuses()
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = fmt.Sprintf("'%s'", status)
+	}
+	return "status must be one of " + strings.Join(names, ", ")
+}
+
+// transitionsDescription describes the moves the workflow allows between
+// its states, for the description of the update_todo status argument
+func (s *MCPServer) transitionsDescription() string {
+	var moves []string
+	for _, state := range s.workflow.States {
+		next := make([]string, len(state.Next))
+		for i, status := range state.Next {
+			next[i] = string(status)
+		}
+		if len(next) == 0 {
+			next = []string{"nothing"}
+		}
+		moves = append(moves, fmt.Sprintf("%s to %s", state.Name, strings.Join(next, " or ")))
+	}
+	return strings.Join(moves, "; ")
+}
+
+// statusResourceURI returns the URI of the resource listing the todos in
+// the given state
+func statusResourceURI(status models.TodoStatus) string {
+	return ResourceTodosStatus + string(status)
+}
+
+// statusResourceHandler returns the handler of the resource listing the
+// todos in the given state
+func (s *MCPServer) statusResourceHandler(status models.TodoStatus) ResourceHandler {
+	return func(ctx context.Context) (*ReadResourceResponse, error) {
+		page, err := s.storage.Query(ctx, storage.TodoQuery{Statuses: []models.TodoStatus{status}})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving %s todos: %w", status, err)
+		}
+		todos := page.Todos
+
+		// Convert to response format
+		todoListResp := TodoListResponse{
+			Todos: make([]TodoResponse, len(todos)),
+			Count: len(todos),
+		}
+
+		for i, todo := range todos {
+			todoListResp.Todos[i] = newTodoResponse(todo)
+		}
+
+		result, err := json.MarshalIndent(todoListResp, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s todos: %w", status, err)
+		}
+
+		return &ReadResourceResponse{
+			Contents: []ResourceContent{
+				{
+					URI:      statusResourceURI(status),
+					MimeType: "application/json",
+					Text:     string(result),
+				},
+			},
+		}, nil
+	}
+}
+
+// handleWorkflowResource handles the workflow resource
+func (s *MCPServer) handleWorkflowResource(ctx context.Context) (*ReadResourceResponse, error) {
+	result, err := json.MarshalIndent(s.workflow, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling workflow: %w", err)
+	}
+
+	return &ReadResourceResponse{
+		Contents: []ResourceContent{
+			{
+				URI:      ResourceWorkflow,
+				MimeType: "application/json",
+				Text:     string(result),
+			},
+		},
+	}, nil
+}
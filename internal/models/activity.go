@@ -0,0 +1,20 @@
+package models
+
+// Activity is what goes on around a todo without changing the todo itself:
+// the time spent on it and its comment thread. It is stored apart from the
+// todo, so adding to it neither bumps the todo's version nor shows up in
+// its history, and todo lists leave it out.
+type Activity struct {
+	TodoID          int         `json:"todo_id"`
+	TimeEntries     []TimeEntry `json:"time_entries,omitempty"`       // in order of their start
+	LastTimeEntryID int         `json:"last_time_entry_id,omitempty"` // of the newest time entry ever added, so removed entries' IDs aren't reissued
+	Comments        []Comment   `json:"comments,omitempty"`           // in order of creation, deleted ones included
+}
+
+// Clone returns a copy of the activity that shares no memory with it
+func (a *Activity) Clone() *Activity {
+	c := *a
+	c.TimeEntries = cloneTimeEntries(a.TimeEntries)
+	c.Comments = cloneComments(a.Comments)
+	return &c
+}
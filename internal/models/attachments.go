@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrAttachmentNotFound is returned for an attachment ID a todo doesn't have
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// Attachment is a file attached to a todo. Its content is kept apart from
+// the todo, in the blob named by its SHA-256 hash.
+type Attachment struct {
+	ID        int       `json:"id"` // unique within the todo
+	Name      string    `json:"name"`
+	MediaType string    `json:"media_type"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddAttachment attaches the file described by attachment to the todo with
+// the next free ID. Its name is reduced to a base name, so that it is safe
+// to offer for download.
+func (t *Todo) AddAttachment(attachment Attachment) *Attachment {
+	attachment.Name = attachmentName(attachment.Name)
+	attachment.ID = 0
+	for _, existing := range t.Attachments {
+		attachment.ID = max(attachment.ID, existing.ID)
+	}
+	attachment.ID++
+
+	t.Attachments = append(t.Attachments, attachment)
+	return &t.Attachments[len(t.Attachments)-1]
+}
+
+// attachmentName returns the last element of the uploaded file name, or
+// "attachment" if that leaves nothing
+func attachmentName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+// Attachment returns the attachment with the given ID, or
+// ErrAttachmentNotFound if the todo has none
+func (t *Todo) Attachment(id int) (*Attachment, error) {
+	for i := range t.Attachments {
+		if t.Attachments[i].ID == id {
+			return &t.Attachments[i], nil
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// RemoveAttachment removes the attachment with the given ID. It reports
+// whether the todo had one.
+func (t *Todo) RemoveAttachment(id int) bool {
+	i := slices.IndexFunc(t.Attachments, func(attachment Attachment) bool { return attachment.ID == id })
+	if i < 0 {
+		return false
+	}
+	t.Attachments = slices.Delete(t.Attachments, i, i+1)
+	if len(t.Attachments) == 0 {
+		t.Attachments = nil
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrEmptyChecklistItem    = errors.New("checklist item must not be empty")
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+)
+
+// ChecklistItem is a step of a todo's checklist, for work too small to be
+// a subtask of its own
+type ChecklistItem struct {
+	ID   int    `json:"id"` // unique within the todo, kept when the item moves
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// Checklist is the checklist of a todo with its progress
+type Checklist struct {
+	TodoID   int             `json:"todo_id"`
+	Items    []ChecklistItem `json:"items"`
+	Progress *Progress       `json:"progress,omitempty"` // nil without items
+}
+
+// AddChecklistItem adds an item with the given text to the todo's
+// checklist at position, counting from 1, or at the end if position is 0
+// or past it
+func (t *Todo) AddChecklistItem(text string, position int) (*ChecklistItem, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyChecklistItem
+	}
+
+	// Items added before the todo kept count may be above it
+	for _, existing := range t.Checklist {
+		t.LastChecklistItemID = max(t.LastChecklistItemID, existing.ID)
+	}
+	t.LastChecklistItemID++
+	item := ChecklistItem{ID: t.LastChecklistItemID, Text: text}
+
+	i := checklistIndex(position, len(t.Checklist))
+	t.Checklist = slices.Insert(t.Checklist, i, item)
+	return &t.Checklist[i], nil
+}
+
+// checklistIndex returns the index of position, counting from 1, in a
+// checklist of n items, or n if position is outside it
+func checklistIndex(position, n int) int {
+	if position < 1 || position > n {
+		return n
+	}
+	return position - 1
+}
+
+// checklistItem returns the index of the item with the given ID, or
+// ErrChecklistItemNotFound if the todo has none
+func (t *Todo) checklistItem(id int) (int, error) {
+	i := slices.IndexFunc(t.Checklist, func(item ChecklistItem) bool { return item.ID == id })
+	if i < 0 {
+		return -1, ErrChecklistItemNotFound
+	}
+	return i, nil
+}
+
+// ChecklistItem returns the item with the given ID, or
+// ErrChecklistItemNotFound if the todo has none
+func (t *Todo) ChecklistItem(id int) (*ChecklistItem, error) {
+	i, err := t.checklistItem(id)
+	if err != nil {
+		return nil, err
+	}
+	return &t.Checklist[i], nil
+}
+
+// EditChecklistItem replaces the text of the item with the given ID
+func (t *Todo) EditChecklistItem(id int, text string) (*ChecklistItem, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyChecklistItem
+	}
+	i, err := t.checklistItem(id)
+	if err != nil {
+		return nil, err
+	}
+	t.Checklist[i].Text = text
+	return &t.Checklist[i], nil
+}
+
+// CheckChecklistItem marks the item with the given ID done or not done
+func (t *Todo) CheckChecklistItem(id int, done bool) (*ChecklistItem, error) {
+	i, err := t.checklistItem(id)
+	if err != nil {
+		return nil, err
+	}
+	t.Checklist[i].Done = done
+	return &t.Checklist[i], nil
+}
+
+// MoveChecklistItem moves the item with the given ID to position, counting
+// from 1, or to the end if position is past it
+func (t *Todo) MoveChecklistItem(id, position int) (*ChecklistItem, error) {
+	i, err := t.checklistItem(id)
+	if err != nil {
+		return nil, err
+	}
+	item := t.Checklist[i]
+	t.Checklist = slices.Delete(t.Checklist, i, i+1)
+
+	j := checklistIndex(position, len(t.Checklist))
+	t.Checklist = slices.Insert(t.Checklist, j, item)
+	return &t.Checklist[j], nil
+}
+
+// RemoveChecklistItem removes the item with the given ID from the todo's
+// checklist
+func (t *Todo) RemoveChecklistItem(id int) error {
+	i, err := t.checklistItem(id)
+	if err != nil {
+		return err
+	}
+	t.Checklist = slices.Delete(t.Checklist, i, i+1)
+	if len(t.Checklist) == 0 {
+		t.Checklist = nil
+	}
+	return nil
+}
+
+// ChecklistProgress returns how many of the todo's checklist items are
+// done, or nil if it has none
+func (t *Todo) ChecklistProgress() *Progress {
+	if len(t.Checklist) == 0 {
+		return nil
+	}
+	progress := &Progress{Total: len(t.Checklist)}
+	for _, item := range t.Checklist {
+		if item.Done {
+			progress.Completed++
+		}
+	}
+	progress.Percent = progress.Completed * 100 / progress.Total
+	return progress
+}
+
+// ChecklistSummary returns the todo's checklist with its progress
+func (t *Todo) ChecklistSummary() *Checklist {
+	checklist := &Checklist{TodoID: t.ID, Items: t.Checklist, Progress: t.ChecklistProgress()}
+	if checklist.Items == nil {
+		checklist.Items = []ChecklistItem{}
+	}
+	return checklist
+}
+
+// AddChecklistItemRequest represents the request body for adding an item
+// to a todo's checklist
+type AddChecklistItemRequest struct {
+	Text     string `json:"text"`
+	Position int    `json:"position,omitempty"` // counting from 1, defaults to the end
+}
+
+// UpdateChecklistItemRequest represents the request body for changing an
+// item of a todo's checklist
+type UpdateChecklistItemRequest struct {
+	Text     *string `json:"text,omitempty"`
+	Done     *bool   `json:"done,omitempty"`
+	Position *int    `json:"position,omitempty"` // counting from 1
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmptyComment    = errors.New("comment must not be empty")
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+// Comment is a note in the thread of a todo, such as a progress update
+// logged by an agent, that leaves the todo's description alone. Comments
+// are only ever appended: deleting one clears its body but keeps its place
+// in the thread, so comment IDs are never reused.
+type Comment struct {
+	ID        int        `json:"id"` // unique within the todo, in order of creation
+	Author    string     `json:"author,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// cloneComments returns a copy of comments that shares no memory with it
+func cloneComments(comments []Comment) []Comment {
+	if comments == nil {
+		return nil
+	}
+	c := make([]Comment, len(comments))
+	for i, comment := range comments {
+		c[i] = comment
+		c[i].EditedAt = cloneTime(comment.EditedAt)
+		c[i].DeletedAt = cloneTime(comment.DeletedAt)
+	}
+	return c
+}
+
+// AddComment appends a comment by author to the thread at the given time.
+// The body is trimmed and must not be empty.
+func (a *Activity) AddComment(at time.Time, author, body string) (*Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	id := 1
+	if n := len(a.Comments); n > 0 {
+		id = a.Comments[n-1].ID + 1
+	}
+	a.Comments = append(a.Comments, Comment{ID: id, Author: author, Body: body, CreatedAt: at})
+	return &a.Comments[len(a.Comments)-1], nil
+}
+
+// Comment returns the comment with the given ID, or ErrCommentNotFound if
+// the thread has none or it was deleted
+func (a *Activity) Comment(id int) (*Comment, error) {
+	for i := range a.Comments {
+		if a.Comments[i].ID == id && a.Comments[i].DeletedAt == nil {
+			return &a.Comments[i], nil
+		}
+	}
+	return nil, ErrCommentNotFound
+}
+
+// EditComment replaces the body of the comment with the given ID, marking
+// it edited at the given time
+func (a *Activity) EditComment(id int, at time.Time, body string) (*Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+	comment, err := a.Comment(id)
+	if err != nil {
+		return nil, err
+	}
+	comment.Body = body
+	comment.EditedAt = &at
+	return comment, nil
+}
+
+// DeleteComment deletes the comment with the given ID at the given time
+func (a *Activity) DeleteComment(id int, at time.Time) error {
+	comment, err := a.Comment(id)
+	if err != nil {
+		return err
+	}
+	comment.Body = ""
+	comment.DeletedAt = &at
+	return nil
+}
+
+// Thread returns the comments that weren't deleted, oldest first
+func (a *Activity) Thread() []Comment {
+	thread := []Comment{}
+	for _, comment := range a.Comments {
+		if comment.DeletedAt == nil {
+			thread = append(thread, comment)
+		}
+	}
+	return thread
+}
+
+// CommentRequest represents the request body for adding or editing a
+// comment
+type CommentRequest struct {
+	Body string `json:"body"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "slices"
+
+// AddBlockerRequest represents the request to block a todo by another
+type AddBlockerRequest struct {
+	BlockerID int `json:"blocker_id"`
+}
+
+// NormalizeBlockers returns the blocker IDs sorted without duplicates
+func NormalizeBlockers(ids []int) []int {
+	if len(ids) == 0 {
+		return nil
+	}
+	normalized := slices.Clone(ids)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// AddBlocker makes the todo blocked by the todo with the given ID, keeping
+// BlockedBy normalized. It reports whether the todo wasn't blocked by it
+// already.
+func (t *Todo) AddBlocker(id int) bool {
+	i, found := slices.BinarySearch(t.BlockedBy, id)
+	if found {
+		return false
+	}
+	t.BlockedBy = slices.Insert(t.BlockedBy, i, id)
+	return true
+}
+
+// RemoveBlocker stops the todo from being blocked by the todo with the
+// given ID. It reports whether the todo was blocked by it.
+func (t *Todo) RemoveBlocker(id int) bool {
+	i, found := slices.BinarySearch(t.BlockedBy, id)
+	if !found {
+		return false
+	}
+	t.BlockedBy = slices.Delete(t.BlockedBy, i, i+1)
+	if len(t.BlockedBy) == 0 {
+		t.BlockedBy = nil
+	}
+	return true
+}
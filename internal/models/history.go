@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TodoEventAction names the kind of change a TodoEvent records
+type TodoEventAction string
+
+const (
+	EventCreated  TodoEventAction = "created"
+	EventUpdated  TodoEventAction = "updated"
+	EventDeleted  TodoEventAction = "deleted" // moved to the trash
+	EventRestored TodoEventAction = "restored"
+	EventPurged   TodoEventAction = "purged"
+)
+
+// FieldChange is the old and new JSON value of one todo field. A missing
+// value means the field was unset.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// TodoEvent is one entry of a todo's audit history. Version is the todo's
+// version after the change.
+type TodoEvent struct {
+	TodoID  int             `json:"todo_id"`
+	Action  TodoEventAction `json:"action"`
+	Actor   string          `json:"actor,omitempty"`
+	Version int             `json:"version"`
+	Changes []FieldChange   `json:"changes,omitempty"`
+	At      time.Time       `json:"at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Project groups todos into a list, such as personal, team or per-repo
+// todos. A todo belongs to at most one project. Archiving a project hides
+// its todos from listings unless they are asked for explicitly.
+type Project struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"` // unique, ignoring case
+	Description string    `json:"description"`
+	Archived    bool      `json:"archived"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Clone returns a copy of the project
+func (p *Project) Clone() *Project {
+	c := *p
+	return &c
+}
+
+// CreateProjectRequest represents the request body for creating a project
+type CreateProjectRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateProjectRequest represents the request body for updating a project
+type UpdateProjectRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Archived    *bool   `json:"archived,omitempty"`
+}
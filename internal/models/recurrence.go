@@ -0,0 +1,208 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a recurring todo comes back
+type Frequency string
+
+const (
+	// FrequencyDaily repeats every Interval days
+	FrequencyDaily Frequency = "daily"
+
+	// FrequencyWeekly repeats every Interval weeks, on Weekdays or else on
+	// the weekday the todo is due
+	FrequencyWeekly Frequency = "weekly"
+
+	// FrequencyMonthly repeats every Interval months on MonthDay, or on the
+	// month's last day if it is shorter
+	FrequencyMonthly Frequency = "monthly"
+
+	// FrequencyAfterCompletion repeats Interval days after the todo was
+	// completed, however late that was
+	FrequencyAfterCompletion Frequency = "after_completion"
+)
+
+// Frequencies lists the recurrence frequencies
+var Frequencies = []Frequency{FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyAfterCompletion}
+
+// ErrInvalidRecurrence is returned by Recurrence.Validate
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// Recurrence is an RRULE-style rule making a todo come back once it is
+// completed. The next occurrence is due on the first date of the rule's
+// schedule after both the completed todo's due date and the completion, so
+// occurrences missed while the todo was overdue are skipped.
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	Interval  int       `json:"interval,omitempty"`  // defaults to 1
+	Weekdays  []string  `json:"weekdays,omitempty"`  // lowercase English names, weekly only
+	MonthDay  int       `json:"month_day,omitempty"` // 1 to 31, required for monthly only
+}
+
+// Clone returns a copy of the rule that shares no memory with it
+func (r *Recurrence) Clone() *Recurrence {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Weekdays = slices.Clone(r.Weekdays)
+	return &c
+}
+
+// Validate checks that the rule has a known frequency and only the fields
+// that frequency uses, with values in range
+func (r *Recurrence) Validate() error {
+	if !slices.Contains(Frequencies, r.Frequency) {
+		names := make([]string, len(Frequencies))
+		for i, f := range Frequencies {
+			names[i] = string(f)
+		}
+		return fmt.Errorf("%w: frequency must be one of %s", ErrInvalidRecurrence, strings.Join(names, ", "))
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("%w: interval must not be negative", ErrInvalidRecurrence)
+	}
+	if len(r.Weekdays) > 0 && r.Frequency != FrequencyWeekly {
+		return fmt.Errorf("%w: weekdays only apply to weekly recurrence", ErrInvalidRecurrence)
+	}
+	for _, name := range r.Weekdays {
+		if _, ok := parseWeekday(name); !ok {
+			return fmt.Errorf("%w: unknown weekday %q", ErrInvalidRecurrence, name)
+		}
+	}
+	if r.Frequency == FrequencyMonthly && (r.MonthDay < 1 || r.MonthDay > 31) {
+		return fmt.Errorf("%w: month_day must be between 1 and 31", ErrInvalidRecurrence)
+	}
+	if r.MonthDay != 0 && r.Frequency != FrequencyMonthly {
+		return fmt.Errorf("%w: month_day only applies to monthly recurrence", ErrInvalidRecurrence)
+	}
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// interval returns the rule's interval, applying the default
+func (r *Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// Next returns the first date of the schedule after both anchor, the date
+// of the occurrence just completed, and completedAt. Dates keep the
+// anchor's time of day and location.
+func (r *Recurrence) Next(anchor, completedAt time.Time) time.Time {
+	n := r.interval()
+	after := func(t time.Time) bool {
+		return t.After(anchor) && t.After(completedAt)
+	}
+
+	switch r.Frequency {
+	case FrequencyAfterCompletion:
+		done := completedAt.In(anchor.Location())
+		next := time.Date(done.Year(), done.Month(), done.Day(), anchor.Hour(), anchor.Minute(), anchor.Second(), anchor.Nanosecond(), anchor.Location())
+		return next.AddDate(0, 0, n)
+
+	case FrequencyWeekly:
+		days := make(map[time.Weekday]bool)
+		for _, name := range r.Weekdays {
+			day, _ := parseWeekday(name)
+			days[day] = true
+		}
+		if len(days) == 0 {
+			days[anchor.Weekday()] = true
+		}
+		// Weeks start on Monday and count from the anchor's
+		monday := civilDay(anchor) - (int(anchor.Weekday())+6)%7
+		for next := anchor.AddDate(0, 0, 1); ; next = next.AddDate(0, 0, 1) {
+			weeks := (civilDay(next) - monday) / 7
+			if days[next.Weekday()] && weeks%n == 0 && after(next) {
+				return next
+			}
+		}
+
+	case FrequencyMonthly:
+		for k := 0; ; k += n {
+			first := time.Date(anchor.Year(), anchor.Month()+time.Month(k), 1, anchor.Hour(), anchor.Minute(), anchor.Second(), anchor.Nanosecond(), anchor.Location())
+			day := min(r.MonthDay, first.AddDate(0, 1, -1).Day())
+			if next := first.AddDate(0, 0, day-1); after(next) {
+				return next
+			}
+		}
+
+	default:
+		next := anchor.AddDate(0, 0, n)
+		for !after(next) {
+			next = next.AddDate(0, 0, n)
+		}
+		return next
+	}
+}
+
+// civilDay numbers the calendar day of t, so that subtracting two of them
+// counts days regardless of daylight saving changes
+func civilDay(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// NextOccurrence returns the todo that follows a recurring todo completed
+// at completedAt, or nil if the todo doesn't recur. The new todo is in the
+// initial state of w. It carries over the todo's
+// content, project, parent, estimate, rule and checklist, with every item
+// unchecked, but not its blockers, time entries, comments or attachments,
+// and moves its start and due dates along the schedule. The schedule is
+// anchored at the due date, or the start date without one; a todo with
+// neither gets the next date as its due date.
+func (t *Todo) NextOccurrence(w *Workflow, completedAt time.Time) *Todo {
+	if t.Recurrence == nil {
+		return nil
+	}
+
+	next := &Todo{
+		Title:           t.Title,
+		Description:     t.Description,
+		Status:          w.Initial,
+		Priority:        t.Priority,
+		Tags:            slices.Clone(t.Tags),
+		ProjectID:       t.ProjectID,
+		ParentID:        t.ParentID,
+		Recurrence:      t.Recurrence.Clone(),
+		EstimateMinutes: t.EstimateMinutes,
+		Checklist:       slices.Clone(t.Checklist),
+	}
+	for i := range next.Checklist {
+		next.Checklist[i].Done = false
+	}
+
+	switch {
+	case t.DueAt != nil:
+		due := t.Recurrence.Next(*t.DueAt, completedAt)
+		next.DueAt = &due
+		if t.StartAt != nil {
+			// Keep the time between start and due date
+			start := due.Add(t.StartAt.Sub(*t.DueAt))
+			next.StartAt = &start
+		}
+	case t.StartAt != nil:
+		start := t.Recurrence.Next(*t.StartAt, completedAt)
+		next.StartAt = &start
+	default:
+		due := t.Recurrence.Next(completedAt, completedAt)
+		next.DueAt = &due
+	}
+	return next
+}
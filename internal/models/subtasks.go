@@ -0,0 +1,57 @@
+package models
+
+import "sort"
+
+// Progress is how many of a todo's direct subtasks, or of the items of its
+// checklist, are done
+type Progress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+	Percent   int `json:"percent"` // rounded down
+}
+
+// TodoTree is a todo with its subtasks, each a tree of its own
+type TodoTree struct {
+	*Todo
+	Progress *Progress   `json:"progress,omitempty"` // nil without subtasks
+	Subtasks []*TodoTree `json:"subtasks,omitempty"`
+}
+
+// BuildTree arranges the subtasks of root, at any depth, into a tree
+// ordered by ID, counting the ones in a done state of w towards progress.
+// Subtasks whose parent isn't root or among the others are left out.
+func BuildTree(w *Workflow, root *Todo, subtasks []*Todo) *TodoTree {
+	children := make(map[int][]*Todo)
+	for _, todo := range subtasks {
+		children[todo.ParentID] = append(children[todo.ParentID], todo)
+	}
+
+	var build func(todo *Todo) *TodoTree
+	build = func(todo *Todo) *TodoTree {
+		tree := &TodoTree{Todo: todo}
+		kids := children[todo.ID]
+		if len(kids) == 0 {
+			return tree
+		}
+
+		sort.Slice(kids, func(i, j int) bool { return kids[i].ID < kids[j].ID })
+		tree.Progress = &Progress{Total: len(kids)}
+		for _, kid := range kids {
+			if w.Done(kid.Status) {
+				tree.Progress.Completed++
+			}
+			tree.Subtasks = append(tree.Subtasks, build(kid))
+		}
+		tree.Progress.Percent = tree.Progress.Completed * 100 / tree.Progress.Total
+		return tree
+	}
+	return build(root)
+}
+
+// Flatten drops the subtasks of the tree's subtasks, keeping their
+// progress, so that only one level remains below the tree's root
+func (t *TodoTree) Flatten() {
+	for _, subtask := range t.Subtasks {
+		subtask.Subtasks = nil
+	}
+}
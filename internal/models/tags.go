@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidTag is returned for a tag that is empty or contains a comma,
+// which separates tags in query parameters
+var ErrInvalidTag = errors.New("tags must be non-empty and can't contain commas")
+
+// TagCount is a tag with the number of todos carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// RenameTagRequest represents the request to rename a tag on every todo
+type RenameTagRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MergeTagsRequest represents the request to fold one tag into another
+type MergeTagsRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+// NormalizeTag trims surrounding space from a tag and lowercases it, so
+// that "Work" and "work " are the same tag
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || strings.Contains(tag, ",") {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes each tag and returns them sorted without
+// duplicates
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		t, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, t)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+// HasTag reports whether the todo carries the normalized tag
+func (t *Todo) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
@@ -0,0 +1,296 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+var (
+	ErrTimerRunning      = errors.New("a timer is already running on this todo")
+	ErrTimerNotRunning   = errors.New("no timer is running on this todo")
+	ErrInvalidTimeEntry  = errors.New("time entry must end after it starts")
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+)
+
+// TimeEntry is a span of work on a todo, either timed with StartTimer and
+// StopTimer or logged afterwards with LogTime. Entries are part of the
+// todo's Activity.
+type TimeEntry struct {
+	ID    int        `json:"id"` // unique within the todo
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"` // nil while the timer runs
+	Note  string     `json:"note,omitempty"`
+	Actor string     `json:"actor,omitempty"`
+}
+
+// Duration returns how long the entry lasted, or has lasted until now if
+// its timer is still running
+func (e *TimeEntry) Duration(now time.Time) time.Duration {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	return max(end.Sub(e.Start), 0)
+}
+
+// cloneTimeEntries returns a copy of entries that shares no memory with it
+func cloneTimeEntries(entries []TimeEntry) []TimeEntry {
+	if entries == nil {
+		return nil
+	}
+	c := make([]TimeEntry, len(entries))
+	for i, entry := range entries {
+		c[i] = entry
+		c[i].End = cloneTime(entry.End)
+	}
+	return c
+}
+
+// RunningTimer returns the entry of the todo's running timer, or nil
+func (a *Activity) RunningTimer() *TimeEntry {
+	for i := range a.TimeEntries {
+		if a.TimeEntries[i].End == nil {
+			return &a.TimeEntries[i]
+		}
+	}
+	return nil
+}
+
+// StartTimer starts timing work on the todo at the given time. A todo has
+// at most one running timer, so this fails with ErrTimerRunning if it has
+// one already.
+func (a *Activity) StartTimer(at time.Time, actor, note string) (*TimeEntry, error) {
+	if a.RunningTimer() != nil {
+		return nil, ErrTimerRunning
+	}
+	return a.addTimeEntry(TimeEntry{Start: at, Note: note, Actor: actor}), nil
+}
+
+// StopTimer stops the todo's running timer at the given time, or at its
+// start if that is later, and returns its entry. It fails with
+// ErrTimerNotRunning if no timer is running.
+func (a *Activity) StopTimer(at time.Time) (*TimeEntry, error) {
+	entry := a.RunningTimer()
+	if entry == nil {
+		return nil, ErrTimerNotRunning
+	}
+	end := at
+	if end.Before(entry.Start) {
+		end = entry.Start
+	}
+	entry.End = &end
+	return entry, nil
+}
+
+// LogTime records work done on the todo from start to end, which must be
+// after start
+func (a *Activity) LogTime(start, end time.Time, actor, note string) (*TimeEntry, error) {
+	if !end.After(start) {
+		return nil, ErrInvalidTimeEntry
+	}
+	return a.addTimeEntry(TimeEntry{Start: start, End: &end, Note: note, Actor: actor}), nil
+}
+
+// addTimeEntry adds entry with the next ID never used by the todo,
+// keeping the entries in order of their start
+func (a *Activity) addTimeEntry(entry TimeEntry) *TimeEntry {
+	// Entries added before the activity kept count may be above it
+	for _, existing := range a.TimeEntries {
+		a.LastTimeEntryID = max(a.LastTimeEntryID, existing.ID)
+	}
+	a.LastTimeEntryID++
+	entry.ID = a.LastTimeEntryID
+
+	i := sort.Search(len(a.TimeEntries), func(i int) bool {
+		return a.TimeEntries[i].Start.After(entry.Start)
+	})
+	a.TimeEntries = slices.Insert(a.TimeEntries, i, entry)
+	return &a.TimeEntries[i]
+}
+
+// RemoveTimeEntry removes the entry with the given ID, running or not. It
+// reports whether the todo had one.
+func (a *Activity) RemoveTimeEntry(id int) bool {
+	i := slices.IndexFunc(a.TimeEntries, func(entry TimeEntry) bool { return entry.ID == id })
+	if i < 0 {
+		return false
+	}
+	a.TimeEntries = slices.Delete(a.TimeEntries, i, i+1)
+	if len(a.TimeEntries) == 0 {
+		a.TimeEntries = nil
+	}
+	return true
+}
+
+// TimeSpent returns the time spent on the todo up to now, the running
+// timer included
+func (a *Activity) TimeSpent(now time.Time) time.Duration {
+	var spent time.Duration
+	for i := range a.TimeEntries {
+		spent += a.TimeEntries[i].Duration(now)
+	}
+	return spent
+}
+
+// TrackedMinutes returns the total of the finished time entries in minutes
+func (a *Activity) TrackedMinutes() int {
+	var tracked time.Duration
+	for i := range a.TimeEntries {
+		if a.TimeEntries[i].End != nil {
+			tracked += a.TimeEntries[i].Duration(time.Time{})
+		}
+	}
+	return minutes(tracked)
+}
+
+// minutes returns d in whole minutes, rounded to the nearest
+func minutes(d time.Duration) int {
+	return int(d.Round(time.Minute) / time.Minute)
+}
+
+// TimeSummary compares the time spent on a todo with its estimate
+type TimeSummary struct {
+	TodoID           int         `json:"todo_id"`
+	EstimateMinutes  int         `json:"estimate_minutes,omitempty"`
+	SpentMinutes     int         `json:"spent_minutes"`               // the running timer included
+	RemainingMinutes *int        `json:"remaining_minutes,omitempty"` // negative once over the estimate, nil without one
+	Running          *TimeEntry  `json:"running,omitempty"`
+	Entries          []TimeEntry `json:"entries"`
+}
+
+// TimeSummary summarizes the time spent on the todo as of now against its
+// estimate, zero for none
+func (a *Activity) TimeSummary(estimateMinutes int, now time.Time) *TimeSummary {
+	summary := &TimeSummary{
+		TodoID:          a.TodoID,
+		EstimateMinutes: estimateMinutes,
+		SpentMinutes:    minutes(a.TimeSpent(now)),
+		Running:         a.RunningTimer(),
+		Entries:         a.TimeEntries,
+	}
+	if summary.Entries == nil {
+		summary.Entries = []TimeEntry{}
+	}
+	if estimateMinutes > 0 {
+		remaining := estimateMinutes - summary.SpentMinutes
+		summary.RemainingMinutes = &remaining
+	}
+	return summary
+}
+
+// TimeStatus is the time spent on a todo at a glance, as shown with the
+// todo itself
+type TimeStatus struct {
+	SpentMinutes     int  `json:"spent_minutes"` // the running timer included
+	TimerRunning     bool `json:"timer_running"`
+	RemainingMinutes *int `json:"remaining_minutes,omitempty"` // negative once over the estimate, nil without one
+}
+
+// TimeStatus sums up the time spent on the todo as of now against its
+// estimate, zero for none
+func (a *Activity) TimeStatus(estimateMinutes int, now time.Time) *TimeStatus {
+	summary := a.TimeSummary(estimateMinutes, now)
+	return &TimeStatus{
+		SpentMinutes:     summary.SpentMinutes,
+		TimerRunning:     summary.Running != nil,
+		RemainingMinutes: summary.RemainingMinutes,
+	}
+}
+
+// TimeReportItem is the time spent on one todo within a report's period
+type TimeReportItem struct {
+	TodoID          int        `json:"todo_id"`
+	Title           string     `json:"title"`
+	Status          TodoStatus `json:"status"`
+	Minutes         int        `json:"minutes"`
+	EstimateMinutes int        `json:"estimate_minutes,omitempty"`
+	TrackedMinutes  int        `json:"tracked_minutes"` // all time, finished entries only
+}
+
+// TimeReport is the time spent on todos within a period
+type TimeReport struct {
+	Since        time.Time        `json:"since"`
+	Before       time.Time        `json:"before"`
+	TotalMinutes int              `json:"total_minutes"`
+	Todos        []TimeReportItem `json:"todos"`
+}
+
+// BuildTimeReport reports the time spent on each of todos from since up to
+// before, as recorded in their activities, counting only the part of each
+// entry within that period and running timers up to now. Todos without
+// time in the period are left out and the rest are ordered by the time
+// spent, most first.
+func BuildTimeReport(todos []*Todo, activities []*Activity, since, before, now time.Time) *TimeReport {
+	report := &TimeReport{Since: since, Before: before, Todos: []TimeReportItem{}}
+
+	byTodo := make(map[int]*Todo, len(todos))
+	for _, todo := range todos {
+		byTodo[todo.ID] = todo
+	}
+
+	var total time.Duration
+	for _, activity := range activities {
+		todo, exists := byTodo[activity.TodoID]
+		if !exists {
+			continue
+		}
+
+		var spent time.Duration
+		for i := range activity.TimeEntries {
+			entry := &activity.TimeEntries[i]
+			start := entry.Start
+			end := start.Add(entry.Duration(now))
+			if start.Before(since) {
+				start = since
+			}
+			if end.After(before) {
+				end = before
+			}
+			if end.After(start) {
+				spent += end.Sub(start)
+			}
+		}
+		if spent == 0 {
+			continue
+		}
+
+		total += spent
+		report.Todos = append(report.Todos, TimeReportItem{
+			TodoID:          todo.ID,
+			Title:           todo.Title,
+			Status:          todo.Status,
+			Minutes:         minutes(spent),
+			EstimateMinutes: todo.EstimateMinutes,
+			TrackedMinutes:  activity.TrackedMinutes(),
+		})
+	}
+	report.TotalMinutes = minutes(total)
+
+	sort.SliceStable(report.Todos, func(i, j int) bool {
+		return report.Todos[i].Minutes > report.Todos[j].Minutes
+	})
+	return report
+}
+
+// StartOfWeek returns the start of the Monday of t's week in t's location
+func StartOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	// Sunday is day 0 of time.Weekday, but the last day of the week here
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// StartTimerRequest represents the request body for starting a timer
+type StartTimerRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+// LogTimeRequest represents the request body for logging time on a todo:
+// either start and end, or minutes ending at end, which defaults to now
+type LogTimeRequest struct {
+	Start   string `json:"start,omitempty"` // RFC 3339 or a date expression
+	End     string `json:"end,omitempty"`   // RFC 3339 or a date expression
+	Minutes int    `json:"minutes,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
@@ -1,36 +1,148 @@
 package models
 
 import (
+	"errors"
+	"slices"
 	"time"
 )
 
-// TodoStatus represents the status of a todo item
+// TodoStatus represents the status of a todo item: the name of a state of
+// the workflow todos follow
 type TodoStatus string
 
+// The statuses of DefaultWorkflow
 const (
 	StatusPending   TodoStatus = "pending"
 	StatusCompleted TodoStatus = "completed"
 )
 
-// Todo represents a todo item
+// Priority ranks how much a todo matters
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists the priority levels from lowest to highest
+var Priorities = []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+// Rank returns the position of p in Priorities, or -1 if p is not a
+// priority level. The empty priority of todos created before priorities
+// existed ranks as PriorityNone.
+func (p Priority) Rank() int {
+	if p == "" {
+		return 0
+	}
+	for i, level := range Priorities {
+		if level == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether p is one of Priorities
+func (p Priority) Valid() bool {
+	return p != "" && p.Rank() >= 0
+}
+
+// Todo represents a todo item. Version starts at 1 and is incremented by
+// storage on every update, so it identifies one state of the todo.
 type Todo struct {
-	ID          int        `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      TodoStatus `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID                  int             `json:"id"`
+	Title               string          `json:"title"`
+	Description         string          `json:"description"`
+	Status              TodoStatus      `json:"status"`
+	Priority            Priority        `json:"priority,omitempty"`
+	StartAt             *time.Time      `json:"start_at,omitempty"`
+	DueAt               *time.Time      `json:"due_at,omitempty"`
+	Tags                []string        `json:"tags,omitempty"`       // normalized with NormalizeTags
+	ProjectID           int             `json:"project_id,omitempty"` // zero if the todo is in no project
+	ParentID            int             `json:"parent_id,omitempty"`  // zero for a top-level todo
+	BlockedBy           []int           `json:"blocked_by,omitempty"` // IDs of the todos to complete first, ascending
+	Recurrence          *Recurrence     `json:"recurrence,omitempty"`
+	NextID              int             `json:"next_id,omitempty"` // the occurrence generated when this recurring todo was completed
+	EstimateMinutes     int             `json:"estimate_minutes,omitempty"`
+	Attachments         []Attachment    `json:"attachments,omitempty"`
+	Checklist           []ChecklistItem `json:"checklist,omitempty"`              // in order
+	LastChecklistItemID int             `json:"last_checklist_item_id,omitempty"` // of the newest item ever added, so removed items' IDs aren't reissued
+	Version             int             `json:"version"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	DeletedAt           *time.Time      `json:"deleted_at,omitempty"` // set while the todo is in the trash
+}
+
+// Clone returns a copy of the todo that shares no memory with it
+func (t *Todo) Clone() *Todo {
+	c := *t
+	c.StartAt = cloneTime(t.StartAt)
+	c.DueAt = cloneTime(t.DueAt)
+	c.DeletedAt = cloneTime(t.DeletedAt)
+	c.Tags = slices.Clone(t.Tags)
+	c.BlockedBy = slices.Clone(t.BlockedBy)
+	c.Recurrence = t.Recurrence.Clone()
+	c.Attachments = slices.Clone(t.Attachments)
+	c.Checklist = slices.Clone(t.Checklist)
+	return &c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// ErrStartAfterDue is returned by CheckSchedule for a todo that would start
+// after it is due
+var ErrStartAfterDue = errors.New("start date is after the due date")
+
+// CheckSchedule checks that the todo doesn't start after it is due
+func (t *Todo) CheckSchedule() error {
+	if t.StartAt != nil && t.DueAt != nil && t.StartAt.After(*t.DueAt) {
+		return ErrStartAfterDue
+	}
+	return nil
+}
+
+// Overdue reports whether the todo is past its due date at now without
+// being in a done state of w
+func (t *Todo) Overdue(w *Workflow, now time.Time) bool {
+	return t.DueAt != nil && t.DueAt.Before(now) && !w.Done(t.Status)
 }
 
 // CreateTodoRequest represents the request body for creating a todo
 type CreateTodoRequest struct {
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description"`
+	Title           string      `json:"title" validate:"required"`
+	Description     string      `json:"description"`
+	Priority        Priority    `json:"priority,omitempty"` // defaults to none
+	StartAt         string      `json:"start_at,omitempty"` // RFC 3339 or a date expression
+	DueAt           string      `json:"due_at,omitempty"`   // RFC 3339 or a date expression
+	Tags            []string    `json:"tags,omitempty"`
+	ProjectID       int         `json:"project_id,omitempty"`
+	ParentID        int         `json:"parent_id,omitempty"`
+	BlockedBy       []int       `json:"blocked_by,omitempty"`
+	Recurrence      *Recurrence `json:"recurrence,omitempty"`
+	EstimateMinutes int         `json:"estimate_minutes,omitempty"`
 }
 
 // UpdateTodoRequest represents the request body for updating a todo
 type UpdateTodoRequest struct {
-	Title       *string     `json:"title,omitempty"`
-	Description *string     `json:"description,omitempty"`
-	Status      *TodoStatus `json:"status,omitempty"`
+	Title           *string     `json:"title,omitempty"`
+	Description     *string     `json:"description,omitempty"`
+	Status          *TodoStatus `json:"status,omitempty"`
+	Priority        *Priority   `json:"priority,omitempty"`
+	StartAt         *string     `json:"start_at,omitempty"`         // like CreateTodoRequest, or "" to clear
+	DueAt           *string     `json:"due_at,omitempty"`           // like CreateTodoRequest, or "" to clear
+	Tags            *[]string   `json:"tags,omitempty"`             // replaces all tags
+	ProjectID       *int        `json:"project_id,omitempty"`       // 0 removes the todo from its project
+	ParentID        *int        `json:"parent_id,omitempty"`        // 0 makes the todo top-level
+	BlockedBy       *[]int      `json:"blocked_by,omitempty"`       // replaces all blockers
+	Recurrence      *Recurrence `json:"recurrence,omitempty"`       // an empty frequency stops it recurring
+	EstimateMinutes *int        `json:"estimate_minutes,omitempty"` // 0 clears the estimate
 }
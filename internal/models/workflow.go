@@ -0,0 +1,185 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidWorkflow   = errors.New("invalid workflow")
+	ErrUnknownStatus     = errors.New("unknown status")
+	ErrInvalidTransition = errors.New("status transition not allowed")
+)
+
+// State is one status of a workflow
+type State struct {
+	Name        TodoStatus   `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Done        bool         `json:"done,omitempty"` // closes the todo, like completing it
+	Next        []TodoStatus `json:"next,omitempty"` // the states a todo may move to from this one
+}
+
+// Workflow defines the statuses todos move through. States marked done,
+// such as completed or cancelled, close a todo: it no longer blocks others,
+// counts towards its parent's progress, can't be overdue and recurs. A
+// todo may only move between states along their Next transitions.
+type Workflow struct {
+	Initial TodoStatus `json:"initial,omitempty"` // state of new todos, defaults to the first
+	States  []State    `json:"states"`
+}
+
+// DefaultWorkflow is the workflow used without a workflow file: todos are
+// pending until completed, and can be reopened
+var DefaultWorkflow = &Workflow{
+	Initial: StatusPending,
+	States: []State{
+		{Name: StatusPending, Description: "Not done yet", Next: []TodoStatus{StatusCompleted}},
+		{Name: StatusCompleted, Description: "Done", Done: true, Next: []TodoStatus{StatusPending}},
+	},
+}
+
+// WorkflowOrDefault returns w, or DefaultWorkflow if w is nil
+func WorkflowOrDefault(w *Workflow) *Workflow {
+	if w == nil {
+		return DefaultWorkflow
+	}
+	return w
+}
+
+// LoadWorkflow reads the workflow for the -workflow command-line flag from
+// the JSON file at path, or returns DefaultWorkflow if path is empty
+func LoadWorkflow(path string) (*Workflow, error) {
+	if path == "" {
+		return DefaultWorkflow, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	var w Workflow
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWorkflow, err)
+	}
+	if w.Initial == "" && len(w.States) > 0 {
+		w.Initial = w.States[0].Name
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// stateName is the form of state names, which appear in URIs
+var stateName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Validate checks that the states have distinct lowercase names, that the
+// initial state and the targets of transitions exist, and that there is a
+// done state to complete todos with
+func (w *Workflow) Validate() error {
+	seen := make(map[TodoStatus]bool)
+	done := false
+	for _, state := range w.States {
+		if !stateName.MatchString(string(state.Name)) {
+			return fmt.Errorf("%w: state name %q must be lowercase letters, digits and underscores", ErrInvalidWorkflow, state.Name)
+		}
+		if seen[state.Name] {
+			return fmt.Errorf("%w: duplicate state %q", ErrInvalidWorkflow, state.Name)
+		}
+		seen[state.Name] = true
+		done = done || state.Done
+	}
+	if !done {
+		return fmt.Errorf("%w: no state is marked done", ErrInvalidWorkflow)
+	}
+	for _, state := range w.States {
+		for _, next := range state.Next {
+			if !seen[next] {
+				return fmt.Errorf("%w: state %q moves to unknown state %q", ErrInvalidWorkflow, state.Name, next)
+			}
+		}
+	}
+
+	initial, ok := w.State(w.Initial)
+	if !ok {
+		return fmt.Errorf("%w: unknown initial state %q", ErrInvalidWorkflow, w.Initial)
+	}
+	if initial.Done {
+		return fmt.Errorf("%w: initial state %q is done", ErrInvalidWorkflow, w.Initial)
+	}
+	return nil
+}
+
+// State returns the state named status
+func (w *Workflow) State(status TodoStatus) (State, bool) {
+	for _, state := range w.States {
+		if state.Name == status {
+			return state, true
+		}
+	}
+	return State{}, false
+}
+
+// Statuses lists the names of the states in order
+func (w *Workflow) Statuses() []TodoStatus {
+	statuses := make([]TodoStatus, len(w.States))
+	for i, state := range w.States {
+		statuses[i] = state.Name
+	}
+	return statuses
+}
+
+// DoneStatuses lists the names of the done states in order
+func (w *Workflow) DoneStatuses() []TodoStatus {
+	var statuses []TodoStatus
+	for _, state := range w.States {
+		if state.Done {
+			statuses = append(statuses, state.Name)
+		}
+	}
+	return statuses
+}
+
+// Done reports whether status is a done state
+func (w *Workflow) Done(status TodoStatus) bool {
+	state, ok := w.State(status)
+	return ok && state.Done
+}
+
+// CheckTransition fails with ErrUnknownStatus unless to is a state, and
+// with ErrInvalidTransition unless a todo may move from the state from to
+// it. Staying in the same state is always allowed, and so is leaving a
+// status the workflow doesn't know, so that todos stored under an earlier
+// workflow can be moved into this one.
+func (w *Workflow) CheckTransition(from, to TodoStatus) error {
+	if from == to {
+		return nil
+	}
+	if _, ok := w.State(to); !ok {
+		return fmt.Errorf("%w %q", ErrUnknownStatus, to)
+	}
+	state, ok := w.State(from)
+	if !ok || slices.Contains(state.Next, to) {
+		return nil
+	}
+
+	allowed := make([]string, len(state.Next))
+	for i, next := range state.Next {
+		allowed[i] = string(next)
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: %s is final", ErrInvalidTransition, from)
+	}
+	return fmt.Errorf("%w: %s can only move to %s", ErrInvalidTransition, from, strings.Join(allowed, ", "))
+}
+
+// Valid reports whether status is a state of the workflow
+func (w *Workflow) Valid(status TodoStatus) bool {
+	_, ok := w.State(status)
+	return ok
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// activityOf returns a copy of the activity stored for the todo with the
+// given ID, or an empty activity if none is
+func activityOf(activities map[int]*models.Activity, id int) *models.Activity {
+	if activity, exists := activities[id]; exists {
+		return activity.Clone()
+	}
+	return &models.Activity{TodoID: id}
+}
+
+// listActivities returns copies of the activities of the todos outside the
+// trash, ordered by todo ID
+func listActivities(activities map[int]*models.Activity, todos map[int]*models.Todo) []*models.Activity {
+	result := make([]*models.Activity, 0, len(activities))
+	for id, activity := range activities {
+		if todo, exists := todos[id]; !exists || todo.DeletedAt != nil {
+			continue
+		}
+		result = append(result, activity.Clone())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].TodoID < result[j].TodoID })
+	return result
+}
+
+// activityFile keeps the activity of the file backend's todos as JSON next
+// to them, so that it changes without touching the todos file. Callers
+// serialize access with the backend's own lock.
+type activityFile struct {
+	path string
+}
+
+// load reads the activities, which are empty if the file doesn't exist yet
+func (a activityFile) load() (map[int]*models.Activity, error) {
+	data, err := os.ReadFile(a.path)
+	if os.IsNotExist(err) {
+		return make(map[int]*models.Activity), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+
+	activities := make(map[int]*models.Activity)
+	if len(data) == 0 {
+		return activities, nil
+	}
+	if err := json.Unmarshal(data, &activities); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptFile, a.path, err)
+	}
+	return activities, nil
+}
+
+// save atomically replaces the activity file
+func (a activityFile) save(activities map[int]*models.Activity) error {
+	data, err := json.MarshalIndent(activities, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %w", err)
+	}
+	return writeFileAtomic(a.path, data, 0644)
+}
+
+// sweep removes the activity of todos that no longer exist, such as the
+// purged ones, saving only if there was any
+func (a activityFile) sweep(todos map[int]*models.Todo) error {
+	activities, err := a.load()
+	if err != nil {
+		return err
+	}
+
+	swept := false
+	for id := range activities {
+		if _, exists := todos[id]; !exists {
+			delete(activities, id)
+			swept = true
+		}
+	}
+	if !swept {
+		return nil
+	}
+	return a.save(activities)
+}
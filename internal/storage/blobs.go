@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrBlobTooLarge = errors.New("blob exceeds the size limit")
+)
+
+// DefaultMaxBlobSize is the size limit of attachments unless the
+// -max-attachment-size command-line flag sets another
+const DefaultMaxBlobSize = 10 << 20
+
+// blobName is the form of blob file names: a lowercase SHA-256 hash
+var blobName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// blobTempPrefix starts the names of the temp files blobs are written to
+const blobTempPrefix = ".blob"
+
+// BlobStore keeps the content of attachments in a directory, each blob in
+// a file named after the SHA-256 hash of its content, so that a file
+// attached twice is stored once. Blobs are never modified: a blob no todo
+// refers to any more is removed by SweepBlobsPeriodically.
+type BlobStore struct {
+	dir     string
+	maxSize int64
+}
+
+// NewBlobStore creates a blob store in dir, which is created on the first
+// Put, holding blobs of up to maxSize bytes
+func NewBlobStore(dir string, maxSize int64) *BlobStore {
+	return &BlobStore{dir: dir, maxSize: maxSize}
+}
+
+// OpenBlobs returns the blob store for the attachments of the todos Open
+// opens with the same backend and path: a directory next to the storage
+// file, named after it with a ".blobs" suffix
+func OpenBlobs(backend, path string, maxSize int64) *BlobStore {
+	return NewBlobStore(storagePath(backend, path)+".blobs", maxSize)
+}
+
+// MaxSize returns the size limit of blobs in bytes
+func (b *BlobStore) MaxSize() int64 {
+	return b.maxSize
+}
+
+// Blob describes stored content
+type Blob struct {
+	Hash      string // hex-encoded SHA-256 of the content
+	Size      int64
+	MediaType string
+}
+
+// Put stores the content read from r, failing with ErrBlobTooLarge if it
+// exceeds the size limit. The media type is sniffed from the content, or
+// taken from the extension of name, the file name it was uploaded as, when
+// the content only looks like generic text or binary data.
+func (b *BlobStore) Put(r io.Reader, name string) (*Blob, error) {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(b.dir, blobTempPrefix+tempFileSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temp file on any failure below
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// Read one byte past the limit to tell content that fits exactly from
+	// content that is too large
+	content := bufio.NewReaderSize(io.LimitReader(r, b.maxSize+1), 512)
+	head, _ := content.Peek(512)
+	mediaType := detectMediaType(name, head)
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if size > b.maxSize {
+		return nil, fmt.Errorf("%w of %d bytes", ErrBlobTooLarge, b.maxSize)
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	// Identical content may be stored already, in which case renaming over
+	// it changes nothing
+	blob := &Blob{Hash: hex.EncodeToString(hash.Sum(nil)), Size: size, MediaType: mediaType}
+	if err := os.Rename(tmpPath, b.path(blob.Hash)); err != nil {
+		return nil, fmt.Errorf("failed to store blob: %w", err)
+	}
+	committed = true
+	return blob, nil
+}
+
+// detectMediaType returns the media type of content starting with head,
+// uploaded as the file name
+func detectMediaType(name string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	generic := sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain")
+	if byName := mime.TypeByExtension(filepath.Ext(name)); generic && byName != "" {
+		return byName
+	}
+	return sniffed
+}
+
+// Open opens the blob with the given hash for reading, failing with
+// ErrBlobNotFound if there is none
+func (b *BlobStore) Open(hash string) (*os.File, error) {
+	if !blobName.MatchString(hash) {
+		return nil, ErrBlobNotFound
+	}
+	file, err := os.Open(b.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return file, nil
+}
+
+// path returns the path of the blob with the given hash
+func (b *BlobStore) path(hash string) string {
+	return filepath.Join(b.dir, hash)
+}
+
+// Sweep removes the blobs whose hash isn't in referenced, along with the
+// temp files of failed uploads, if they were written before the cutoff. The
+// cutoff spares blobs just stored by Put whose todo is yet to be saved.
+func (b *BlobStore) Sweep(referenced map[string]bool, before time.Time) (int, error) {
+	entries, err := os.ReadDir(b.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read blob directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		isBlob := blobName.MatchString(name)
+		if entry.IsDir() || !isBlob && !strings.HasPrefix(name, blobTempPrefix) || referenced[name] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(b.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove blob: %w", err)
+		}
+		if isBlob {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// SweepBlobsPeriodically removes the blobs that no todo in s, trash
+// included, has attached, checking every interval until ctx is done. Blobs
+// younger than interval are kept, so an upload has that long to be saved
+// with its todo. Once its blob is removed, undoing the removal of an
+// attachment brings back an attachment without content. It is meant to run
+// in its own goroutine.
+func SweepBlobsPeriodically(ctx context.Context, s TodoStorage, blobs *BlobStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := sweepBlobs(ctx, s, blobs, time.Now().Add(-interval))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("storage: failed to sweep blobs: %v", err)
+		} else if removed > 0 {
+			log.Printf("storage: removed %d unattached blobs", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepBlobs removes the blobs written before the cutoff that no todo in s
+// has attached
+func sweepBlobs(ctx context.Context, s TodoStorage, blobs *BlobStore, before time.Time) (int, error) {
+	todos, err := s.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	trash, err := s.ListTrash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, todo := range append(todos, trash...) {
+		for _, attachment := range todo.Attachments {
+			referenced[attachment.SHA256] = true
+		}
+	}
+	return blobs.Sweep(referenced, before)
+}
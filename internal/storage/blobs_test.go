@@ -0,0 +1,91 @@
+package storage_test
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+func TestBlobStore(t *testing.T) {
+	blobs := storage.NewBlobStore(filepath.Join(t.TempDir(), "todos.json.blobs"), 16)
+
+	log, err := blobs.Put(strings.NewReader("build failed\n"), "build.log")
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if log.Size != 13 || len(log.Hash) != 64 {
+		t.Errorf("Put() = %+v, want 13 bytes named by their SHA-256", log)
+	}
+	again, err := blobs.Put(strings.NewReader("build failed\n"), "copy.txt")
+	if err != nil {
+		t.Fatalf("second Put failed: %v", err)
+	}
+	if again.Hash != log.Hash {
+		t.Errorf("same content stored as %s and %s, want one blob", log.Hash, again.Hash)
+	}
+
+	file, err := blobs.Open(log.Hash)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != "build failed\n" {
+		t.Errorf("Open() content = %q, want the stored content", content)
+	}
+
+	// Content at the limit fits, one byte more doesn't
+	if _, err := blobs.Put(strings.NewReader(strings.Repeat("x", 16)), "full"); err != nil {
+		t.Errorf("Put at the size limit failed: %v", err)
+	}
+	if _, err := blobs.Put(strings.NewReader(strings.Repeat("x", 17)), "big"); !errors.Is(err, storage.ErrBlobTooLarge) {
+		t.Errorf("Put over the size limit: err = %v, want ErrBlobTooLarge", err)
+	}
+
+	if _, err := blobs.Open(strings.Repeat("0", 64)); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Open of a missing blob: err = %v, want ErrBlobNotFound", err)
+	}
+	if _, err := blobs.Open("../todos.json"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Open of a path: err = %v, want ErrBlobNotFound", err)
+	}
+
+	// Only unreferenced blobs older than the cutoff are swept
+	if removed, err := blobs.Sweep(nil, time.Now().Add(-time.Hour)); err != nil || removed != 0 {
+		t.Errorf("Sweep of new blobs = %d, %v, want none removed", removed, err)
+	}
+	removed, err := blobs.Sweep(map[string]bool{log.Hash: true}, time.Now().Add(time.Minute))
+	if err != nil || removed != 1 {
+		t.Errorf("Sweep = %d, %v, want the unreferenced blob removed", removed, err)
+	}
+	if file, err := blobs.Open(log.Hash); err != nil {
+		t.Errorf("Open of a referenced blob after Sweep failed: %v", err)
+	} else {
+		file.Close()
+	}
+}
+
+func TestBlobStoreMediaType(t *testing.T) {
+	blobs := storage.NewBlobStore(filepath.Join(t.TempDir(), "todos.json.blobs"), storage.DefaultMaxBlobSize)
+
+	for _, tc := range []struct {
+		name, content, want string
+	}{
+		{"screenshot.bin", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"notes.html", "plain words", "text/html; charset=utf-8"},
+		{"notes", "plain words", "text/plain; charset=utf-8"},
+		{"data", "\x00\x01\x02", "application/octet-stream"},
+	} {
+		blob, err := blobs.Put(strings.NewReader(tc.content), tc.name)
+		if err != nil {
+			t.Fatalf("Put(%q) failed: %v", tc.name, err)
+		}
+		if blob.MediaType != tc.want {
+			t.Errorf("Put(%q) media type = %q, want %q", tc.name, blob.MediaType, tc.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// contextStorage adapts a BasicStorage to TodoStorage
+type contextStorage struct {
+	storage BasicStorage
+
+	// actors is storage itself if it records actors in its history
+	actors actorStorage
+
+	// binder is storage itself if its calls can stop waiting for a lock
+	binder contextBinder
+}
+
+// contextBinder is implemented by BasicStorage backends whose calls may
+// wait for a lock held by another process. bind returns a view of the
+// backend whose calls give up waiting with ctx's error once ctx is done.
+type contextBinder interface {
+	bind(ctx context.Context) BasicStorage
+}
+
+// WithContext returns a TodoStorage backed by storage. Each call fails
+// with the context's error if it is already cancelled or past its deadline;
+// otherwise it runs the context-free call unchanged, except that backends
+// in this package stop waiting for a lock once the context is done. The
+// actor set with WithActor reaches the history of backends in this
+// package.
+func WithContext(storage BasicStorage) TodoStorage {
+	actors, _ := storage.(actorStorage)
+	binder, _ := storage.(contextBinder)
+	return &contextStorage{storage: storage, actors: actors, binder: binder}
+}
+
+// at returns the storage to make a call with ctx through
+func (c *contextStorage) at(ctx context.Context) *contextStorage {
+	if c.binder == nil {
+		return c
+	}
+	bound := c.binder.bind(ctx)
+	actors, _ := bound.(actorStorage)
+	return &contextStorage{storage: bound, actors: actors}
+}
+
+// Create creates a new todo item
+func (c *contextStorage) Create(ctx context.Context, todo *models.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.createAs(ActorFromContext(ctx), todo)
+	}
+	return c.at(ctx).storage.Create(todo)
+}
+
+// GetByID retrieves a todo by its ID
+func (c *contextStorage) GetByID(ctx context.Context, id int) (*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.GetByID(id)
+}
+
+// GetAll retrieves all todos
+func (c *contextStorage) GetAll(ctx context.Context) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.GetAll()
+}
+
+// Update updates an existing todo
+func (c *contextStorage) Update(ctx context.Context, id int, todo *models.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.updateAs(ActorFromContext(ctx), id, todo, forced(ctx))
+	}
+	return c.at(ctx).storage.Update(id, todo)
+}
+
+// Delete moves a todo to the trash
+func (c *contextStorage) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.deleteAs(ActorFromContext(ctx), id)
+	}
+	return c.at(ctx).storage.Delete(id)
+}
+
+// GetByStatus retrieves todos by status
+func (c *contextStorage) GetByStatus(ctx context.Context, status models.TodoStatus) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.GetByStatus(status)
+}
+
+// Query retrieves one page of the todos selected by the query
+func (c *contextStorage) Query(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.Query(query)
+}
+
+// ListTrash retrieves the todos in the trash
+func (c *contextStorage) ListTrash(ctx context.Context) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.ListTrash()
+}
+
+// Restore moves a todo out of the trash and returns it
+func (c *contextStorage) Restore(ctx context.Context, id int) (*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.restoreAs(ActorFromContext(ctx), id)
+	}
+	return c.at(ctx).storage.Restore(id)
+}
+
+// Purge permanently deletes a todo from the trash
+func (c *contextStorage) Purge(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.purgeAs(ActorFromContext(ctx), id)
+	}
+	return c.at(ctx).storage.Purge(id)
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff
+func (c *contextStorage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.purgeTrashAs(ActorFromContext(ctx), before)
+	}
+	return c.at(ctx).storage.PurgeTrash(before)
+}
+
+// History retrieves the changes made to a todo, oldest first
+func (c *contextStorage) History(ctx context.Context, id int) ([]*models.TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.History(id)
+}
+
+// Tags lists the tags of the todos outside the trash with their counts
+func (c *contextStorage) Tags(ctx context.Context) ([]models.TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.Tags()
+}
+
+// RenameTag renames a tag on every todo carrying it
+func (c *contextStorage) RenameTag(ctx context.Context, from, to string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.retagAs(ActorFromContext(ctx), from, to, false)
+	}
+	return c.at(ctx).storage.RenameTag(from, to)
+}
+
+// MergeTags folds the tag from into an existing tag
+func (c *contextStorage) MergeTags(ctx context.Context, from, into string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if c.actors != nil {
+		return c.at(ctx).actors.retagAs(ActorFromContext(ctx), from, into, true)
+	}
+	return c.at(ctx).storage.MergeTags(from, into)
+}
+
+// Subtasks retrieves the subtasks of a todo at any depth
+func (c *contextStorage) Subtasks(ctx context.Context, id int) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.Subtasks(id)
+}
+
+// CreateProject creates a new project
+func (c *contextStorage) CreateProject(ctx context.Context, project *models.Project) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.at(ctx).storage.CreateProject(project)
+}
+
+// GetProject retrieves a project by its ID
+func (c *contextStorage) GetProject(ctx context.Context, id int) (*models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.GetProject(id)
+}
+
+// ListProjects retrieves the projects ordered by ID
+func (c *contextStorage) ListProjects(ctx context.Context, includeArchived bool) ([]*models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.ListProjects(includeArchived)
+}
+
+// UpdateProject updates an existing project
+func (c *contextStorage) UpdateProject(ctx context.Context, id int, project *models.Project) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.at(ctx).storage.UpdateProject(id, project)
+}
+
+// DeleteProject permanently deletes a project no todo belongs to
+func (c *contextStorage) DeleteProject(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.at(ctx).storage.DeleteProject(id)
+}
+
+// Activity retrieves the activity of a todo outside the trash
+func (c *contextStorage) Activity(ctx context.Context, id int) (*models.Activity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.Activity(id)
+}
+
+// ChangeActivity applies change to the activity of a todo outside the trash
+func (c *contextStorage) ChangeActivity(ctx context.Context, id int, change func(*models.Activity) error) (*models.Activity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.ChangeActivity(id, change)
+}
+
+// Activities retrieves the activity stored for the todos outside the trash
+func (c *contextStorage) Activities(ctx context.Context) ([]*models.Activity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.at(ctx).storage.Activities()
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrBlockerNotFound = errors.New("blocking todo not found")
+	ErrDependencyCycle = errors.New("a todo can't be blocked by itself or by todos it blocks")
+	ErrBlocked         = errors.New("todo has open blockers")
+)
+
+type forceKey struct{}
+
+// WithForce returns a context whose updates complete todos even while
+// they have open blockers, and move todos between any two states of the
+// workflow
+func WithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// forced reports whether the context was returned by WithForce
+func forced(ctx context.Context) bool {
+	force, _ := ctx.Value(forceKey{}).(bool)
+	return force
+}
+
+// checkBlockers fails with ErrBlockerNotFound unless every ID in blockedBy
+// names a todo outside the trash, and with ErrDependencyCycle if the todo
+// with the given ID is among them or among the todos blocking them, at any
+// depth. The ID is zero for a todo yet to be created. todos must hold the
+// blockers and the todos blocking them, trash included, so that restoring
+// a todo can't close a cycle.
+func checkBlockers(todos map[int]*models.Todo, id int, blockedBy []int) error {
+	for _, blockerID := range blockedBy {
+		if blocker, exists := todos[blockerID]; !exists || blocker.DeletedAt != nil {
+			return fmt.Errorf("%w: %d", ErrBlockerNotFound, blockerID)
+		}
+	}
+	if id == 0 {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	queue := slices.Clone(blockedBy)
+	for len(queue) > 0 {
+		blockerID := queue[0]
+		queue = queue[1:]
+		if blockerID == id {
+			return ErrDependencyCycle
+		}
+		if seen[blockerID] {
+			continue
+		}
+		seen[blockerID] = true
+		if blocker, exists := todos[blockerID]; exists {
+			queue = append(queue, blocker.BlockedBy...)
+		}
+	}
+	return nil
+}
+
+// openBlockers returns the IDs of the todos blocking todo that are neither
+// in a done state of w nor in the trash
+func openBlockers(w *models.Workflow, todos map[int]*models.Todo, todo *models.Todo) []int {
+	var open []int
+	for _, blockerID := range todo.BlockedBy {
+		blocker, exists := todos[blockerID]
+		if exists && blocker.DeletedAt == nil && !w.Done(blocker.Status) {
+			open = append(open, blockerID)
+		}
+	}
+	return open
+}
+
+// checkCompletion fails with ErrBlocked if an update from before to after
+// completes a todo with open blockers, unless it is forced. Subtasks
+// completed along with the todo are not checked.
+func checkCompletion(w *models.Workflow, todos map[int]*models.Todo, before, after *models.Todo, force bool) error {
+	if force || !completes(w, before, after) {
+		return nil
+	}
+	if open := openBlockers(w, todos, after); len(open) > 0 {
+		return fmt.Errorf("%w: %s", ErrBlocked, joinIDs(open))
+	}
+	return nil
+}
+
+// joinIDs formats todo IDs as a comma-separated list
+func joinIDs(ids []int) string {
+	var s string
+	for i, id := range ids {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprint(id)
+	}
+	return s
+}
+
+// isBlocked reports whether todo has blockers open in w
+func isBlocked(w *models.Workflow, todos map[int]*models.Todo, todo *models.Todo) bool {
+	return len(openBlockers(w, todos, todo)) > 0
+}
+
+// unblockedTodos returns copies of the remaining todos, trash included,
+// that were blocked by purged ones, with those blockers removed, updated
+// at the given time. Purged IDs can be reused, so they mustn't linger.
+func unblockedTodos(todos map[int]*models.Todo, purged []*models.Todo, at time.Time) []carriedChange {
+	gone := make(map[int]bool, len(purged))
+	for _, todo := range purged {
+		gone[todo.ID] = true
+	}
+
+	var changes []carriedChange
+	for _, todo := range todos {
+		if gone[todo.ID] || !slices.ContainsFunc(todo.BlockedBy, func(id int) bool { return gone[id] }) {
+			continue
+		}
+		unblocked := todo.Clone()
+		unblocked.BlockedBy = slices.DeleteFunc(unblocked.BlockedBy, func(id int) bool { return gone[id] })
+		if len(unblocked.BlockedBy) == 0 {
+			unblocked.BlockedBy = nil
+		}
+		unblocked.UpdatedAt = at
+		unblocked.Version++
+		changes = append(changes, carriedChange{before: todo, after: unblocked})
+	}
+	slices.SortFunc(changes, func(a, b carriedChange) int { return a.before.ID - b.before.ID })
+	return changes
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Due views, the derived selections of open todos by due date
+const (
+	DueOverdue  = "overdue"
+	DueToday    = "today"
+	DueThisWeek = "this_week"
+)
+
+// DueViews lists the views accepted by TodoQuery.SetDueView
+var DueViews = []string{DueOverdue, DueToday, DueThisWeek}
+
+// SetDueView narrows the query to the open todos of a due view as seen at
+// now: those due before now, those due on now's day, or those due in now's
+// week, which starts on Monday. Days and weeks follow now's location.
+func (q *TodoQuery) SetDueView(view string, now time.Time) error {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch view {
+	case DueOverdue:
+		q.DueBefore = now
+	case DueToday:
+		q.DueSince = day
+		q.DueBefore = day.AddDate(0, 0, 1)
+	case DueThisWeek:
+		// Sunday is day 0 of time.Weekday, but the last day of the week here
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		q.DueSince = monday
+		q.DueBefore = monday.AddDate(0, 0, 7)
+	default:
+		return fmt.Errorf("%w: due view must be one of %s", ErrInvalidQuery, strings.Join(DueViews, ", "))
+	}
+
+	q.Open = true
+	return nil
+}
@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -15,30 +18,167 @@ import (
 var (
 	ErrTodoNotFound = errors.New("todo not found")
 	ErrTodoExists   = errors.New("todo already exists")
+	ErrCorruptFile  = errors.New("todos file is corrupted")
+
+	// ErrVersionConflict is returned by Update when the todo's Version no
+	// longer matches the stored one, i.e. it was changed in the meantime
+	ErrVersionConflict = errors.New("todo was modified concurrently")
 )
 
-// FileStorage implements TodoStorage using JSON file storage
+// tempFileSuffix is appended to the todos file name (followed by a random
+// part) for the temp files used during atomic saves
+const tempFileSuffix = ".tmp-*"
+
+// FileStorage implements BasicStorage using JSON file storage. The mutex
+// serializes goroutines of this process, while an advisory lock on a
+// sibling ".lock" file serializes separate processes sharing the same file.
+//
+// The parsed todos are cached in memory and only re-read when the file's
+// identity, size or modification time changes, e.g. after another process
+// saved it.
 type FileStorage struct {
 	filePath string
-	mutex    sync.RWMutex
+	workflow *models.Workflow
+	history  historyFile
+	projects projectFile
+	activity activityFile
+	ids      idFile
+
+	// ctx ends the wait for the lock file of calls made through a view
+	// returned by bind; nil waits as long as it takes
+	ctx context.Context
+
+	*fileState
+}
+
+// fileState is the state a FileStorage shares with its views
+type fileState struct {
+	mutex sync.RWMutex
+
+	// cacheMutex guards the cache fields, which concurrent readers holding
+	// only the shared lock may refresh
+	cacheMutex  sync.Mutex
+	cache       map[int]*models.Todo
+	cacheNextID int
+	cacheInfo   os.FileInfo
+}
+
+// lockPollInterval is how often a call waiting for another process to
+// release the lock file tries again
+const lockPollInterval = 10 * time.Millisecond
+
+// lockPath returns the path of the lock file guarding the todos file
+func (f *FileStorage) lockPath() string {
+	return f.filePath + ".lock"
+}
+
+// withLock runs fn while holding both the in-process and the cross-process
+// lock. Writers take them exclusively for the whole read-modify-write cycle.
+func (f *FileStorage) withLock(exclusive bool, fn func() error) error {
+	if exclusive {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+	} else {
+		f.mutex.RLock()
+		defer f.mutex.RUnlock()
+	}
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(f.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Each acquisition uses its own descriptor: flock locks belong to the
+	// open file, so sharing one between concurrent readers would let the
+	// first reader to finish release the lock for all of them
+	lockFile, err := os.OpenFile(f.lockPath(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lockFile.Close()
+
+	if err := f.lockFile(lockFile, exclusive); err != nil {
+		return err
+	}
+	defer unlockFileHandle(lockFile)
+
+	return fn()
 }
 
-// NewFileStorage creates a new file-based storage instance
-func NewFileStorage(filePath string) *FileStorage {
+// lockFile takes the lock on the open lock file, polling while another
+// process holds it until the lock is free or the storage's context is done
+func (f *FileStorage) lockFile(lockFile *os.File, exclusive bool) error {
+	ctx := f.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		locked, err := tryLockFileHandle(lockFile, exclusive)
+		if err != nil {
+			return fmt.Errorf("failed to lock file: %w", err)
+		}
+		if locked {
+			return nil
+		}
+
+		timer := time.NewTimer(lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// bind returns a view of the storage whose calls stop waiting for another
+// process to release the lock file once ctx is done
+func (f *FileStorage) bind(ctx context.Context) BasicStorage {
+	bound := *f
+	bound.ctx = ctx
+	return &bound
+}
+
+// NewFileStorage creates a new file-based storage instance whose todos
+// follow workflow, or models.DefaultWorkflow if it is nil
+func NewFileStorage(filePath string, workflow *models.Workflow) *FileStorage {
 	return &FileStorage{
-		filePath: filePath,
+		filePath:  filePath,
+		workflow:  models.WorkflowOrDefault(workflow),
+		history:   historyFile{path: filePath + ".history"},
+		projects:  projectFile{path: filePath + ".projects"},
+		activity:  activityFile{path: filePath + ".activity"},
+		ids:       idFile{path: filePath + ".ids"},
+		fileState: &fileState{},
 	}
 }
 
-// loadTodos loads todos from the JSON file
+// loadTodos loads todos from the JSON file, serving them from the cache
+// when the file hasn't changed since it was last read or written. Callers
+// must hold the lock, and only holders of the exclusive lock may modify the
+// returned map.
 func (f *FileStorage) loadTodos() (map[int]*models.Todo, int, error) {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
 
 	// Check if file exists
-	if _, err := os.Stat(f.filePath); os.IsNotExist(err) {
+	info, err := os.Stat(f.filePath)
+	if os.IsNotExist(err) {
 		// File doesn't exist, return empty map
-		return make(map[int]*models.Todo), 1, nil
+		f.invalidateCache()
+		nextID, err := f.ids.load()
+		if err != nil {
+			return nil, 0, err
+		}
+		return make(map[int]*models.Todo), max(nextID, 1), nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if f.cache != nil && sameFileState(f.cacheInfo, info) {
+		return f.cache, f.cacheNextID, nil
 	}
 
 	// Read file
@@ -51,34 +191,102 @@ func (f *FileStorage) loadTodos() (map[int]*models.Todo, int, error) {
 	var todos map[int]*models.Todo
 	if len(data) == 0 {
 		// Empty file, return empty map
-		return make(map[int]*models.Todo), 1, nil
+		todos = make(map[int]*models.Todo)
+	} else if err := json.Unmarshal(data, &todos); err != nil {
+		f.invalidateCache()
+		return nil, 0, fmt.Errorf("%w: failed to parse JSON: %v", ErrCorruptFile, err)
 	}
 
-	if err := json.Unmarshal(data, &todos); err != nil {
-		return nil, 0, fmt.Errorf("failed to parse JSON: %w", err)
+	// Purges save the ID mark before the todos, so it is at least as new
+	// as the file just read
+	nextID, err := f.ids.load()
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Find the highest ID to determine next ID
+	f.cache = todos
+	f.cacheNextID = max(nextTodoID(todos), nextID)
+	f.cacheInfo = info
+
+	return f.cache, f.cacheNextID, nil
+}
+
+// invalidateCache drops the cached todos. Callers must hold cacheMutex.
+func (f *FileStorage) invalidateCache() {
+	f.cache = nil
+	f.cacheNextID = 0
+	f.cacheInfo = nil
+}
+
+// sameFileState reports whether two stats describe the same unchanged file.
+// Saves replace the file through a rename, so a new inode alone reveals a
+// write even when size and modification time happen to match.
+func sameFileState(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// idFile keeps the next ID the file backend hands out once todos were
+// purged, so that their IDs are never reissued: the todos file alone only
+// tells the highest ID still in it. Purges save it before the todos file.
+type idFile struct {
+	path string
+}
+
+// load reads the next ID, which is zero if no todo was purged yet
+func (i idFile) load() (int, error) {
+	data, err := os.ReadFile(i.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ID mark: %w", err)
+	}
+
+	var mark struct {
+		NextID int `json:"next_id"`
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if err := json.Unmarshal(data, &mark); err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrCorruptFile, i.path, err)
+	}
+	return mark.NextID, nil
+}
+
+// save atomically replaces the next ID
+func (i idFile) save(nextID int) error {
+	data, err := json.Marshal(map[string]int{"next_id": nextID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ID mark: %w", err)
+	}
+	return writeFileAtomic(i.path, data, 0644)
+}
+
+// nextTodoID returns the ID following the highest one in todos
+func nextTodoID(todos map[int]*models.Todo) int {
 	nextID := 1
 	for id := range todos {
 		if id >= nextID {
 			nextID = id + 1
 		}
 	}
-
-	return todos, nextID, nil
+	return nextID
 }
 
-// saveTodos saves todos to the JSON file
+// saveTodos saves todos to the JSON file. The data is written to a temp
+// file in the same directory, fsynced and then renamed over the target, so a
+// crash part way through never leaves a truncated todos file behind.
+// Callers must hold the exclusive lock.
 func (f *FileStorage) saveTodos(todos map[int]*models.Todo) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(f.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
+	// The caller may have modified the cached map in place, so it is only
+	// trustworthy again once the save succeeds. The next ID never goes
+	// down, whatever was purged.
+	nextID := f.cacheNextID
+	f.invalidateCache()
 
 	// Marshal to JSON
 	data, err := json.MarshalIndent(todos, "", "  ")
@@ -86,115 +294,873 @@ func (f *FileStorage) saveTodos(todos map[int]*models.Todo) error {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(f.filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := writeFileAtomic(f.filePath, data, 0644); err != nil {
+		return err
+	}
+
+	// Remember the state of the file we just wrote so the next load can
+	// skip re-reading it
+	if info, err := os.Stat(f.filePath); err == nil && nextID > 0 {
+		f.cache = todos
+		f.cacheNextID = max(nextTodoID(todos), nextID)
+		f.cacheInfo = info
 	}
 
 	return nil
 }
 
-// Create creates a new todo item
-func (f *FileStorage) Create(todo *models.Todo) error {
-	todos, nextID, err := f.loadTodos()
+// writeFileAtomic replaces path with data via a synced temp file and rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+tempFileSuffix)
 	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temp file on any failure below
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	committed = true
+
+	// Sync the directory so the rename itself survives a crash
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// CheckIntegrity verifies the todos and projects files on startup.
+// Leftover temp files from an interrupted save are removed and logged; the
+// saved file itself was never touched by such a save. A file that cannot be
+// parsed is reported as ErrCorruptFile instead of failing every later
+// request.
+func (f *FileStorage) CheckIntegrity() error {
+	// Hold the exclusive lock so a save in progress in another process
+	// doesn't have its temp file removed from under it
+	return f.withLock(true, f.checkIntegrity)
+}
+
+func (f *FileStorage) checkIntegrity() error {
+	for _, saved := range []string{f.filePath, f.projects.path, f.activity.path, f.ids.path} {
+		pattern := filepath.Join(filepath.Dir(saved), filepath.Base(saved)+tempFileSuffix)
+		leftovers, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("failed to scan for temp files: %w", err)
+		}
+		for _, path := range leftovers {
+			log.Printf("storage: removing leftover temp file %s from an interrupted save", path)
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove leftover temp file %s: %w", path, err)
+			}
+		}
+	}
+
+	if _, err := f.projects.load(); err != nil {
+		return err
+	}
+	if _, err := f.activity.load(); err != nil {
+		return err
+	}
+	if _, err := f.ids.load(); err != nil {
 		return err
 	}
 
-	todo.ID = nextID
-	todo.CreatedAt = time.Now()
-	todo.UpdatedAt = time.Now()
+	data, err := os.ReadFile(f.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var todos map[int]*models.Todo
+	if err := json.Unmarshal(data, &todos); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrCorruptFile, f.filePath, err)
+	}
+
+	return nil
+}
+
+// Create creates a new todo item
+func (f *FileStorage) Create(todo *models.Todo) error {
+	return f.createAs("", todo)
+}
+
+func (f *FileStorage) createAs(actor string, todo *models.Todo) error {
+	return f.withLock(true, func() error {
+		todos, nextID, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		if err := f.checkProject(todo.ProjectID); err != nil {
+			return err
+		}
+		if err := checkParent(todos, 0, todo.ParentID); err != nil {
+			return err
+		}
+		if err := checkBlockers(todos, 0, todo.BlockedBy); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		todo.ID = nextID
+		todo.Version = 1
+		todo.CreatedAt = now
+		todo.UpdatedAt = now
 
-	todos[todo.ID] = todo
+		// Store a copy so later changes by the caller don't leak in
+		todoCopy := todo.Clone()
+		todos[todo.ID] = todoCopy
 
-	return f.saveTodos(todos)
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(newTodoEvent(models.EventCreated, actor, nil, todoCopy))
+		return nil
+	})
 }
 
 // GetByID retrieves a todo by its ID
 func (f *FileStorage) GetByID(id int) (*models.Todo, error) {
-	todos, _, err := f.loadTodos()
+	var result *models.Todo
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+
+		// Return a copy to avoid race conditions
+		todoCopy := todo.Clone()
+		result = todoCopy
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	todo, exists := todos[id]
-	if !exists {
-		return nil, ErrTodoNotFound
-	}
-
-	// Return a copy to avoid race conditions
-	todoCopy := *todo
-	return &todoCopy, nil
+	return result, nil
 }
 
 // GetAll retrieves all todos
 func (f *FileStorage) GetAll() ([]*models.Todo, error) {
-	todos, _, err := f.loadTodos()
+	var result []*models.Todo
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		result = make([]*models.Todo, 0, len(todos))
+		for _, todo := range todos {
+			if todo.DeletedAt != nil {
+				continue
+			}
+			// Add a copy to avoid race conditions
+			todoCopy := todo.Clone()
+			result = append(result, todoCopy)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]*models.Todo, 0, len(todos))
-	for _, todo := range todos {
-		// Add a copy to avoid race conditions
-		todoCopy := *todo
-		result = append(result, &todoCopy)
-	}
-
 	return result, nil
 }
 
 // Update updates an existing todo
 func (f *FileStorage) Update(id int, updatedTodo *models.Todo) error {
-	todos, _, err := f.loadTodos()
+	return f.updateAs("", id, updatedTodo, false)
+}
+
+func (f *FileStorage) updateAs(actor string, id int, updatedTodo *models.Todo, force bool) error {
+	return f.withLock(true, func() error {
+		todos, nextID, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+		if updatedTodo.ProjectID != todo.ProjectID {
+			if err := f.checkProject(updatedTodo.ProjectID); err != nil {
+				return err
+			}
+		}
+		if updatedTodo.ParentID != todo.ParentID {
+			if err := checkParent(todos, id, updatedTodo.ParentID); err != nil {
+				return err
+			}
+		}
+		if !slices.Equal(updatedTodo.BlockedBy, todo.BlockedBy) {
+			if err := checkBlockers(todos, id, updatedTodo.BlockedBy); err != nil {
+				return err
+			}
+		}
+		if err := checkTransition(f.workflow, todo, updatedTodo, force); err != nil {
+			return err
+		}
+		if err := checkCompletion(f.workflow, todos, todo, updatedTodo, force); err != nil {
+			return err
+		}
+
+		stored, err := prepareUpdate(todo, updatedTodo)
+		if err != nil {
+			return err
+		}
+		next := nextOccurrence(f.workflow, todo, stored)
+		if next != nil {
+			linkOccurrence(stored, next, nextID)
+		}
+
+		events := []*models.TodoEvent{newTodoEvent(models.EventUpdated, actor, todo, stored)}
+		for _, subtask := range completedSubtasks(f.workflow, todos, todo, stored) {
+			todos[subtask.ID] = completedCopy(subtask, stored.Status, stored.UpdatedAt)
+			events = append(events, newTodoEvent(models.EventUpdated, actor, subtask, todos[subtask.ID]))
+		}
+		if next != nil {
+			todos[next.ID] = next
+			events = append(events, newTodoEvent(models.EventCreated, actor, nil, next))
+		}
+		todos[id] = stored
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+
+		*updatedTodo = *stored.Clone()
+		return nil
+	})
+}
+
+// Delete moves a todo to the trash
+func (f *FileStorage) Delete(id int) error {
+	return f.deleteAs("", id)
+}
+
+func (f *FileStorage) deleteAs(actor string, id int) error {
+	return f.withLock(true, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+
+		now := time.Now()
+		var events []*models.TodoEvent
+		for _, trashed := range append([]*models.Todo{todo}, liveSubtasks(todos, id)...) {
+			todos[trashed.ID] = trashedCopy(trashed, now)
+			events = append(events, newTodoEvent(models.EventDeleted, actor, trashed, todos[trashed.ID]))
+		}
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+		return nil
+	})
+}
+
+// GetByStatus retrieves todos by status
+func (f *FileStorage) GetByStatus(status models.TodoStatus) ([]*models.Todo, error) {
+	var result []*models.Todo
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		for _, todo := range todos {
+			if todo.Status == status && todo.DeletedAt == nil {
+				// Add a copy to avoid race conditions
+				todoCopy := todo.Clone()
+				result = append(result, todoCopy)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Query retrieves one page of the todos selected by the query
+func (f *FileStorage) Query(query TodoQuery) (*TodoPage, error) {
+	var page *TodoPage
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+
+		page, err = ApplyQuery(f.workflow, queryCandidates(f.workflow, todos, projects, query), query)
+		if err != nil {
+			return err
+		}
+
+		// Return copies to avoid race conditions
+		for i, todo := range page.Todos {
+			todoCopy := todo.Clone()
+			page.Todos[i] = todoCopy
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	todo, exists := todos[id]
-	if !exists {
-		return ErrTodoNotFound
+	return page, nil
+}
+
+// ListTrash retrieves the todos in the trash
+func (f *FileStorage) ListTrash() ([]*models.Todo, error) {
+	var result []*models.Todo
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		result = make([]*models.Todo, 0)
+		for _, todo := range todos {
+			if todo.DeletedAt != nil {
+				// Add a copy to avoid race conditions
+				todoCopy := todo.Clone()
+				result = append(result, todoCopy)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Preserve original ID and CreatedAt
-	updatedTodo.ID = todo.ID
-	updatedTodo.CreatedAt = todo.CreatedAt
-	updatedTodo.UpdatedAt = time.Now()
+	return result, nil
+}
 
-	todos[id] = updatedTodo
-	return f.saveTodos(todos)
+// Restore moves a todo out of the trash
+func (f *FileStorage) Restore(id int) (*models.Todo, error) {
+	return f.restoreAs("", id)
 }
 
-// Delete deletes a todo by its ID
-func (f *FileStorage) Delete(id int) error {
-	todos, _, err := f.loadTodos()
+func (f *FileStorage) restoreAs(actor string, id int) (*models.Todo, error) {
+	var result *models.Todo
+	err := f.withLock(true, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt == nil {
+			return ErrTodoNotFound
+		}
+		if err := checkRestore(todos, todo); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var events []*models.TodoEvent
+		for _, restored := range append([]*models.Todo{todo}, restoredSubtasks(todos, todo)...) {
+			todos[restored.ID] = restoredCopy(restored, now)
+			events = append(events, newTodoEvent(models.EventRestored, actor, restored, todos[restored.ID]))
+		}
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+
+		todoCopy := todos[id].Clone()
+		result = todoCopy
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, exists := todos[id]
-	if !exists {
-		return ErrTodoNotFound
+	return result, nil
+}
+
+// Purge permanently deletes a todo from the trash
+func (f *FileStorage) Purge(id int) error {
+	return f.purgeAs("", id)
+}
+
+func (f *FileStorage) purgeAs(actor string, id int) error {
+	return f.withLock(true, func() error {
+		todos, nextID, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt == nil {
+			return ErrTodoNotFound
+		}
+		if err := f.ids.save(nextID); err != nil {
+			return err
+		}
+
+		var events []*models.TodoEvent
+		purged := append([]*models.Todo{todo}, trashedSubtasks(todos, id)...)
+		for _, gone := range purged {
+			delete(todos, gone.ID)
+			events = append(events, newTodoEvent(models.EventPurged, actor, gone, nil))
+		}
+		for _, change := range unblockedTodos(todos, purged, time.Now()) {
+			todos[change.after.ID] = change.after
+			events = append(events, newTodoEvent(models.EventUpdated, actor, change.before, change.after))
+		}
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+		f.sweepActivity(todos)
+		return nil
+	})
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff, or
+// the whole trash if the cutoff is zero, and returns how many were deleted
+func (f *FileStorage) PurgeTrash(before time.Time) (int, error) {
+	return f.purgeTrashAs("", before)
+}
+
+func (f *FileStorage) purgeTrashAs(actor string, before time.Time) (int, error) {
+	purged := 0
+	err := f.withLock(true, func() error {
+		todos, nextID, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		var trashed []*models.Todo
+		for _, todo := range todos {
+			if inTrashBefore(todo, before) {
+				trashed = append(trashed, todo)
+			}
+		}
+		if len(trashed) == 0 {
+			return nil
+		}
+		if err := f.ids.save(nextID); err != nil {
+			return err
+		}
+
+		var events []*models.TodoEvent
+		for _, todo := range trashed {
+			delete(todos, todo.ID)
+			events = append(events, newTodoEvent(models.EventPurged, actor, todo, nil))
+		}
+		for _, change := range unblockedTodos(todos, trashed, time.Now()) {
+			todos[change.after.ID] = change.after
+			events = append(events, newTodoEvent(models.EventUpdated, actor, change.before, change.after))
+		}
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+		f.sweepActivity(todos)
+		purged = len(trashed)
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	delete(todos, id)
-	return f.saveTodos(todos)
+	return purged, nil
 }
 
-// GetByStatus retrieves todos by status
-func (f *FileStorage) GetByStatus(status models.TodoStatus) ([]*models.Todo, error) {
-	todos, _, err := f.loadTodos()
+// History retrieves the changes made to a todo, oldest first
+func (f *FileStorage) History(id int) ([]*models.TodoEvent, error) {
+	var result []*models.TodoEvent
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		result, err = f.history.read(id)
+		if err != nil {
+			return err
+		}
+		if len(result) == 0 {
+			if _, exists := todos[id]; !exists {
+				return ErrTodoNotFound
+			}
+			result = make([]*models.TodoEvent, 0)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return result, nil
+}
+
+// Tags lists the tags of the todos outside the trash with their counts
+func (f *FileStorage) Tags() ([]models.TagCount, error) {
+	var result []models.TagCount
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		result = countTags(todos)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RenameTag renames a tag on every todo carrying it
+func (f *FileStorage) RenameTag(from, to string) (int, error) {
+	return f.retagAs("", from, to, false)
+}
+
+// MergeTags folds the tag from into an existing tag
+func (f *FileStorage) MergeTags(from, into string) (int, error) {
+	return f.retagAs("", from, into, true)
+}
+
+func (f *FileStorage) retagAs(actor, from, to string, merge bool) (int, error) {
+	from, to, err := normalizeRetag(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	err = f.withLock(true, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		targets, err := planRetag(todos, from, to, merge)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		events := make([]*models.TodoEvent, 0, len(targets))
+		for _, todo := range targets {
+			retagged := retaggedCopy(todo, from, to, now)
+			todos[todo.ID] = retagged
+			events = append(events, newTodoEvent(models.EventUpdated, actor, todo, retagged))
+		}
+		if err := f.saveTodos(todos); err != nil {
+			return err
+		}
+		f.history.record(events...)
+		changed = len(targets)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// Subtasks retrieves the subtasks of a todo at any depth
+func (f *FileStorage) Subtasks(id int) ([]*models.Todo, error) {
 	var result []*models.Todo
-	for _, todo := range todos {
-		if todo.Status == status {
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+
+		todo, exists := todos[id]
+		if !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+
+		result = make([]*models.Todo, 0)
+		for _, subtask := range liveSubtasks(todos, id) {
 			// Add a copy to avoid race conditions
-			todoCopy := *todo
-			result = append(result, &todoCopy)
+			result = append(result, subtask.Clone())
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkProject fails with ErrProjectNotFound if a todo's project ID names
+// no project. Callers must hold the lock.
+func (f *FileStorage) checkProject(projectID int) error {
+	if projectID == 0 {
+		return nil
+	}
+	projects, err := f.projects.load()
+	if err != nil {
+		return err
+	}
+	return checkProjectRef(projects, projectID)
+}
+
+// CreateProject creates a new project
+func (f *FileStorage) CreateProject(project *models.Project) error {
+	if err := normalizeProject(project); err != nil {
+		return err
+	}
+
+	return f.withLock(true, func() error {
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+		if err := checkProjectName(projects, 0, project.Name); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		project.ID = nextProjectID(projects)
+		project.Version = 1
+		project.CreatedAt = now
+		project.UpdatedAt = now
+
+		projects[project.ID] = project.Clone()
+		return f.projects.save(projects)
+	})
+}
+
+// GetProject retrieves a project by its ID
+func (f *FileStorage) GetProject(id int) (*models.Project, error) {
+	var result *models.Project
+	err := f.withLock(false, func() error {
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+
+		project, exists := projects[id]
+		if !exists {
+			return ErrProjectNotFound
+		}
+		result = project.Clone()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListProjects retrieves the projects ordered by ID
+func (f *FileStorage) ListProjects(includeArchived bool) ([]*models.Project, error) {
+	var result []*models.Project
+	err := f.withLock(false, func() error {
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+
+		result = listProjects(projects, includeArchived)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateProject updates an existing project
+func (f *FileStorage) UpdateProject(id int, updatedProject *models.Project) error {
+	if err := normalizeProject(updatedProject); err != nil {
+		return err
+	}
+
+	return f.withLock(true, func() error {
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+
+		project, exists := projects[id]
+		if !exists {
+			return ErrProjectNotFound
+		}
+		if err := checkProjectName(projects, id, updatedProject.Name); err != nil {
+			return err
+		}
+		if err := prepareProjectUpdate(project, updatedProject); err != nil {
+			return err
+		}
+
+		projects[id] = updatedProject.Clone()
+		return f.projects.save(projects)
+	})
+}
+
+// DeleteProject permanently deletes a project no todo belongs to
+func (f *FileStorage) DeleteProject(id int) error {
+	return f.withLock(true, func() error {
+		projects, err := f.projects.load()
+		if err != nil {
+			return err
+		}
+		if _, exists := projects[id]; !exists {
+			return ErrProjectNotFound
+		}
+
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		if projectInUse(todos, id) {
+			return ErrProjectNotEmpty
+		}
+
+		delete(projects, id)
+		return f.projects.save(projects)
+	})
+}
+
+// sweepActivity removes the activity of todos purged from the saved todos.
+// The purge already happened, so a failure is only logged; the activity
+// left behind belongs to no todo, since IDs are never reissued, and goes
+// with the next purge.
+func (f *FileStorage) sweepActivity(todos map[int]*models.Todo) {
+	if err := f.activity.sweep(todos); err != nil {
+		log.Printf("storage: failed to remove the activity of purged todos: %v", err)
+	}
+}
+
+// Activity retrieves the activity of a todo outside the trash
+func (f *FileStorage) Activity(id int) (*models.Activity, error) {
+	var result *models.Activity
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		if todo, exists := todos[id]; !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+
+		activities, err := f.activity.load()
+		if err != nil {
+			return err
+		}
+		result = activityOf(activities, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ChangeActivity applies change to the activity of a todo outside the
+// trash. Only the activity file is written; the todos file is left alone.
+func (f *FileStorage) ChangeActivity(id int, change func(*models.Activity) error) (*models.Activity, error) {
+	var result *models.Activity
+	err := f.withLock(true, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		if todo, exists := todos[id]; !exists || todo.DeletedAt != nil {
+			return ErrTodoNotFound
+		}
+
+		activities, err := f.activity.load()
+		if err != nil {
+			return err
+		}
+		activity := activityOf(activities, id)
+		if err := change(activity); err != nil {
+			return err
+		}
+
+		activities[id] = activity.Clone()
+		if err := f.activity.save(activities); err != nil {
+			return err
+		}
+		result = activity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Activities retrieves the activity stored for the todos outside the trash
+func (f *FileStorage) Activities() ([]*models.Activity, error) {
+	var result []*models.Activity
+	err := f.withLock(false, func() error {
+		todos, _, err := f.loadTodos()
+		if err != nil {
+			return err
+		}
+		activities, err := f.activity.load()
+		if err != nil {
+			return err
+		}
+
+		result = listActivities(activities, todos)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
@@ -0,0 +1,93 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+	"github.com/shghadge/todo_mcp/internal/storage/storagetest"
+)
+
+func TestFileStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, workflow *models.Workflow) storage.TodoStorage {
+		return storage.WithContext(storage.NewFileStorage(filepath.Join(t.TempDir(), "todos.json"), workflow))
+	})
+}
+
+func TestFileStoragePurgeOutlivesActivityFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "todos.json")
+	s := storage.WithContext(storage.NewFileStorage(path, nil))
+
+	kept := &models.Todo{Title: "kept", Status: models.StatusPending}
+	gone := &models.Todo{Title: "gone", Status: models.StatusPending}
+	for _, todo := range []*models.Todo{kept, gone} {
+		if err := s.Create(ctx, todo); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if _, err := s.ChangeActivity(ctx, gone.ID, func(activity *models.Activity) error {
+		_, err := activity.AddComment(time.Now(), "agent", "about to go")
+		return err
+	}); err != nil {
+		t.Fatalf("ChangeActivity failed: %v", err)
+	}
+	if err := s.Delete(ctx, gone.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// An activity file that can't be read fails the sweep after the save,
+	// which leaves the purge standing
+	if err := os.Remove(path + ".activity"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := os.Mkdir(path+".activity", 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if err := s.Purge(ctx, gone.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	for name, s := range map[string]storage.TodoStorage{
+		"same storage":  s,
+		"fresh storage": storage.WithContext(storage.NewFileStorage(path, nil)),
+	} {
+		todos, err := s.GetAll(ctx)
+		if err != nil || len(todos) != 1 || todos[0].ID != kept.ID {
+			t.Errorf("%s: GetAll() = %v, %v, want the kept todo", name, todos, err)
+		}
+		if trash, err := s.ListTrash(ctx); err != nil || len(trash) != 0 {
+			t.Errorf("%s: ListTrash() = %v, %v, want it empty", name, trash, err)
+		}
+	}
+}
+
+func TestFileStorageKeepsPurgedIDsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "todos.json")
+	s := storage.WithContext(storage.NewFileStorage(path, nil))
+
+	purged := &models.Todo{Title: "purged", Status: models.StatusPending}
+	if err := s.Create(ctx, purged); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := s.Delete(ctx, purged.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Purge(ctx, purged.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	reopened := storage.WithContext(storage.NewFileStorage(path, nil))
+	todo := &models.Todo{Title: "new", Status: models.StatusPending}
+	if err := reopened.Create(ctx, todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if todo.ID <= purged.ID {
+		t.Errorf("Create after reopening assigned ID %d, want one above the purged %d", todo.ID, purged.ID)
+	}
+}
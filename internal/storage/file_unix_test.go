@@ -0,0 +1,45 @@
+//go:build unix
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+func TestFileStorageLockWaitEndsWithContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.json")
+	s := storage.WithContext(storage.NewFileStorage(path, nil))
+
+	// Another process holding the lock file
+	lockFile, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("failed to open lock file: %v", err)
+	}
+	defer lockFile.Close()
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.GetAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetAll while locked: err = %v, want DeadlineExceeded", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("GetAll waited %v after its deadline", waited)
+	}
+
+	syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	if _, err := s.GetAll(context.Background()); err != nil {
+		t.Errorf("GetAll after the lock was released failed: %v", err)
+	}
+}
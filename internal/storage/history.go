@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context naming who makes the changes done with it,
+// e.g. a REST client or an MCP client. Storage records the actor in the
+// history of each todo it changes.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or "" if none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// actorStorage is implemented by BasicStorage backends that can record the
+// actor of each change in their history. WithContext uses it to pass on
+// the actor carried by the context, and whether it forces updates; the
+// exported methods record no actor and force nothing.
+type actorStorage interface {
+	createAs(actor string, todo *models.Todo) error
+	updateAs(actor string, id int, todo *models.Todo, force bool) error
+	deleteAs(actor string, id int) error
+	restoreAs(actor string, id int) (*models.Todo, error)
+	purgeAs(actor string, id int) error
+	purgeTrashAs(actor string, before time.Time) (int, error)
+	retagAs(actor, from, to string, merge bool) (int, error)
+}
+
+// unaudited lists the fields left out of history diffs because every
+// change touches them
+var unaudited = map[string]bool{
+	"id":         true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// newTodoEvent describes the change from before to after, either of which
+// is nil when the todo was just created or has been purged
+func newTodoEvent(action models.TodoEventAction, actor string, before, after *models.Todo) *models.TodoEvent {
+	event := &models.TodoEvent{
+		Action:  action,
+		Actor:   actor,
+		Changes: diffTodos(before, after),
+		At:      time.Now(),
+	}
+	if after != nil {
+		event.TodoID = after.ID
+		event.Version = after.Version
+		event.At = after.UpdatedAt
+	} else if before != nil {
+		event.TodoID = before.ID
+		event.Version = before.Version
+	}
+	return event
+}
+
+// diffTodos returns the fields that differ between two todos, comparing
+// their JSON encoding so that new model fields are covered automatically
+func diffTodos(before, after *models.Todo) []models.FieldChange {
+	oldFields := todoFields(before)
+	newFields := todoFields(after)
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []models.FieldChange
+	for _, name := range names {
+		if unaudited[name] || bytes.Equal(oldFields[name], newFields[name]) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{
+			Field: name,
+			Old:   oldFields[name],
+			New:   newFields[name],
+		})
+	}
+	return changes
+}
+
+// todoFields returns the JSON encoding of each field of todo
+func todoFields(todo *models.Todo) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if todo == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(todo)
+	if err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// currentHistory drops the events of earlier todos that had the same ID.
+// IDs can be reused once a todo is purged, so a todo's history starts at
+// its last creation.
+func currentHistory(events []*models.TodoEvent) []*models.TodoEvent {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Action == models.EventCreated {
+			return events[i:]
+		}
+	}
+	return events
+}
+
+// historyFile keeps the audit history of the file and WAL backends as JSON
+// lines next to their data. Callers serialize access with the backend's own
+// locks.
+type historyFile struct {
+	path string
+}
+
+// append durably writes events to the end of the file
+func (h historyFile) append(events ...*models.TodoEvent) error {
+	var buf bytes.Buffer
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history event: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	file, err := os.OpenFile(h.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	// Start on a fresh line if a crash left the last one torn, so that only
+	// the torn event is lost
+	data := buf.Bytes()
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to append to history: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync history: %w", err)
+	}
+	return nil
+}
+
+// record appends events for a change that is already saved, so a failure
+// is only logged rather than reported as a failed change
+func (h historyFile) record(events ...*models.TodoEvent) {
+	if err := h.append(events...); err != nil {
+		log.Printf("storage: failed to record history in %s: %v", h.path, err)
+	}
+}
+
+// read returns the events recorded for a todo, oldest first. A line torn
+// by a crash during an append is skipped.
+func (h historyFile) read(todoID int) ([]*models.TodoEvent, error) {
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var events []*models.TodoEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event models.TodoEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.TodoID == todoID {
+			events = append(events, &event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return currentHistory(events), nil
+}
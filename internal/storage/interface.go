@@ -1,11 +1,140 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/shghadge/todo_mcp/internal/models"
 )
 
-// TodoStorage defines the interface for todo storage operations
+// TodoStorage defines the interface for todo storage operations. Every
+// method takes a context so callers can cancel a slow call or pass down a
+// deadline.
 type TodoStorage interface {
+	// Create creates a new todo item
+	Create(ctx context.Context, todo *models.Todo) error
+
+	// GetByID retrieves a todo by its ID
+	GetByID(ctx context.Context, id int) (*models.Todo, error)
+
+	// GetAll retrieves all todos
+	GetAll(ctx context.Context) ([]*models.Todo, error)
+
+	// Update updates an existing todo. If todo.Version is non-zero it must
+	// match the stored version or ErrVersionConflict is returned, making
+	// read-modify-write sequences safe; a zero Version overwrites blindly.
+	// On success todo holds the stored state with its new Version; on
+	// failure it is left as it was.
+	// Create and Update fail with ErrProjectNotFound if the todo's
+	// ProjectID names no project, and with ErrParentNotFound or
+	// ErrParentCycle if its ParentID doesn't name a todo outside the trash
+	// that it could be a subtask of. They fail with ErrBlockerNotFound if
+	// its BlockedBy names a todo that doesn't exist or is in the trash, and
+	// with ErrDependencyCycle if the todo would end up blocking itself.
+	// Update fails with models.ErrUnknownStatus if the todo's Status isn't
+	// a state of the storage's workflow, and with models.ErrInvalidTransition
+	// if the workflow doesn't let it move there from its stored status.
+	// Completing a todo, that is moving it into a done state, fails with
+	// ErrBlocked while any of its blockers is open, and moves all of its
+	// open subtasks, at any depth, into the same state. A context from
+	// WithForce skips the transition and blocker checks. Completing a
+	// recurring todo for the first time creates its next occurrence, as
+	// returned by models.Todo.NextOccurrence, and sets the todo's NextID to
+	// it.
+	Update(ctx context.Context, id int, todo *models.Todo) error
+
+	// Delete moves a todo to the trash along with its subtasks at any
+	// depth. Trashed todos are hidden from every other read and can't be
+	// updated until restored.
+	Delete(ctx context.Context, id int) error
+
+	// GetByStatus retrieves todos by status
+	GetByStatus(ctx context.Context, status models.TodoStatus) ([]*models.Todo, error)
+
+	// Query retrieves one page of the todos selected by the query
+	Query(ctx context.Context, query TodoQuery) (*TodoPage, error)
+
+	// ListTrash retrieves the todos in the trash
+	ListTrash(ctx context.Context) ([]*models.Todo, error)
+
+	// Restore moves a todo out of the trash, along with the subtasks
+	// trashed with it, and returns it. It fails with ErrParentTrashed while
+	// the todo's parent is in the trash.
+	Restore(ctx context.Context, id int) (*models.Todo, error)
+
+	// Purge permanently deletes a todo and its subtasks from the trash.
+	// Todos they blocked stop being blocked by them.
+	Purge(ctx context.Context, id int) error
+
+	// PurgeTrash permanently deletes the todos trashed before the cutoff,
+	// or the whole trash if the cutoff is zero, returning how many it
+	// deleted. Like Purge, it removes them from the blockers of other todos.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+
+	// History retrieves the changes made to a todo, oldest first. Every
+	// mutation above records an event with the actor set by WithActor and
+	// the fields it changed; the history outlives a purge of the todo.
+	History(ctx context.Context, id int) ([]*models.TodoEvent, error)
+
+	// Tags lists the tags of the todos outside the trash, ordered by tag,
+	// with how many todos carry each
+	Tags(ctx context.Context) ([]models.TagCount, error)
+
+	// RenameTag renames a tag on every todo carrying it, trash included,
+	// and returns how many todos it changed. It fails with ErrTagNotFound if
+	// no todo carries from and with ErrTagExists if one already carries to.
+	RenameTag(ctx context.Context, from, to string) (int, error)
+
+	// MergeTags replaces the tag from with into on every todo carrying it,
+	// like RenameTag but folding from into an existing tag
+	MergeTags(ctx context.Context, from, into string) (int, error)
+
+	// Subtasks retrieves the subtasks of a todo outside the trash at any
+	// depth, ordered by ID; models.BuildTree arranges them into a tree
+	Subtasks(ctx context.Context, id int) ([]*models.Todo, error)
+
+	// CreateProject creates a new project. Project names are unique,
+	// ignoring case, or ErrProjectExists is returned.
+	CreateProject(ctx context.Context, project *models.Project) error
+
+	// GetProject retrieves a project by its ID
+	GetProject(ctx context.Context, id int) (*models.Project, error)
+
+	// ListProjects retrieves the projects ordered by ID, including archived
+	// ones only if includeArchived is set
+	ListProjects(ctx context.Context, includeArchived bool) ([]*models.Project, error)
+
+	// UpdateProject updates an existing project, checking its Version like
+	// Update does for todos
+	UpdateProject(ctx context.Context, id int, project *models.Project) error
+
+	// DeleteProject permanently deletes a project. It fails with
+	// ErrProjectNotEmpty while any todo, trash included, belongs to it.
+	DeleteProject(ctx context.Context, id int) error
+
+	// Activity retrieves the activity of a todo outside the trash, which is
+	// empty if nothing was added to it yet. Activity is kept apart from the
+	// todo: no other read returns it, and changing it neither bumps the
+	// todo's Version nor records history. It is trashed and restored along
+	// with the todo and goes when the todo is purged.
+	Activity(ctx context.Context, id int) (*models.Activity, error)
+
+	// ChangeActivity applies change to the activity of a todo outside the
+	// trash and stores the result, returning it. Changes of the same
+	// activity are applied one at a time, so they never conflict; if change
+	// fails, its error is returned and nothing is stored.
+	ChangeActivity(ctx context.Context, id int, change func(*models.Activity) error) (*models.Activity, error)
+
+	// Activities retrieves the activity stored for the todos outside the
+	// trash, ordered by todo ID, leaving out todos with none
+	Activities(ctx context.Context) ([]*models.Activity, error)
+}
+
+// BasicStorage is the context-free form of TodoStorage. It is implemented
+// by backends whose calls are short enough that checking for cancellation
+// before each call is all that's needed; wrap them with WithContext.
+type BasicStorage interface {
 	// Create creates a new todo item
 	Create(todo *models.Todo) error
 
@@ -18,9 +147,140 @@ type TodoStorage interface {
 	// Update updates an existing todo
 	Update(id int, todo *models.Todo) error
 
-	// Delete deletes a todo by its ID
+	// Delete moves a todo to the trash
 	Delete(id int) error
 
 	// GetByStatus retrieves todos by status
 	GetByStatus(status models.TodoStatus) ([]*models.Todo, error)
+
+	// Query retrieves one page of the todos selected by the query
+	Query(query TodoQuery) (*TodoPage, error)
+
+	// ListTrash retrieves the todos in the trash
+	ListTrash() ([]*models.Todo, error)
+
+	// Restore moves a todo out of the trash and returns it
+	Restore(id int) (*models.Todo, error)
+
+	// Purge permanently deletes a todo from the trash
+	Purge(id int) error
+
+	// PurgeTrash permanently deletes the todos trashed before the cutoff,
+	// or the whole trash if the cutoff is zero
+	PurgeTrash(before time.Time) (int, error)
+
+	// History retrieves the changes made to a todo, oldest first
+	History(id int) ([]*models.TodoEvent, error)
+
+	// Tags lists the tags of the todos outside the trash with their counts
+	Tags() ([]models.TagCount, error)
+
+	// RenameTag renames a tag on every todo carrying it
+	RenameTag(from, to string) (int, error)
+
+	// MergeTags folds the tag from into an existing tag
+	MergeTags(from, into string) (int, error)
+
+	// Subtasks retrieves the subtasks of a todo at any depth
+	Subtasks(id int) ([]*models.Todo, error)
+
+	// CreateProject creates a new project
+	CreateProject(project *models.Project) error
+
+	// GetProject retrieves a project by its ID
+	GetProject(id int) (*models.Project, error)
+
+	// ListProjects retrieves the projects ordered by ID
+	ListProjects(includeArchived bool) ([]*models.Project, error)
+
+	// UpdateProject updates an existing project
+	UpdateProject(id int, project *models.Project) error
+
+	// DeleteProject permanently deletes a project no todo belongs to
+	DeleteProject(id int) error
+
+	// Activity retrieves the activity of a todo outside the trash
+	Activity(id int) (*models.Activity, error)
+
+	// ChangeActivity applies change to the activity of a todo outside the
+	// trash and returns the result
+	ChangeActivity(id int, change func(*models.Activity) error) (*models.Activity, error)
+
+	// Activities retrieves the activity stored for the todos outside the
+	// trash
+	Activities() ([]*models.Activity, error)
+}
+
+// Supported storage backends
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+	BackendWAL    = "wal"
+)
+
+// Open creates the TodoStorage for the named backend at the given path.
+// An empty path selects todos.json, todos.db or todos.log in the working
+// directory. The todos follow workflow, or models.DefaultWorkflow if it is
+// nil. walOptions configure the wal backend and are ignored by the others.
+func Open(backend, path string, workflow *models.Workflow, walOptions WALOptions) (TodoStorage, error) {
+	path = storagePath(backend, path)
+	switch backend {
+	case BackendFile, "":
+		fileStorage := NewFileStorage(path, workflow)
+		if err := fileStorage.CheckIntegrity(); err != nil {
+			return nil, err
+		}
+		return WithContext(fileStorage), nil
+	case BackendSQLite:
+		return NewSQLiteStorage(path, workflow)
+	case BackendWAL:
+		walStorage, err := NewWALStorage(path, workflow, walOptions)
+		if err != nil {
+			return nil, err
+		}
+		return WithContext(walStorage), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", backend)
+	}
+}
+
+// storagePath returns path, or the default path of the backend if it is
+// empty
+func storagePath(backend, path string) string {
+	if path != "" {
+		return path
+	}
+	switch backend {
+	case BackendSQLite:
+		return "todos.db"
+	case BackendWAL:
+		return "todos.log"
+	default:
+		return "todos.json"
+	}
+}
+
+// prepareUpdate checks the version of an update against the stored todo
+// and returns a copy of the update with the fields storage owns filled in:
+// the original ID, DeletedAt, CreatedAt and NextID, a LastChecklistItemID
+// that never goes back, a fresh UpdatedAt and the next version. A zero
+// Version skips the check, writing blindly over
+// whatever is stored.
+//
+// The update itself is left as it is, so that a caller whose write then
+// fails can retry it; backends copy the result back once it is stored.
+func prepareUpdate(existing, updated *models.Todo) (*models.Todo, error) {
+	if updated.Version != 0 && updated.Version != existing.Version {
+		return nil, ErrVersionConflict
+	}
+
+	stored := updated.Clone()
+	stored.ID = existing.ID
+	stored.NextID = existing.NextID
+	stored.LastChecklistItemID = max(existing.LastChecklistItemID, updated.LastChecklistItemID)
+	stored.DeletedAt = existing.DeletedAt
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = time.Now()
+	stored.Version = existing.Version + 1
+	return stored, nil
 }
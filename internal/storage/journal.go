@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// journalLimit is the number of mutations each session can undo
+const journalLimit = 100
+
+// A session's stacks are dropped once it has neither mutated nor undone
+// or redone anything for journalIdle, and beyond journalSessions sessions
+// the longest idle ones go first
+const (
+	journalIdle     = 24 * time.Hour
+	journalSessions = 1000
+)
+
+// JournalChange is one mutation reversed by Undo or reapplied by Redo
+type JournalChange struct {
+	// Action is the original mutation
+	Action models.TodoEventAction `json:"action"`
+
+	// Todo is the todo's state afterwards
+	Todo *models.Todo `json:"todo"`
+}
+
+// journalStacks are the undo and redo stacks of one session
+type journalStacks struct {
+	undo, redo []*journalEntry
+	used       time.Time
+}
+
+// journalEntry is a recorded mutation: the todo's state before and after
+// it, where a nil before stands for a todo that didn't exist yet
+type journalEntry struct {
+	action models.TodoEventAction
+	before *models.Todo
+	after  *models.Todo
+}
+
+// Journal is a TodoStorage that remembers the mutations made through it so
+// they can be undone and redone. Each session set with WithSession, or
+// without one each actor set with WithActor, has its own undo and redo
+// stacks, holding its last 100 mutations; making a new mutation clears the
+// session's redo stack. A session idle for a day is forgotten, and so are
+// the longest idle ones beyond a thousand sessions. Purges are final and can't be
+// undone, and tag renames and merges, which change many todos at once, are
+// not journaled; neither are changes to projects, only moving todos
+// between them. Subtasks trashed along with a todo come back when its
+// delete is undone, but those completed along with it stay completed, and
+// so does the next occurrence created by completing a recurring todo.
+//
+// Undo and redo are mutations themselves and show up in the history of the
+// todos they change. A todo changed by someone else since the journaled
+// mutation is not touched: the step fails with ErrVersionConflict and is
+// dropped from the journal.
+type Journal struct {
+	TodoStorage
+
+	mutex    sync.Mutex
+	sessions map[string]*journalStacks
+}
+
+// NewJournal returns a Journal recording the mutations made to storage
+func NewJournal(storage TodoStorage) *Journal {
+	return &Journal{
+		TodoStorage: storage,
+		sessions:    make(map[string]*journalStacks),
+	}
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context whose mutations a Journal keeps on the
+// undo and redo stacks of session rather than on those of the actor, for
+// clients whose name other clients may share
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// journalSession returns the session set by WithSession, or the actor if
+// there is none
+func journalSession(ctx context.Context) string {
+	if session, ok := ctx.Value(sessionKey{}).(string); ok {
+		return session
+	}
+	return ActorFromContext(ctx)
+}
+
+// record pushes a mutation on the session's undo stack
+func (j *Journal) record(ctx context.Context, entry *journalEntry) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	now := time.Now()
+	session := journalSession(ctx)
+	stacks, exists := j.sessions[session]
+	if !exists {
+		j.evict(now)
+		stacks = &journalStacks{}
+		j.sessions[session] = stacks
+	}
+	stacks.undo = pushEntry(stacks.undo, entry)
+	stacks.redo = nil
+	stacks.used = now
+}
+
+// evict drops the sessions idle for too long, and the longest idle ones
+// while there are too many to make room for another. Callers must hold the
+// mutex.
+func (j *Journal) evict(now time.Time) {
+	for session, stacks := range j.sessions {
+		if now.Sub(stacks.used) >= journalIdle {
+			delete(j.sessions, session)
+		}
+	}
+	for len(j.sessions) >= journalSessions {
+		var idlest string
+		for session, stacks := range j.sessions {
+			if idlest == "" || stacks.used.Before(j.sessions[idlest].used) {
+				idlest = session
+			}
+		}
+		delete(j.sessions, idlest)
+	}
+}
+
+// pushEntry pushes an entry on a stack, dropping the oldest beyond the
+// journal's limit
+func pushEntry(stack []*journalEntry, entry *journalEntry) []*journalEntry {
+	stack = append(stack, entry)
+	if len(stack) > journalLimit {
+		stack = slices.Clone(stack[len(stack)-journalLimit:])
+	}
+	return stack
+}
+
+// Create creates a new todo item
+func (j *Journal) Create(ctx context.Context, todo *models.Todo) error {
+	if err := j.TodoStorage.Create(ctx, todo); err != nil {
+		return err
+	}
+
+	j.record(ctx, &journalEntry{action: models.EventCreated, after: todo.Clone()})
+	return nil
+}
+
+// Update updates an existing todo. Blind updates, with a zero Version, are
+// pinned to the version read just before so that the journal knows exactly
+// which state they replaced.
+func (j *Journal) Update(ctx context.Context, id int, todo *models.Todo) error {
+	blind := todo.Version == 0
+
+	for {
+		before, err := j.TodoStorage.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if blind {
+			todo.Version = before.Version
+		}
+
+		err = j.TodoStorage.Update(ctx, id, todo)
+		if errors.Is(err, ErrVersionConflict) && blind {
+			// Another writer got in between; read again and retry
+			continue
+		}
+		if err != nil {
+			if blind {
+				todo.Version = 0
+			}
+			return err
+		}
+
+		j.record(ctx, &journalEntry{action: models.EventUpdated, before: before, after: todo.Clone()})
+		return nil
+	}
+}
+
+// Delete moves a todo to the trash. The journal records the todo as the
+// trash holds it; if it is gone from there already, restored or purged by
+// someone else, there is nothing left to undo.
+func (j *Journal) Delete(ctx context.Context, id int) error {
+	if err := j.TodoStorage.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	after, err := j.trashed(ctx, id)
+	if err != nil {
+		if !errors.Is(err, ErrTodoNotFound) {
+			log.Printf("storage: failed to journal the delete of todo %d: %v", id, err)
+		}
+		return nil
+	}
+	before := after.Clone()
+	before.DeletedAt = nil
+	j.record(ctx, &journalEntry{action: models.EventDeleted, before: before, after: after})
+	return nil
+}
+
+// trashed reads the todo with the given ID from the trash
+func (j *Journal) trashed(ctx context.Context, id int) (*models.Todo, error) {
+	trash, err := j.TodoStorage.ListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, todo := range trash {
+		if todo.ID == id {
+			return todo, nil
+		}
+	}
+	return nil, ErrTodoNotFound
+}
+
+// Restore moves a todo out of the trash and returns it
+func (j *Journal) Restore(ctx context.Context, id int) (*models.Todo, error) {
+	todo, err := j.TodoStorage.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	after := todo.Clone()
+	before := todo.Clone()
+	before.DeletedAt = &after.UpdatedAt
+	j.record(ctx, &journalEntry{action: models.EventRestored, before: before, after: after})
+	return todo, nil
+}
+
+// Undo reverses up to n of the session's most recent mutations, newest
+// first, and returns what it reversed; n below 1 counts as 1. It stops at
+// the first step that fails, returning the steps done before it along with
+// the error.
+func (j *Journal) Undo(ctx context.Context, n int) ([]JournalChange, error) {
+	return j.replay(ctx, n, ErrNothingToUndo, true)
+}
+
+// Redo reapplies up to n of the session's most recently undone mutations and
+// returns what it reapplied, stopping like Undo at the first failure
+func (j *Journal) Redo(ctx context.Context, n int) ([]JournalChange, error) {
+	return j.replay(ctx, n, ErrNothingToRedo, false)
+}
+
+// replay pops up to n entries off the session's undo or redo stack, moves
+// each todo back to its state before the mutation (undo) or forward to its
+// state after it (redo), and pushes the entries on the other stack
+func (j *Journal) replay(ctx context.Context, n int, empty error, undo bool) ([]JournalChange, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	stacks, exists := j.sessions[journalSession(ctx)]
+	if !exists {
+		return nil, empty
+	}
+	from, to := &stacks.undo, &stacks.redo
+	if !undo {
+		from, to = to, from
+	}
+	if len(*from) == 0 {
+		return nil, empty
+	}
+	if n < 1 {
+		n = 1
+	}
+	stacks.used = time.Now()
+
+	changes := make([]JournalChange, 0, n)
+	for len(changes) < n && len(*from) > 0 {
+		stack := *from
+		entry := stack[len(stack)-1]
+		*from = stack[:len(stack)-1]
+
+		current, target := entry.after, entry.before
+		if !undo {
+			current, target = entry.before, entry.after
+		}
+
+		result, err := j.transition(ctx, current, target)
+		if err != nil {
+			return changes, fmt.Errorf("failed to %s %s of todo %d: %w", replayVerb(undo), entry.action, current.ID, err)
+		}
+
+		if undo {
+			entry.before = result
+		} else {
+			entry.after = result
+		}
+
+		// The next entry for the same todo starts from the state this step
+		// left it in
+		for i := len(*from) - 1; i >= 0; i-- {
+			if next := (*from)[i]; next.after.ID == result.ID {
+				if undo {
+					next.after = result
+				} else {
+					next.before = result
+				}
+				break
+			}
+		}
+
+		*to = pushEntry(*to, entry)
+		changes = append(changes, JournalChange{Action: entry.action, Todo: result})
+	}
+
+	return changes, nil
+}
+
+func replayVerb(undo bool) string {
+	if undo {
+		return "undo"
+	}
+	return "redo"
+}
+
+// transition moves a todo from the journaled current state to target, nil
+// meaning the todo should be gone, and returns its new state. The todo must
+// still be at the current version unless it is in the trash.
+func (j *Journal) transition(ctx context.Context, current, target *models.Todo) (*models.Todo, error) {
+	wantTrashed := target == nil || target.DeletedAt != nil
+
+	if current.DeletedAt != nil {
+		if wantTrashed {
+			return current, nil
+		}
+		return j.TodoStorage.Restore(ctx, current.ID)
+	}
+
+	stored, err := j.TodoStorage.GetByID(ctx, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Version != current.Version {
+		return nil, ErrVersionConflict
+	}
+
+	if wantTrashed {
+		if err := j.TodoStorage.Delete(ctx, current.ID); err != nil {
+			return nil, err
+		}
+		return j.trashed(ctx, current.ID)
+	}
+
+	// Forced, since the recorded state may be out of reach of the workflow's
+	// transitions or have open blockers
+	updated := target.Clone()
+	updated.Version = current.Version
+	if err := j.TodoStorage.Update(WithForce(ctx), current.ID, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,230 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+	"github.com/shghadge/todo_mcp/internal/storage/storagetest"
+)
+
+// newJournal returns a journal over a fresh file storage whose todos
+// follow workflow, or models.DefaultWorkflow if it is nil
+func newJournal(t *testing.T, workflow *models.Workflow) *storage.Journal {
+	return storage.NewJournal(storage.WithContext(storage.NewFileStorage(filepath.Join(t.TempDir(), "todos.json"), workflow)))
+}
+
+func TestJournal(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, workflow *models.Workflow) storage.TodoStorage {
+		return newJournal(t, workflow)
+	})
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	j := newJournal(t, nil)
+	agent := storage.WithActor(context.Background(), "agent")
+	other := storage.WithActor(context.Background(), "other")
+
+	todo := &models.Todo{Title: "draft", Status: models.StatusPending}
+	if err := j.Create(agent, todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	edit := &models.Todo{Title: "final", Status: models.StatusCompleted}
+	if err := j.Update(agent, todo.ID, edit); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := j.Delete(agent, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Journals are kept per actor
+	if _, err := j.Undo(other, 1); !errors.Is(err, storage.ErrNothingToUndo) {
+		t.Fatalf("Undo by another actor error = %v, want ErrNothingToUndo", err)
+	}
+	// and per session, even of the same actor
+	if _, err := j.Undo(storage.WithSession(agent, "second session"), 1); !errors.Is(err, storage.ErrNothingToUndo) {
+		t.Fatalf("Undo in another session error = %v, want ErrNothingToUndo", err)
+	}
+
+	changes, err := j.Undo(agent, 2)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Action != models.EventDeleted || changes[1].Action != models.EventUpdated {
+		t.Fatalf("Undo = %+v, want the delete then the update", changes)
+	}
+	got, err := j.GetByID(agent, todo.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Undo failed: %v", err)
+	}
+	if got.Title != "draft" || got.Status != models.StatusPending {
+		t.Fatalf("after Undo todo = %+v, want the original", got)
+	}
+
+	// Undoing the create moves the todo to the trash; redo brings it back
+	if _, err := j.Undo(agent, 5); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := j.GetByID(agent, todo.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Fatalf("GetByID after undoing create error = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := j.Undo(agent, 1); !errors.Is(err, storage.ErrNothingToUndo) {
+		t.Fatalf("Undo of empty journal error = %v, want ErrNothingToUndo", err)
+	}
+
+	changes, err = j.Redo(agent, 2)
+	if err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Action != models.EventCreated || changes[1].Action != models.EventUpdated {
+		t.Fatalf("Redo = %+v, want the create then the update", changes)
+	}
+	got, err = j.GetByID(agent, todo.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Redo failed: %v", err)
+	}
+	if got.Title != "final" || got.Status != models.StatusCompleted {
+		t.Fatalf("after Redo todo = %+v, want the edited one", got)
+	}
+
+	// A change by someone else blocks undoing over it
+	got.Description = "changed elsewhere"
+	if err := j.Update(other, todo.ID, got); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := j.Undo(agent, 1); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("Undo over another change error = %v, want ErrVersionConflict", err)
+	}
+
+	// A new mutation clears the redo stack
+	if _, err := j.Undo(other, 1); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if err := j.Delete(other, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := j.Redo(other, 1); !errors.Is(err, storage.ErrNothingToRedo) {
+		t.Fatalf("Redo after a new mutation error = %v, want ErrNothingToRedo", err)
+	}
+}
+
+func TestJournalUndoTransition(t *testing.T) {
+	j := newJournal(t, &models.Workflow{
+		Initial: "open",
+		States: []models.State{
+			{Name: "open", Next: []models.TodoStatus{"closed"}},
+			{Name: "closed", Done: true},
+		},
+	})
+	ctx := storage.WithActor(context.Background(), "agent")
+	todo := &models.Todo{Title: "final", Status: "open"}
+	if err := j.Create(ctx, todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	todo.Status = "closed"
+	if err := j.Update(ctx, todo.ID, todo); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Undo restores the recorded state even though the workflow has no
+	// way back from closed
+	if _, err := j.Undo(ctx, 1); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	got, err := j.GetByID(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Undo failed: %v", err)
+	}
+	if got.Status != "open" {
+		t.Fatalf("after Undo status = %s, want open", got.Status)
+	}
+}
+
+// countingStorage hands out IDs without storing anything, so that the
+// journal's bookkeeping can be exercised across many sessions cheaply
+type countingStorage struct {
+	storage.TodoStorage
+	lastID int
+}
+
+func (s *countingStorage) Create(ctx context.Context, todo *models.Todo) error {
+	s.lastID++
+	todo.ID, todo.Version = s.lastID, 1
+	return nil
+}
+
+func (s *countingStorage) GetByID(ctx context.Context, id int) (*models.Todo, error) {
+	return &models.Todo{ID: id, Version: 1}, nil
+}
+
+func (s *countingStorage) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+func (s *countingStorage) ListTrash(ctx context.Context) ([]*models.Todo, error) {
+	trash := make([]*models.Todo, s.lastID)
+	for i := range trash {
+		deletedAt := time.Now()
+		trash[i] = &models.Todo{ID: i + 1, Version: 2, DeletedAt: &deletedAt}
+	}
+	return trash, nil
+}
+
+func TestJournalEvictsIdlestSession(t *testing.T) {
+	j := storage.NewJournal(&countingStorage{})
+	create := func(session string) {
+		t.Helper()
+		if err := j.Create(storage.WithSession(context.Background(), session), &models.Todo{Title: "todo"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	create("kept")
+	create("dropped")
+	for i := range 998 {
+		create(fmt.Sprintf("session %d", i))
+	}
+	create("kept")
+	// The thousand and first session makes room by forgetting the idlest
+	create("new")
+
+	if _, err := j.Undo(storage.WithSession(context.Background(), "dropped"), 1); !errors.Is(err, storage.ErrNothingToUndo) {
+		t.Errorf("Undo in the idlest session error = %v, want ErrNothingToUndo", err)
+	}
+	if changes, err := j.Undo(storage.WithSession(context.Background(), "kept"), 2); err != nil || len(changes) != 2 {
+		t.Errorf("Undo in a recently used session = %d changes, %v, want 2", len(changes), err)
+	}
+}
+
+func TestJournalDeleteRecordsTrashedTodo(t *testing.T) {
+	j := newJournal(t, nil)
+	ctx := storage.WithActor(context.Background(), "agent")
+	todo := &models.Todo{Title: "draft"}
+	if err := j.Create(ctx, todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := j.Delete(ctx, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := j.Undo(ctx, 1); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	changes, err := j.Redo(ctx, 1)
+	if err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	trash, err := j.ListTrash(ctx)
+	if err != nil || len(trash) != 1 {
+		t.Fatalf("ListTrash() = %d todos, %v, want 1", len(trash), err)
+	}
+	got, want := changes[0].Todo, trash[0]
+	if got.Version != want.Version || got.DeletedAt == nil || !got.DeletedAt.Equal(*want.DeletedAt) {
+		t.Errorf("Redo = version %d deleted at %v, want the stored version %d deleted at %v", got.Version, got.DeletedAt, want.Version, want.DeletedAt)
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectExists   = errors.New("a project with that name already exists")
+	ErrProjectNotEmpty = errors.New("project still has todos")
+	ErrInvalidProject  = errors.New("project name is required")
+)
+
+// NoProject is the TodoQuery.ProjectID selecting the todos in no project
+const NoProject = -1
+
+// projectNameKey is the form of a project name compared for uniqueness
+func projectNameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// normalizeProject trims the project's name and checks it isn't empty
+func normalizeProject(project *models.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		return ErrInvalidProject
+	}
+	return nil
+}
+
+// checkProjectName fails with ErrProjectExists if a project other than the
+// one with the given ID already has the name
+func checkProjectName(projects map[int]*models.Project, id int, name string) error {
+	key := projectNameKey(name)
+	for _, project := range projects {
+		if project.ID != id && projectNameKey(project.Name) == key {
+			return fmt.Errorf("%w: %q", ErrProjectExists, project.Name)
+		}
+	}
+	return nil
+}
+
+// checkProjectRef fails with ErrProjectNotFound if a todo's project ID
+// names no project. Zero, for no project, always passes.
+func checkProjectRef(projects map[int]*models.Project, projectID int) error {
+	if projectID == 0 {
+		return nil
+	}
+	if _, exists := projects[projectID]; !exists {
+		return fmt.Errorf("%w: %d", ErrProjectNotFound, projectID)
+	}
+	return nil
+}
+
+// prepareProjectUpdate checks the version of a project update like
+// prepareUpdate does for todos and fills in the fields storage owns
+func prepareProjectUpdate(existing, updated *models.Project) error {
+	if updated.Version != 0 && updated.Version != existing.Version {
+		return ErrVersionConflict
+	}
+
+	updated.ID = existing.ID
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = time.Now()
+	updated.Version = existing.Version + 1
+	return nil
+}
+
+// projectInUse reports whether any todo, trash included, belongs to the
+// project
+func projectInUse(todos map[int]*models.Todo, id int) bool {
+	for _, todo := range todos {
+		if todo.ProjectID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// listProjects returns copies of the projects ordered by ID, leaving out
+// archived ones unless includeArchived is set
+func listProjects(projects map[int]*models.Project, includeArchived bool) []*models.Project {
+	result := make([]*models.Project, 0, len(projects))
+	for _, project := range projects {
+		if project.Archived && !includeArchived {
+			continue
+		}
+		result = append(result, project.Clone())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// nextProjectID returns the ID following the highest one in projects
+func nextProjectID(projects map[int]*models.Project) int {
+	nextID := 1
+	for id := range projects {
+		if id >= nextID {
+			nextID = id + 1
+		}
+	}
+	return nextID
+}
+
+// queryCandidates returns the todos an in-memory query runs over: all of
+// them, except the todos of archived projects when the query hides those
+// and blocked todos when it asks for ready ones, by the states of w
+func queryCandidates(w *models.Workflow, todos map[int]*models.Todo, projects map[int]*models.Project, q TodoQuery) []*models.Todo {
+	hideArchived := q.hidesArchived()
+
+	result := make([]*models.Todo, 0, len(todos))
+	for _, todo := range todos {
+		if hideArchived && todo.ProjectID != 0 {
+			if project, exists := projects[todo.ProjectID]; exists && project.Archived {
+				continue
+			}
+		}
+		if q.Ready && isBlocked(w, todos, todo) {
+			continue
+		}
+		result = append(result, todo)
+	}
+	return result
+}
+
+// projectFile keeps the projects of the file backend as JSON next to its
+// todos. Callers serialize access with the backend's own lock.
+type projectFile struct {
+	path string
+}
+
+// load reads the projects, which are empty if the file doesn't exist yet
+func (p projectFile) load() (map[int]*models.Project, error) {
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return make(map[int]*models.Project), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects: %w", err)
+	}
+
+	projects := make(map[int]*models.Project)
+	if len(data) == 0 {
+		return projects, nil
+	}
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptFile, p.path, err)
+	}
+	return projects, nil
+}
+
+// save atomically replaces the projects file
+func (p projectFile) save(projects map[int]*models.Project) error {
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal projects: %w", err)
+	}
+	return writeFileAtomic(p.path, data, 0644)
+}
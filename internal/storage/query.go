@@ -0,0 +1,442 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrInvalidQuery  = errors.New("invalid query")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Fields todos can be sorted on
+const (
+	SortByID        = "id"
+	SortByTitle     = "title"
+	SortByStatus    = "status"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByPriority  = "priority"
+	SortByDueAt     = "due_at"
+)
+
+// SortFields lists the accepted values of TodoQuery.SortBy
+var SortFields = []string{SortByID, SortByTitle, SortByStatus, SortByCreatedAt, SortByUpdatedAt, SortByPriority, SortByDueAt}
+
+// TodoQuery selects, orders and pages todos. Zero-valued fields don't
+// filter, so the zero TodoQuery returns every todo ordered by ID.
+type TodoQuery struct {
+	// IDs restricts the result to these IDs
+	IDs []int
+
+	// Statuses restricts the result to todos in any of these statuses
+	Statuses []models.TodoStatus
+
+	// Priorities restricts the result to todos with any of these priorities
+	Priorities []models.Priority
+
+	// TagsAny, TagsAll and TagsNone restrict the result to todos carrying
+	// at least one, all or none of their tags, which must be normalized
+	// with models.NormalizeTags
+	TagsAny  []string
+	TagsAll  []string
+	TagsNone []string
+
+	// ProjectID restricts the result to the todos of one project, or with
+	// NoProject to the todos in none. Todos of archived projects are left
+	// out unless ProjectID names their project or IncludeArchived is set.
+	ProjectID       int
+	IncludeArchived bool
+
+	// ParentID restricts the result to the direct subtasks of one todo, or
+	// with NoParent to top-level todos
+	ParentID int
+
+	// Title and Description match case-insensitive substrings of the
+	// respective field; Text matches either of them
+	Title       string
+	Description string
+	Text        string
+
+	// CreatedSince and UpdatedSince are inclusive lower bounds, while
+	// CreatedBefore and UpdatedBefore are exclusive upper bounds
+	CreatedSince  time.Time
+	CreatedBefore time.Time
+	UpdatedSince  time.Time
+	UpdatedBefore time.Time
+
+	// DueSince and DueBefore bound the due date like the bounds above;
+	// setting either leaves out todos without a due date
+	DueSince  time.Time
+	DueBefore time.Time
+
+	// Open restricts the result to todos that aren't done
+	Open bool
+
+	// Ready restricts the result to todos that aren't done and whose
+	// blockers are all done or in the trash: the ones to work on next
+	Ready bool
+
+	// SortBy is one of SortFields, defaulting to SortByID. Ties are always
+	// broken by ID so that the order is stable across pages. Todos without
+	// a due date sort after all others by SortByDueAt.
+	SortBy     string
+	Descending bool
+
+	// Limit caps the number of todos returned, zero meaning no limit.
+	// When more todos match, the page's NextCursor continues after the
+	// last one returned.
+	Limit  int
+	Cursor string
+}
+
+// TodoPage is one page of query results
+type TodoPage struct {
+	Todos []*models.Todo
+
+	// NextCursor is set when more todos match the query
+	NextCursor string
+}
+
+// Validate checks the query for unknown sort fields and bad limits
+func (q *TodoQuery) Validate() error {
+	if q.SortBy != "" && !isSortField(q.SortBy) {
+		return fmt.Errorf("%w: sort field must be one of %s", ErrInvalidQuery, strings.Join(SortFields, ", "))
+	}
+	for _, p := range q.Priorities {
+		if !p.Valid() {
+			return fmt.Errorf("%w: unknown priority %q", ErrInvalidQuery, p)
+		}
+	}
+	for _, tags := range [][]string{q.TagsAny, q.TagsAll, q.TagsNone} {
+		for _, tag := range tags {
+			if normalized, err := models.NormalizeTag(tag); err != nil || normalized != tag {
+				return fmt.Errorf("%w: tag %q is not normalized", ErrInvalidQuery, tag)
+			}
+		}
+	}
+	if q.ProjectID < NoProject {
+		return fmt.Errorf("%w: invalid project ID %d", ErrInvalidQuery, q.ProjectID)
+	}
+	if q.ParentID < NoParent {
+		return fmt.Errorf("%w: invalid parent ID %d", ErrInvalidQuery, q.ParentID)
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	}
+	if q.Cursor != "" {
+		if _, err := q.decodeCursor(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortField returns the field to sort on, applying the default
+func (q *TodoQuery) sortField() string {
+	if q.SortBy == "" {
+		return SortByID
+	}
+	return q.SortBy
+}
+
+// hidesArchived reports whether the query leaves out the todos of archived
+// projects
+func (q *TodoQuery) hidesArchived() bool {
+	return !q.IncludeArchived && q.ProjectID <= 0
+}
+
+func isSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether todo passes the query's filters, which tell
+// open todos from done ones by the states of w. Todos in the trash never
+// match. Whether the todo's project is archived and whether its blockers
+// are open are not considered, as those depend on other records than the
+// todo.
+func (q *TodoQuery) Matches(w *models.Workflow, todo *models.Todo) bool {
+	if todo.DeletedAt != nil {
+		return false
+	}
+	if len(q.IDs) > 0 && !containsInt(q.IDs, todo.ID) {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsStatus(q.Statuses, todo.Status) {
+		return false
+	}
+	if len(q.Priorities) > 0 && !containsPriority(q.Priorities, todo.Priority) {
+		return false
+	}
+	switch {
+	case q.ProjectID == NoProject && todo.ProjectID != 0:
+		return false
+	case q.ProjectID > 0 && todo.ProjectID != q.ProjectID:
+		return false
+	}
+	switch {
+	case q.ParentID == NoParent && todo.ParentID != 0:
+		return false
+	case q.ParentID > 0 && todo.ParentID != q.ParentID:
+		return false
+	}
+	if len(q.TagsAny) > 0 && !slices.ContainsFunc(q.TagsAny, todo.HasTag) {
+		return false
+	}
+	for _, tag := range q.TagsAll {
+		if !todo.HasTag(tag) {
+			return false
+		}
+	}
+	if slices.ContainsFunc(q.TagsNone, todo.HasTag) {
+		return false
+	}
+	if q.Title != "" && !containsFold(todo.Title, q.Title) {
+		return false
+	}
+	if q.Description != "" && !containsFold(todo.Description, q.Description) {
+		return false
+	}
+	if q.Text != "" && !containsFold(todo.Title, q.Text) && !containsFold(todo.Description, q.Text) {
+		return false
+	}
+	if !q.CreatedSince.IsZero() && todo.CreatedAt.Before(q.CreatedSince) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !todo.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	if !q.UpdatedSince.IsZero() && todo.UpdatedAt.Before(q.UpdatedSince) {
+		return false
+	}
+	if !q.UpdatedBefore.IsZero() && !todo.UpdatedAt.Before(q.UpdatedBefore) {
+		return false
+	}
+	if !q.DueSince.IsZero() && (todo.DueAt == nil || todo.DueAt.Before(q.DueSince)) {
+		return false
+	}
+	if !q.DueBefore.IsZero() && (todo.DueAt == nil || !todo.DueAt.Before(q.DueBefore)) {
+		return false
+	}
+	if (q.Open || q.Ready) && w.Done(todo.Status) {
+		return false
+	}
+	return true
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStatus(values []models.TodoStatus, v models.TodoStatus) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPriority(values []models.Priority, v models.Priority) bool {
+	for _, value := range values {
+		if value.Rank() == v.Rank() {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether substr is within s, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(foldText(s), foldText(substr))
+}
+
+// foldText lowers the case of s for the text filters. SQLiteStorage calls
+// it through the fold_text SQL function, so that every backend folds
+// non-ASCII letters alike.
+func foldText(s string) string {
+	return strings.ToLower(s)
+}
+
+// compareTodos orders a and b by the sort field, then by ID
+func compareTodos(a, b *models.Todo, field string) int {
+	var c int
+	switch field {
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	case SortByStatus:
+		c = strings.Compare(string(a.Status), string(b.Status))
+	case SortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByPriority:
+		c = a.Priority.Rank() - b.Priority.Rank()
+	case SortByDueAt:
+		c = compareInt64(dueKey(a), dueKey(b))
+	}
+	if c != 0 {
+		return c
+	}
+	switch {
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	}
+	return 0
+}
+
+// dueKey is the key todos are sorted on by SortByDueAt: the due date in
+// Unix nanoseconds, or the largest key for todos without one
+func dueKey(todo *models.Todo) int64 {
+	if todo.DueAt == nil {
+		return math.MaxInt64
+	}
+	return todo.DueAt.UnixNano()
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// queryCursor is the decoded form of TodoQuery.Cursor: the sort key of the
+// last todo on the previous page
+type queryCursor struct {
+	SortBy string    `json:"s"`
+	ID     int       `json:"i"`
+	Text   string    `json:"t,omitempty"`
+	Time   time.Time `json:"d,omitzero"`
+}
+
+// cursorFor returns the cursor continuing after todo
+func (q *TodoQuery) cursorFor(todo *models.Todo) string {
+	c := queryCursor{SortBy: q.sortField(), ID: todo.ID}
+	switch c.SortBy {
+	case SortByTitle:
+		c.Text = todo.Title
+	case SortByStatus:
+		c.Text = string(todo.Status)
+	case SortByCreatedAt:
+		c.Time = todo.CreatedAt
+	case SortByUpdatedAt:
+		c.Time = todo.UpdatedAt
+	case SortByPriority:
+		c.Text = string(todo.Priority)
+	case SortByDueAt:
+		if todo.DueAt != nil {
+			c.Time = *todo.DueAt
+		}
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the todo position the cursor points at, holding
+// just the ID and the sort field
+func (q *TodoQuery) decodeCursor() (*models.Todo, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c queryCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != q.sortField() {
+		return nil, fmt.Errorf("%w: cursor belongs to a query sorted by %s", ErrInvalidCursor, c.SortBy)
+	}
+
+	pivot := &models.Todo{ID: c.ID}
+	switch c.SortBy {
+	case SortByTitle:
+		pivot.Title = c.Text
+	case SortByStatus:
+		pivot.Status = models.TodoStatus(c.Text)
+	case SortByCreatedAt:
+		pivot.CreatedAt = c.Time
+	case SortByUpdatedAt:
+		pivot.UpdatedAt = c.Time
+	case SortByPriority:
+		pivot.Priority = models.Priority(c.Text)
+	case SortByDueAt:
+		if !c.Time.IsZero() {
+			pivot.DueAt = &c.Time
+		}
+	}
+	return pivot, nil
+}
+
+// ApplyQuery filters, sorts and pages todos in memory. It is the query
+// path for backends that hold every todo in memory anyway; the todos in the
+// returned page are the ones passed in, not copies. Callers leave out the
+// todos of archived projects beforehand if the query hides them, and w is
+// the workflow telling open todos from done ones.
+func ApplyQuery(w *models.Workflow, todos []*models.Todo, q TodoQuery) (*TodoPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	field := q.sortField()
+	less := func(a, b *models.Todo) bool {
+		if q.Descending {
+			return compareTodos(a, b, field) > 0
+		}
+		return compareTodos(a, b, field) < 0
+	}
+
+	var pivot *models.Todo
+	if q.Cursor != "" {
+		pivot, _ = q.decodeCursor()
+	}
+
+	matched := make([]*models.Todo, 0)
+	for _, todo := range todos {
+		if !q.Matches(w, todo) {
+			continue
+		}
+		if pivot != nil && !less(pivot, todo) {
+			continue
+		}
+		matched = append(matched, todo)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return less(matched[i], matched[j])
+	})
+
+	page := &TodoPage{Todos: matched}
+	if q.Limit > 0 && len(matched) > q.Limit {
+		page.Todos = matched[:q.Limit]
+		page.NextCursor = q.cursorFor(page.Todos[q.Limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "github.com/shghadge/todo_mcp/internal/models"
+
+// nextOccurrence returns the todo an update from before to after creates:
+// the next occurrence of a recurring todo it completes, unless the todo
+// has had one already, or nil. after must have been through prepareUpdate.
+func nextOccurrence(w *models.Workflow, before, after *models.Todo) *models.Todo {
+	if !completes(w, before, after) || after.NextID != 0 {
+		return nil
+	}
+	return after.NextOccurrence(w, after.UpdatedAt)
+}
+
+// linkOccurrence gives the next occurrence of a completed todo its ID,
+// version and timestamps, and links the todo to it
+func linkOccurrence(todo, next *models.Todo, id int) {
+	next.ID = id
+	next.Version = 1
+	next.CreatedAt = todo.UpdatedAt
+	next.UpdatedAt = todo.UpdatedAt
+	todo.NextID = id
+}
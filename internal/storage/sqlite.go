@@ -0,0 +1,1351 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteMigrations holds the schema changes applied in order on startup.
+// The index of each entry plus one is recorded in PRAGMA user_version, so
+// new migrations must only ever be appended.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS todos (
+		id         INTEGER PRIMARY KEY,
+		status     TEXT    NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		data       TEXT    NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_todos_status ON todos(status);`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(created_at);
+	CREATE INDEX IF NOT EXISTS idx_todos_updated_at ON todos(updated_at);`,
+	`ALTER TABLE todos ADD COLUMN deleted_at INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deleted_at);`,
+	`CREATE TABLE IF NOT EXISTS todo_events (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		todo_id INTEGER NOT NULL,
+		at      INTEGER NOT NULL,
+		data    TEXT    NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_todo_events_todo_id ON todo_events(todo_id);`,
+	`ALTER TABLE todos ADD COLUMN due_at INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_todos_due_at ON todos(due_at);`,
+	`CREATE TABLE IF NOT EXISTS projects (
+		id       INTEGER PRIMARY KEY,
+		name_key TEXT    NOT NULL UNIQUE,
+		archived INTEGER NOT NULL,
+		data     TEXT    NOT NULL
+	);
+	ALTER TABLE todos ADD COLUMN project_id INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);`,
+	`ALTER TABLE todos ADD COLUMN parent_id INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);`,
+	`CREATE TABLE IF NOT EXISTS todo_activity (
+		todo_id INTEGER PRIMARY KEY,
+		data    TEXT    NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS todo_sequence (next_id INTEGER NOT NULL);
+	INSERT INTO todo_sequence (next_id) SELECT COALESCE(MAX(id), 0) + 1 FROM todos;`,
+}
+
+// fold_text folds the case of text in SQL the way foldText does in Go,
+// which SQLite's lower() only does for ASCII letters
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("fold_text", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch text := args[0].(type) {
+		case string:
+			return foldText(text), nil
+		case []byte:
+			return foldText(string(text)), nil
+		}
+		return args[0], nil
+	})
+}
+
+// sqliteAncestors selects the todo with the ID bound to its placeholder and
+// its ancestors, trash included
+const sqliteAncestors = `WITH RECURSIVE family(id) AS (
+	SELECT ? UNION SELECT todos.parent_id FROM todos JOIN family ON todos.id = family.id WHERE todos.parent_id IS NOT NULL
+) SELECT data FROM todos WHERE id IN (SELECT id FROM family)`
+
+// sqliteSubtree selects the todo with the ID bound to its placeholder and
+// its subtasks at any depth, trash included
+const sqliteSubtree = `WITH RECURSIVE family(id) AS (
+	SELECT ? UNION SELECT todos.id FROM todos JOIN family ON todos.parent_id = family.id
+) SELECT data FROM todos WHERE id IN (SELECT id FROM family)`
+
+// sqliteBlockerChain selects the todos whose IDs are in the JSON array
+// bound to its placeholder and the todos blocking them at any depth, trash
+// included
+const sqliteBlockerChain = `WITH RECURSIVE chain(id) AS (
+	SELECT value FROM json_each(?)
+	UNION SELECT blocker.value FROM chain JOIN todos ON todos.id = chain.id JOIN json_each(todos.data, '$.blocked_by') AS blocker
+) SELECT data FROM todos WHERE id IN (SELECT id FROM chain)`
+
+// sqliteBlocked is the SQL condition for a todos row with open blockers,
+// with the storage's doneStatuses bound to its placeholder
+const sqliteBlocked = `EXISTS (SELECT 1 FROM json_each(todos.data, '$.blocked_by') AS b
+	JOIN todos AS blocker ON blocker.id = b.value
+	WHERE blocker.deleted_at IS NULL AND blocker.status NOT IN (SELECT value FROM json_each(?)))`
+
+// sqlitePriorityRank is the SQL expression for models.Priority.Rank of a
+// todos row
+var sqlitePriorityRank = func() string {
+	expr := "CASE json_extract(data, '$.priority')"
+	for _, p := range models.Priorities {
+		expr += fmt.Sprintf(" WHEN '%s' THEN %d", p, p.Rank())
+	}
+	return expr + " ELSE 0 END"
+}()
+
+// sqliteSortColumns maps sort fields to the SQL expressions they order by
+var sqliteSortColumns = map[string]string{
+	SortByID:        "id",
+	SortByTitle:     "json_extract(data, '$.title')",
+	SortByStatus:    "status",
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByPriority:  sqlitePriorityRank,
+	SortByDueAt:     fmt.Sprintf("COALESCE(due_at, %d)", int64(math.MaxInt64)),
+}
+
+// SQLiteStorage implements TodoStorage using an embedded SQLite database.
+// Indexed columns hold the fields used for lookups, while the full todo is
+// kept as JSON in the data column so the model can grow without a schema
+// change for every field.
+type SQLiteStorage struct {
+	db       *sql.DB
+	workflow *models.Workflow
+}
+
+// NewSQLiteStorage opens (or creates) the SQLite database at dbPath and
+// brings its schema up to date. Its todos follow workflow, or
+// models.DefaultWorkflow if it is nil.
+func NewSQLiteStorage(dbPath string, workflow *models.Workflow) (*SQLiteStorage, error) {
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// The path is escaped so that a ? or # in it can't end the file name
+	// early. Transactions take the write lock up front: they read before
+	// they write, and a deferred transaction upgrading its read lock while
+	// another process writes fails with SQLITE_BUSY at once, without
+	// waiting out the busy timeout.
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", url.PathEscape(dbPath))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; serializing connections avoids
+	// SQLITE_BUSY errors between goroutines of the same process
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStorage{db: db, workflow: models.WorkflowOrDefault(workflow)}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close closes the underlying database
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies any schema migrations that have not run yet
+func (s *SQLiteStorage) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration: %w", err)
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// scanTodo decodes the data column of a todos row
+func scanTodo(row interface{ Scan(...any) error }) (*models.Todo, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		return nil, err
+	}
+
+	var todo models.Todo
+	if err := json.Unmarshal([]byte(data), &todo); err != nil {
+		return nil, fmt.Errorf("failed to parse todo: %w", err)
+	}
+	return &todo, nil
+}
+
+// queryTodos runs a query selecting the data column and decodes every row
+func (s *SQLiteStorage) queryTodos(ctx context.Context, query string, args ...any) ([]*models.Todo, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Todo, 0)
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+
+	return result, nil
+}
+
+// Create creates a new todo item
+func (s *SQLiteStorage) Create(ctx context.Context, todo *models.Todo) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkSQLiteProject(ctx, tx, todo.ProjectID); err != nil {
+		return err
+	}
+	if err := checkSQLiteParent(ctx, tx, 0, todo.ParentID); err != nil {
+		return err
+	}
+	if err := checkSQLiteBlockers(ctx, tx, 0, todo.BlockedBy); err != nil {
+		return err
+	}
+
+	nextID, err := nextSQLiteID(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored := *todo
+	stored.ID = nextID
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	if err := insertTodo(ctx, tx, &stored); err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, newTodoEvent(models.EventCreated, ActorFromContext(ctx), nil, &stored)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	*todo = stored
+	return nil
+}
+
+// GetByID retrieves a todo by its ID
+func (s *SQLiteStorage) GetByID(ctx context.Context, id int) (*models.Todo, error) {
+	todo, err := scanTodo(s.db.QueryRowContext(ctx, "SELECT data FROM todos WHERE id = ? AND deleted_at IS NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTodoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read todo: %w", err)
+	}
+	return todo, nil
+}
+
+// GetAll retrieves all todos
+func (s *SQLiteStorage) GetAll(ctx context.Context) ([]*models.Todo, error) {
+	return s.queryTodos(ctx, "SELECT data FROM todos WHERE deleted_at IS NULL ORDER BY id")
+}
+
+// Update updates an existing todo
+func (s *SQLiteStorage) Update(ctx context.Context, id int, updatedTodo *models.Todo) error {
+	stored, err := s.replaceTodo(ctx, id, false, models.EventUpdated, func(existing *models.Todo) (*models.Todo, error) {
+		if err := checkTransition(s.workflow, existing, updatedTodo, forced(ctx)); err != nil {
+			return nil, err
+		}
+		return prepareUpdate(existing, updatedTodo)
+	})
+	if err != nil {
+		return err
+	}
+
+	*updatedTodo = *stored
+	return nil
+}
+
+// Delete moves a todo to the trash
+func (s *SQLiteStorage) Delete(ctx context.Context, id int) error {
+	_, err := s.replaceTodo(ctx, id, false, models.EventDeleted, func(todo *models.Todo) (*models.Todo, error) {
+		return trashedCopy(todo, time.Now()), nil
+	})
+	return err
+}
+
+// nextSQLiteID allocates the ID for a new todo. The todo_sequence table
+// keeps it past the highest ID in todos, so that IDs of purged todos are
+// never reissued.
+func nextSQLiteID(ctx context.Context, tx *sql.Tx) (int, error) {
+	var nextID int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT MAX(next_id, (SELECT COALESCE(MAX(id), 0) + 1 FROM todos)) FROM todo_sequence",
+	).Scan(&nextID); err != nil {
+		return 0, fmt.Errorf("failed to allocate ID: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE todo_sequence SET next_id = ?", nextID+1); err != nil {
+		return 0, fmt.Errorf("failed to allocate ID: %w", err)
+	}
+	return nextID, nil
+}
+
+// insertTodo stores a new todo's indexed columns and data
+func insertTodo(ctx context.Context, tx *sql.Tx, todo *models.Todo) error {
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO todos (id, status, created_at, updated_at, due_at, project_id, parent_id, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		todo.ID, string(todo.Status), todo.CreatedAt.UnixNano(), todo.UpdatedAt.UnixNano(), sqliteTime(todo.DueAt), sqliteNullID(todo.ProjectID), sqliteNullID(todo.ParentID), string(data),
+	); err != nil {
+		return fmt.Errorf("failed to insert todo: %w", err)
+	}
+	return nil
+}
+
+// writeTodo stores an existing todo's indexed columns and data
+func writeTodo(ctx context.Context, tx *sql.Tx, todo *models.Todo) error {
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE todos SET status = ?, updated_at = ?, deleted_at = ?, due_at = ?, project_id = ?, parent_id = ?, data = ? WHERE id = ?",
+		string(todo.Status), todo.UpdatedAt.UnixNano(), sqliteTime(todo.DeletedAt), sqliteTime(todo.DueAt), sqliteNullID(todo.ProjectID), sqliteNullID(todo.ParentID), string(data), todo.ID,
+	); err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
+	}
+	return nil
+}
+
+// sqliteTime converts an optional time to its column value, NULL when unset
+func sqliteTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+// sqliteNullID converts a todo's project or parent ID to its column
+// value, NULL for none
+func sqliteNullID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// checkSQLiteProject fails with ErrProjectNotFound if a todo's project ID
+// names no project
+func checkSQLiteProject(ctx context.Context, tx *sql.Tx, projectID int) error {
+	if projectID == 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM projects WHERE id = ?)", projectID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to read project: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrProjectNotFound, projectID)
+	}
+	return nil
+}
+
+// loadSQLiteTodos runs a query selecting the data column of todos within
+// the transaction and returns the todos by ID
+func loadSQLiteTodos(ctx context.Context, tx *sql.Tx, query string, args ...any) (map[int]*models.Todo, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos := make(map[int]*models.Todo)
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos[todo.ID] = todo
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	return todos, nil
+}
+
+// checkSQLiteParent runs checkParent against the parent's ancestors
+func checkSQLiteParent(ctx context.Context, tx *sql.Tx, id, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+
+	ancestors, err := loadSQLiteTodos(ctx, tx, sqliteAncestors, parentID)
+	if err != nil {
+		return err
+	}
+	return checkParent(ancestors, id, parentID)
+}
+
+// sqliteIDs encodes todo IDs as the JSON array json_each expects
+func sqliteIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(ids)
+	return string(data)
+}
+
+// doneStatuses returns the done states of the storage's workflow as a JSON
+// array, for json_each
+func (s *SQLiteStorage) doneStatuses() string {
+	data, _ := json.Marshal(s.workflow.DoneStatuses())
+	return string(data)
+}
+
+// checkSQLiteBlockers runs checkBlockers against the chain of todos
+// blocking the todo
+func checkSQLiteBlockers(ctx context.Context, tx *sql.Tx, id int, blockedBy []int) error {
+	if len(blockedBy) == 0 {
+		return nil
+	}
+
+	chain, err := loadSQLiteTodos(ctx, tx, sqliteBlockerChain, sqliteIDs(blockedBy))
+	if err != nil {
+		return err
+	}
+	return checkBlockers(chain, id, blockedBy)
+}
+
+// checkSQLiteCompletion runs checkCompletion against the todo's blockers
+func checkSQLiteCompletion(ctx context.Context, tx *sql.Tx, w *models.Workflow, existing, changed *models.Todo) error {
+	if forced(ctx) || !completes(w, existing, changed) || len(changed.BlockedBy) == 0 {
+		return nil
+	}
+
+	blockers, err := loadSQLiteTodos(ctx, tx, "SELECT data FROM todos WHERE id IN (SELECT value FROM json_each(?))", sqliteIDs(changed.BlockedBy))
+	if err != nil {
+		return err
+	}
+	return checkCompletion(w, blockers, existing, changed, false)
+}
+
+// cascade returns the changes to subtasks that a change of their ancestor
+// from existing to changed carries along, like the other backends: trashing
+// or completing it does the same to its live or open subtasks, and
+// restoring it restores the subtasks trashed with it
+func cascade(ctx context.Context, tx *sql.Tx, w *models.Workflow, action models.TodoEventAction, existing, changed *models.Todo) ([]carriedChange, error) {
+	if action == models.EventUpdated && !completes(w, existing, changed) {
+		return nil, nil
+	}
+	if action == models.EventRestored && existing.ParentID != 0 {
+		ancestors, err := loadSQLiteTodos(ctx, tx, sqliteAncestors, existing.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRestore(ancestors, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	family, err := loadSQLiteTodos(ctx, tx, sqliteSubtree, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtasks []*models.Todo
+	var carry func(*models.Todo, time.Time) *models.Todo
+	switch action {
+	case models.EventUpdated:
+		subtasks = completedSubtasks(w, family, existing, changed)
+		carry = func(subtask *models.Todo, at time.Time) *models.Todo {
+			return completedCopy(subtask, changed.Status, at)
+		}
+	case models.EventDeleted:
+		subtasks, carry = liveSubtasks(family, existing.ID), trashedCopy
+	case models.EventRestored:
+		subtasks, carry = restoredSubtasks(family, existing), restoredCopy
+	}
+
+	changes := make([]carriedChange, 0, len(subtasks))
+	for _, subtask := range subtasks {
+		changes = append(changes, carriedChange{before: subtask, after: carry(subtask, changed.UpdatedAt)})
+	}
+	return changes, nil
+}
+
+// insertEvent records a history event in the same transaction as the
+// change it describes
+func insertEvent(ctx context.Context, tx *sql.Tx, event *models.TodoEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history event: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO todo_events (todo_id, at, data) VALUES (?, ?, ?)",
+		event.TodoID, event.At.UnixNano(), string(data),
+	); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// replaceTodo replaces a live or trashed todo with the result of change
+// and records the change in its history, along with the changes it carries
+// to subtasks and the next occurrence it creates, within a single
+// transaction, and returns the new version
+func (s *SQLiteStorage) replaceTodo(ctx context.Context, id int, trashed bool, action models.TodoEventAction, change func(*models.Todo) (*models.Todo, error)) (*models.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	condition := "deleted_at IS NULL"
+	if trashed {
+		condition = "deleted_at IS NOT NULL"
+	}
+
+	existing, err := scanTodo(tx.QueryRowContext(ctx, "SELECT data FROM todos WHERE id = ? AND "+condition, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTodoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read todo: %w", err)
+	}
+
+	changed, err := change(existing)
+	if err != nil {
+		return nil, err
+	}
+	if changed.ProjectID != existing.ProjectID {
+		if err := checkSQLiteProject(ctx, tx, changed.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	if changed.ParentID != existing.ParentID {
+		if err := checkSQLiteParent(ctx, tx, id, changed.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Equal(changed.BlockedBy, existing.BlockedBy) {
+		if err := checkSQLiteBlockers(ctx, tx, id, changed.BlockedBy); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSQLiteCompletion(ctx, tx, s.workflow, existing, changed); err != nil {
+		return nil, err
+	}
+	subtasks, err := cascade(ctx, tx, s.workflow, action, existing, changed)
+	if err != nil {
+		return nil, err
+	}
+	var next *models.Todo
+	if action == models.EventUpdated {
+		next = nextOccurrence(s.workflow, existing, changed)
+	}
+	if next != nil {
+		nextID, err := nextSQLiteID(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		linkOccurrence(changed, next, nextID)
+	}
+
+	actor := ActorFromContext(ctx)
+	if err := writeTodo(ctx, tx, changed); err != nil {
+		return nil, err
+	}
+	if err := insertEvent(ctx, tx, newTodoEvent(action, actor, existing, changed)); err != nil {
+		return nil, err
+	}
+	for _, subtask := range subtasks {
+		if err := writeTodo(ctx, tx, subtask.after); err != nil {
+			return nil, err
+		}
+		if err := insertEvent(ctx, tx, newTodoEvent(action, actor, subtask.before, subtask.after)); err != nil {
+			return nil, err
+		}
+	}
+	if next != nil {
+		if err := insertTodo(ctx, tx, next); err != nil {
+			return nil, err
+		}
+		if err := insertEvent(ctx, tx, newTodoEvent(models.EventCreated, actor, nil, next)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// GetByStatus retrieves todos by status
+func (s *SQLiteStorage) GetByStatus(ctx context.Context, status models.TodoStatus) ([]*models.Todo, error) {
+	return s.queryTodos(ctx, "SELECT data FROM todos WHERE status = ? AND deleted_at IS NULL ORDER BY id", string(status))
+}
+
+// Query retrieves one page of the todos selected by the query. Filters,
+// ordering and the page boundary all run in SQL, using the indexes on
+// status and timestamps.
+func (s *SQLiteStorage) Query(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+
+	if len(query.IDs) > 0 {
+		where = append(where, "id IN ("+placeholders(len(query.IDs))+")")
+		for _, id := range query.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(query.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(query.Statuses))+")")
+		for _, status := range query.Statuses {
+			args = append(args, string(status))
+		}
+	}
+	if len(query.Priorities) > 0 {
+		where = append(where, sqlitePriorityRank+" IN ("+placeholders(len(query.Priorities))+")")
+		for _, p := range query.Priorities {
+			args = append(args, p.Rank())
+		}
+	}
+	if len(query.TagsAny) > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(todos.data, '$.tags') WHERE value IN ("+placeholders(len(query.TagsAny))+"))")
+		for _, tag := range query.TagsAny {
+			args = append(args, tag)
+		}
+	}
+	for _, tag := range query.TagsAll {
+		where = append(where, sqliteHasTag)
+		args = append(args, tag)
+	}
+	if len(query.TagsNone) > 0 {
+		where = append(where, "NOT EXISTS (SELECT 1 FROM json_each(todos.data, '$.tags') WHERE value IN ("+placeholders(len(query.TagsNone))+"))")
+		for _, tag := range query.TagsNone {
+			args = append(args, tag)
+		}
+	}
+	switch {
+	case query.ParentID == NoParent:
+		where = append(where, "parent_id IS NULL")
+	case query.ParentID > 0:
+		where = append(where, "parent_id = ?")
+		args = append(args, query.ParentID)
+	}
+	switch {
+	case query.ProjectID == NoProject:
+		where = append(where, "project_id IS NULL")
+	case query.ProjectID > 0:
+		where = append(where, "project_id = ?")
+		args = append(args, query.ProjectID)
+	}
+	if query.hidesArchived() {
+		where = append(where, "(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived))")
+	}
+	if query.Title != "" {
+		where = append(where, "instr(fold_text(json_extract(data, '$.title')), ?) > 0")
+		args = append(args, foldText(query.Title))
+	}
+	if query.Description != "" {
+		where = append(where, "instr(fold_text(json_extract(data, '$.description')), ?) > 0")
+		args = append(args, foldText(query.Description))
+	}
+	if query.Text != "" {
+		where = append(where, "(instr(fold_text(json_extract(data, '$.title')), ?) > 0 OR instr(fold_text(json_extract(data, '$.description')), ?) > 0)")
+		args = append(args, foldText(query.Text), foldText(query.Text))
+	}
+	if !query.CreatedSince.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, query.CreatedSince.UnixNano())
+	}
+	if !query.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, query.CreatedBefore.UnixNano())
+	}
+	if !query.UpdatedSince.IsZero() {
+		where = append(where, "updated_at >= ?")
+		args = append(args, query.UpdatedSince.UnixNano())
+	}
+	if !query.UpdatedBefore.IsZero() {
+		where = append(where, "updated_at < ?")
+		args = append(args, query.UpdatedBefore.UnixNano())
+	}
+	if !query.DueSince.IsZero() {
+		where = append(where, "due_at >= ?")
+		args = append(args, query.DueSince.UnixNano())
+	}
+	if !query.DueBefore.IsZero() {
+		where = append(where, "due_at < ?")
+		args = append(args, query.DueBefore.UnixNano())
+	}
+	if query.Open || query.Ready {
+		where = append(where, "status NOT IN (SELECT value FROM json_each(?))")
+		args = append(args, s.doneStatuses())
+	}
+	if query.Ready {
+		where = append(where, "NOT "+sqliteBlocked)
+		args = append(args, s.doneStatuses())
+	}
+
+	field := query.sortField()
+	column := sqliteSortColumns[field]
+	direction, op := "ASC", ">"
+	if query.Descending {
+		direction, op = "DESC", "<"
+	}
+
+	// Continue strictly after the (sort key, id) of the cursor
+	if query.Cursor != "" {
+		pivot, _ := query.decodeCursor()
+		var key any
+		switch field {
+		case SortByID:
+			key = pivot.ID
+		case SortByTitle:
+			key = pivot.Title
+		case SortByStatus:
+			key = string(pivot.Status)
+		case SortByCreatedAt:
+			key = pivot.CreatedAt.UnixNano()
+		case SortByUpdatedAt:
+			key = pivot.UpdatedAt.UnixNano()
+		case SortByPriority:
+			key = pivot.Priority.Rank()
+		case SortByDueAt:
+			key = dueKey(pivot)
+		}
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, op, column, op))
+		args = append(args, key, key, pivot.ID)
+	}
+
+	sqlQuery := "SELECT data FROM todos WHERE " + strings.Join(where, " AND ")
+	sqlQuery += fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	if query.Limit > 0 {
+		// Fetch one extra row to learn whether another page follows
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	todos, err := s.queryTodos(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TodoPage{Todos: todos}
+	if query.Limit > 0 && len(todos) > query.Limit {
+		page.Todos = todos[:query.Limit]
+		page.NextCursor = query.cursorFor(page.Todos[query.Limit-1])
+	}
+	return page, nil
+}
+
+// ListTrash retrieves the todos in the trash
+func (s *SQLiteStorage) ListTrash(ctx context.Context) ([]*models.Todo, error) {
+	return s.queryTodos(ctx, "SELECT data FROM todos WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id")
+}
+
+// Restore moves a todo out of the trash
+func (s *SQLiteStorage) Restore(ctx context.Context, id int) (*models.Todo, error) {
+	return s.replaceTodo(ctx, id, true, models.EventRestored, func(todo *models.Todo) (*models.Todo, error) {
+		return restoredCopy(todo, time.Now()), nil
+	})
+}
+
+// Purge permanently deletes a todo and its subtasks from the trash
+func (s *SQLiteStorage) Purge(ctx context.Context, id int) error {
+	n, err := s.purgeWhere(ctx, `id IN (WITH RECURSIVE family(id) AS (
+		SELECT id FROM todos WHERE id = ? AND deleted_at IS NOT NULL
+		UNION SELECT todos.id FROM todos JOIN family ON todos.parent_id = family.id WHERE todos.deleted_at IS NOT NULL
+	) SELECT id FROM family)`, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff, or
+// the whole trash if the cutoff is zero
+func (s *SQLiteStorage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if before.IsZero() {
+		return s.purgeWhere(ctx, "1")
+	}
+	return s.purgeWhere(ctx, "deleted_at < ?", before.UnixNano())
+}
+
+// purgeWhere permanently deletes the trashed todos matching condition,
+// removes them from the blockers of other todos and records each change in
+// the history
+func (s *SQLiteStorage) purgeWhere(ctx context.Context, condition string, args ...any) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT data FROM todos WHERE deleted_at IS NOT NULL AND "+condition, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query trash: %w", err)
+	}
+	var purged []*models.Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purged = append(purged, todo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query trash: %w", err)
+	}
+
+	actor := ActorFromContext(ctx)
+	ids := make([]int, 0, len(purged))
+	for _, todo := range purged {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM todos WHERE id = ?", todo.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete todo: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM todo_activity WHERE todo_id = ?", todo.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete activity: %w", err)
+		}
+		if err := insertEvent(ctx, tx, newTodoEvent(models.EventPurged, actor, todo, nil)); err != nil {
+			return 0, err
+		}
+		ids = append(ids, todo.ID)
+	}
+
+	blocked, err := loadSQLiteTodos(ctx, tx, `SELECT data FROM todos WHERE EXISTS (
+		SELECT 1 FROM json_each(todos.data, '$.blocked_by') WHERE value IN (SELECT value FROM json_each(?))
+	)`, sqliteIDs(ids))
+	if err != nil {
+		return 0, err
+	}
+	for _, change := range unblockedTodos(blocked, purged, time.Now()) {
+		if err := writeTodo(ctx, tx, change.after); err != nil {
+			return 0, err
+		}
+		if err := insertEvent(ctx, tx, newTodoEvent(models.EventUpdated, actor, change.before, change.after)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(purged), nil
+}
+
+// History retrieves the changes made to a todo, oldest first
+func (s *SQLiteStorage) History(ctx context.Context, id int) ([]*models.TodoEvent, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT data FROM todo_events WHERE todo_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.TodoEvent
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to query history: %w", err)
+		}
+		var event models.TodoEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse history event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+
+	if len(events) == 0 {
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM todos WHERE id = ?)", id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to read todo: %w", err)
+		}
+		if !exists {
+			return nil, ErrTodoNotFound
+		}
+		return make([]*models.TodoEvent, 0), nil
+	}
+
+	return currentHistory(events), nil
+}
+
+// placeholders returns n comma-separated SQL parameter placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// sqliteHasTag is the SQL condition for a todos row carrying the tag bound
+// to its placeholder
+const sqliteHasTag = "EXISTS (SELECT 1 FROM json_each(todos.data, '$.tags') WHERE value = ?)"
+
+// Tags lists the tags of the todos outside the trash with their counts
+func (s *SQLiteStorage) Tags(ctx context.Context) ([]models.TagCount, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT tag.value, COUNT(*) FROM todos, json_each(todos.data, '$.tags') AS tag WHERE todos.deleted_at IS NULL GROUP BY tag.value ORDER BY tag.value")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.TagCount, 0)
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Tag, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to read tag: %w", err)
+		}
+		result = append(result, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+
+	return result, nil
+}
+
+// RenameTag renames a tag on every todo carrying it
+func (s *SQLiteStorage) RenameTag(ctx context.Context, from, to string) (int, error) {
+	return s.retag(ctx, from, to, false)
+}
+
+// MergeTags folds the tag from into an existing tag
+func (s *SQLiteStorage) MergeTags(ctx context.Context, from, into string) (int, error) {
+	return s.retag(ctx, from, into, true)
+}
+
+// retag replaces the tag from with to in one transaction, recording an
+// update in the history of every todo it changes
+func (s *SQLiteStorage) retag(ctx context.Context, from, to string, merge bool) (int, error) {
+	from, to, err := normalizeRetag(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !merge {
+		var exists int
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM todos WHERE "+sqliteHasTag+" LIMIT 1", to).Scan(&exists)
+		if err == nil {
+			return 0, fmt.Errorf("%w: %q", ErrTagExists, to)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("failed to query tags: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT data FROM todos WHERE "+sqliteHasTag+" ORDER BY id", from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query todos: %w", err)
+	}
+	var targets []*models.Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		targets = append(targets, todo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query todos: %w", err)
+	}
+	if len(targets) == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrTagNotFound, from)
+	}
+
+	now := time.Now()
+	actor := ActorFromContext(ctx)
+	for _, todo := range targets {
+		retagged := retaggedCopy(todo, from, to, now)
+		if err := writeTodo(ctx, tx, retagged); err != nil {
+			return 0, err
+		}
+		if err := insertEvent(ctx, tx, newTodoEvent(models.EventUpdated, actor, todo, retagged)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(targets), nil
+}
+
+// Subtasks retrieves the subtasks of a todo at any depth
+func (s *SQLiteStorage) Subtasks(ctx context.Context, id int) ([]*models.Todo, error) {
+	subtree, err := s.queryTodos(ctx, sqliteSubtree, id)
+	if err != nil {
+		return nil, err
+	}
+
+	family := make(map[int]*models.Todo, len(subtree))
+	for _, todo := range subtree {
+		family[todo.ID] = todo
+	}
+	if todo, exists := family[id]; !exists || todo.DeletedAt != nil {
+		return nil, ErrTodoNotFound
+	}
+
+	return append(make([]*models.Todo, 0), liveSubtasks(family, id)...), nil
+}
+
+// scanProject decodes the data column of a projects row
+func scanProject(row interface{ Scan(...any) error }) (*models.Project, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		return nil, err
+	}
+
+	var project models.Project
+	if err := json.Unmarshal([]byte(data), &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project: %w", err)
+	}
+	return &project, nil
+}
+
+// checkSQLiteProjectName fails with ErrProjectExists if a project other
+// than the one with the given ID already has the name
+func checkSQLiteProjectName(ctx context.Context, tx *sql.Tx, id int, name string) error {
+	var existing string
+	err := tx.QueryRowContext(ctx, "SELECT json_extract(data, '$.name') FROM projects WHERE name_key = ? AND id != ?", projectNameKey(name), id).Scan(&existing)
+	if err == nil {
+		return fmt.Errorf("%w: %q", ErrProjectExists, existing)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read project: %w", err)
+	}
+	return nil
+}
+
+// CreateProject creates a new project
+func (s *SQLiteStorage) CreateProject(ctx context.Context, project *models.Project) error {
+	if err := normalizeProject(project); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkSQLiteProjectName(ctx, tx, 0, project.Name); err != nil {
+		return err
+	}
+
+	var nextID int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) + 1 FROM projects").Scan(&nextID); err != nil {
+		return fmt.Errorf("failed to allocate ID: %w", err)
+	}
+
+	now := time.Now()
+	stored := *project
+	stored.ID = nextID
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO projects (id, name_key, archived, data) VALUES (?, ?, ?, ?)",
+		stored.ID, projectNameKey(stored.Name), stored.Archived, string(data),
+	); err != nil {
+		return fmt.Errorf("failed to insert project: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	*project = stored
+	return nil
+}
+
+// GetProject retrieves a project by its ID
+func (s *SQLiteStorage) GetProject(ctx context.Context, id int) (*models.Project, error) {
+	project, err := scanProject(s.db.QueryRowContext(ctx, "SELECT data FROM projects WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+	return project, nil
+}
+
+// ListProjects retrieves the projects ordered by ID
+func (s *SQLiteStorage) ListProjects(ctx context.Context, includeArchived bool) ([]*models.Project, error) {
+	query := "SELECT data FROM projects WHERE NOT archived ORDER BY id"
+	if includeArchived {
+		query = "SELECT data FROM projects ORDER BY id"
+	}
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Project, 0)
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateProject updates an existing project
+func (s *SQLiteStorage) UpdateProject(ctx context.Context, id int, updatedProject *models.Project) error {
+	if err := normalizeProject(updatedProject); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := scanProject(tx.QueryRowContext(ctx, "SELECT data FROM projects WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read project: %w", err)
+	}
+	if err := checkSQLiteProjectName(ctx, tx, id, updatedProject.Name); err != nil {
+		return err
+	}
+	if err := prepareProjectUpdate(existing, updatedProject); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(updatedProject)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE projects SET name_key = ?, archived = ?, data = ? WHERE id = ?",
+		projectNameKey(updatedProject.Name), updatedProject.Archived, string(data), id,
+	); err != nil {
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteProject permanently deletes a project no todo belongs to
+func (s *SQLiteStorage) DeleteProject(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists, inUse bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM projects WHERE id = ?)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to read project: %w", err)
+	}
+	if !exists {
+		return ErrProjectNotFound
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM todos WHERE project_id = ?)", id).Scan(&inUse); err != nil {
+		return fmt.Errorf("failed to query todos: %w", err)
+	}
+	if inUse {
+		return ErrProjectNotEmpty
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM projects WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// readSQLiteActivity reads the activity of a todo outside the trash
+func readSQLiteActivity(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, id int) (*models.Activity, error) {
+	var live bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM todos WHERE id = ? AND deleted_at IS NULL)", id).Scan(&live); err != nil {
+		return nil, fmt.Errorf("failed to read todo: %w", err)
+	}
+	if !live {
+		return nil, ErrTodoNotFound
+	}
+
+	var data string
+	err := q.QueryRowContext(ctx, "SELECT data FROM todo_activity WHERE todo_id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.Activity{TodoID: id}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+
+	var activity models.Activity
+	if err := json.Unmarshal([]byte(data), &activity); err != nil {
+		return nil, fmt.Errorf("failed to parse activity: %w", err)
+	}
+	return &activity, nil
+}
+
+// Activity retrieves the activity of a todo outside the trash
+func (s *SQLiteStorage) Activity(ctx context.Context, id int) (*models.Activity, error) {
+	return readSQLiteActivity(ctx, s.db, id)
+}
+
+// ChangeActivity applies change to the activity of a todo outside the
+// trash. It is stored in its own table, leaving the todos row alone.
+func (s *SQLiteStorage) ChangeActivity(ctx context.Context, id int, change func(*models.Activity) error) (*models.Activity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	activity, err := readSQLiteActivity(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(activity); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO todo_activity (todo_id, data) VALUES (?, ?) ON CONFLICT (todo_id) DO UPDATE SET data = excluded.data",
+		id, string(data),
+	); err != nil {
+		return nil, fmt.Errorf("failed to write activity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return activity, nil
+}
+
+// Activities retrieves the activity stored for the todos outside the trash
+func (s *SQLiteStorage) Activities(ctx context.Context) ([]*models.Activity, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT todo_activity.data FROM todo_activity
+		JOIN todos ON todos.id = todo_activity.todo_id
+		WHERE todos.deleted_at IS NULL ORDER BY todo_activity.todo_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Activity, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read activity: %w", err)
+		}
+		var activity models.Activity
+		if err := json.Unmarshal([]byte(data), &activity); err != nil {
+			return nil, fmt.Errorf("failed to parse activity: %w", err)
+		}
+		result = append(result, &activity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query activity: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+	"github.com/shghadge/todo_mcp/internal/storage/storagetest"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, workflow *models.Workflow) storage.TodoStorage {
+		s, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "todos.db"), workflow)
+		if err != nil {
+			t.Fatalf("NewSQLiteStorage failed: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func TestSQLiteStorageEscapesPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "odd?name#dir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	path := filepath.Join(dir, "todos 100%.db")
+
+	s, err := storage.NewSQLiteStorage(path, nil)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Create(context.Background(), &models.Todo{Title: "escaped"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("database is not at %q: %v", path, err)
+	}
+}
@@ -0,0 +1,1887 @@
+// Package storagetest provides a conformance suite that every
+// storage.TodoStorage implementation is expected to pass.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+)
+
+// Factory returns a new, empty storage for a single test whose todos
+// follow workflow. Any cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T, workflow *models.Workflow) storage.TodoStorage
+
+// Run runs the conformance suite against storages built by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.TodoStorage)
+	}{
+		{"IDAssignment", testIDAssignment},
+		{"Timestamps", testTimestamps},
+		{"CopySemantics", testCopySemantics},
+		{"NotFound", testNotFound},
+		{"Update", testUpdate},
+		{"Versions", testVersions},
+		{"Delete", testDelete},
+		{"Trash", testTrash},
+		{"PurgedIDsNotReissued", testPurgedIDsNotReissued},
+		{"History", testHistory},
+		{"StatusFilter", testStatusFilter},
+		{"QueryFilters", testQueryFilters},
+		{"QueryFoldsText", testQueryFoldsText},
+		{"QuerySortAndPaging", testQuerySortAndPaging},
+		{"QueryInvalid", testQueryInvalid},
+		{"DueDates", testDueDates},
+		{"Tags", testTags},
+		{"Projects", testProjects},
+		{"Subtasks", testSubtasks},
+		{"Dependencies", testDependencies},
+		{"Recurrence", testRecurrence},
+		{"TimeTracking", testTimeTracking},
+		{"Comments", testComments},
+		{"Attachments", testAttachments},
+		{"Checklist", testChecklist},
+		{"CancelledContext", testCancelledContext},
+		{"Concurrency", testConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t, models.DefaultWorkflow))
+		})
+	}
+
+	// The workflow test runs on a storage following a workflow of its own
+	t.Run("Workflow", func(t *testing.T) {
+		testWorkflow(t, newStorage(t, reviewWorkflow))
+	})
+}
+
+// newTodo returns an unsaved pending todo with the given title
+func newTodo(title string) *models.Todo {
+	return &models.Todo{
+		Title:       title,
+		Description: "description of " + title,
+		Status:      models.StatusPending,
+	}
+}
+
+// mustCreate creates a todo and fails the test on error
+func mustCreate(t *testing.T, s storage.TodoStorage, todo *models.Todo) *models.Todo {
+	t.Helper()
+	if err := s.Create(context.Background(), todo); err != nil {
+		t.Fatalf("Create(%q) failed: %v", todo.Title, err)
+	}
+	return todo
+}
+
+// mustGet retrieves a todo and fails the test on error
+func mustGet(t *testing.T, s storage.TodoStorage, id int) *models.Todo {
+	t.Helper()
+	todo, err := s.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%d) failed: %v", id, err)
+	}
+	return todo
+}
+
+func testIDAssignment(t *testing.T, s storage.TodoStorage) {
+	seen := make(map[int]bool)
+	for i := 0; i < 5; i++ {
+		todo := newTodo(fmt.Sprintf("todo %d", i))
+		todo.ID = 1000 + i // callers can't choose the ID
+		mustCreate(t, s, todo)
+
+		if todo.ID <= 0 {
+			t.Fatalf("Create assigned non-positive ID %d", todo.ID)
+		}
+		if todo.ID >= 1000 {
+			t.Fatalf("Create kept caller-supplied ID %d", todo.ID)
+		}
+		if seen[todo.ID] {
+			t.Fatalf("Create assigned duplicate ID %d", todo.ID)
+		}
+		seen[todo.ID] = true
+
+		if got := mustGet(t, s, todo.ID); got.Title != todo.Title {
+			t.Fatalf("GetByID(%d).Title = %q, want %q", todo.ID, got.Title, todo.Title)
+		}
+	}
+}
+
+func testTimestamps(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	before := time.Now()
+
+	todo := newTodo("timestamps")
+	todo.CreatedAt = before.Add(-24 * time.Hour)
+	mustCreate(t, s, todo)
+
+	if todo.CreatedAt.Before(before) {
+		t.Fatalf("Create kept caller-supplied CreatedAt %v", todo.CreatedAt)
+	}
+	if !todo.UpdatedAt.Equal(todo.CreatedAt) {
+		t.Fatalf("new todo UpdatedAt = %v, want CreatedAt %v", todo.UpdatedAt, todo.CreatedAt)
+	}
+
+	stored := mustGet(t, s, todo.ID)
+	if !stored.CreatedAt.Equal(todo.CreatedAt) {
+		t.Fatalf("stored CreatedAt = %v, want %v", stored.CreatedAt, todo.CreatedAt)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	update := *stored
+	update.Title = "timestamps updated"
+	update.CreatedAt = time.Time{}
+	if err := s.Update(ctx, todo.ID, &update); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	updated := mustGet(t, s, todo.ID)
+	if !updated.CreatedAt.Equal(todo.CreatedAt) {
+		t.Fatalf("Update changed CreatedAt from %v to %v", todo.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(stored.UpdatedAt) {
+		t.Fatalf("Update didn't advance UpdatedAt: %v, was %v", updated.UpdatedAt, stored.UpdatedAt)
+	}
+}
+
+func testCopySemantics(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	todo := mustCreate(t, s, newTodo("original"))
+	id := todo.ID
+
+	// Changing the todo passed to Create must not reach the stored one
+	todo.Title = "changed after create"
+	if got := mustGet(t, s, id); got.Title != "original" {
+		t.Fatalf("stored title = %q after changing the created todo", got.Title)
+	}
+
+	// Nor may changing what GetByID returns
+	got := mustGet(t, s, id)
+	got.Title = "changed after get"
+	if again := mustGet(t, s, id); again.Title != "original" {
+		t.Fatalf("stored title = %q after changing a GetByID result", again.Title)
+	}
+
+	// Nor what GetAll returns
+	all, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	for _, todo := range all {
+		todo.Title = "changed after get all"
+	}
+	if again := mustGet(t, s, id); again.Title != "original" {
+		t.Fatalf("stored title = %q after changing a GetAll result", again.Title)
+	}
+
+	// Nor what GetByStatus returns
+	pending, err := s.GetByStatus(ctx, models.StatusPending)
+	if err != nil {
+		t.Fatalf("GetByStatus failed: %v", err)
+	}
+	for _, todo := range pending {
+		todo.Title = "changed after get by status"
+	}
+	if again := mustGet(t, s, id); again.Title != "original" {
+		t.Fatalf("stored title = %q after changing a GetByStatus result", again.Title)
+	}
+
+	// Nor the todo passed to Update, once Update has returned
+	update := mustGet(t, s, id)
+	update.Title = "updated"
+	if err := s.Update(ctx, id, update); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	update.Title = "changed after update"
+	if again := mustGet(t, s, id); again.Title != "updated" {
+		t.Fatalf("stored title = %q after changing the updated todo", again.Title)
+	}
+
+	// Slices are copied too
+	tagged := newTodo("tagged")
+	tagged.Tags = []string{"home"}
+	mustCreate(t, s, tagged)
+	tagged.Tags[0] = "changed after create"
+	got = mustGet(t, s, tagged.ID)
+	got.Tags[0] = "changed after get"
+	if again := mustGet(t, s, tagged.ID); len(again.Tags) != 1 || again.Tags[0] != "home" {
+		t.Fatalf("stored tags = %v after changing the tags of copies", again.Tags)
+	}
+}
+
+func testNotFound(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	const missing = 424242
+
+	if _, err := s.GetByID(ctx, missing); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("GetByID(missing) error = %v, want ErrTodoNotFound", err)
+	}
+	if err := s.Update(ctx, missing, newTodo("missing")); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Update(missing) error = %v, want ErrTodoNotFound", err)
+	}
+	if err := s.Delete(ctx, missing); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := s.GetByID(ctx, missing); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Update(missing) created the todo: GetByID error = %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	todo := mustCreate(t, s, newTodo("before"))
+
+	update := &models.Todo{
+		ID:          todo.ID + 100, // ignored in favour of the id argument
+		Title:       "after",
+		Description: "new description",
+		Status:      models.StatusCompleted,
+	}
+	if err := s.Update(ctx, todo.ID, update); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if update.ID != todo.ID {
+		t.Fatalf("Update left ID %d on the todo, want %d", update.ID, todo.ID)
+	}
+
+	got := mustGet(t, s, todo.ID)
+	if got.Title != "after" || got.Description != "new description" || got.Status != models.StatusCompleted {
+		t.Fatalf("GetByID after Update = %+v", got)
+	}
+	if _, err := s.GetByID(ctx, todo.ID+100); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Fatalf("Update stored the todo under its ID field: GetByID error = %v", err)
+	}
+}
+
+func testVersions(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	todo := newTodo("versioned")
+	todo.Version = 42 // callers can't choose the version
+	mustCreate(t, s, todo)
+	if todo.Version != 1 {
+		t.Fatalf("new todo Version = %d, want 1", todo.Version)
+	}
+
+	// An update carrying the current version succeeds and bumps it
+	first := mustGet(t, s, todo.ID)
+	first.Title = "first edit"
+	if err := s.Update(ctx, todo.ID, first); err != nil {
+		t.Fatalf("Update with current version failed: %v", err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("Version after Update = %d, want 2", first.Version)
+	}
+	if got := mustGet(t, s, todo.ID); got.Version != 2 {
+		t.Fatalf("stored Version after Update = %d, want 2", got.Version)
+	}
+
+	// An update based on the old version is rejected and changes nothing
+	stale := *todo
+	stale.Title = "stale edit"
+	if err := s.Update(ctx, todo.ID, &stale); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("Update with stale version error = %v, want ErrVersionConflict", err)
+	}
+	if got := mustGet(t, s, todo.ID); got.Title != "first edit" || got.Version != 2 {
+		t.Fatalf("stale Update changed the todo: %+v", got)
+	}
+
+	// A zero version overwrites unconditionally
+	blind := newTodo("blind edit")
+	if err := s.Update(ctx, todo.ID, blind); err != nil {
+		t.Fatalf("Update without version failed: %v", err)
+	}
+	if got := mustGet(t, s, todo.ID); got.Title != "blind edit" || got.Version != 3 {
+		t.Fatalf("after blind Update got %+v, want title %q version 3", got, "blind edit")
+	}
+
+	// A rejected update leaves the caller's todo as it was, so that it can
+	// be fixed and sent again
+	failed := mustGet(t, s, todo.ID)
+	failed.ProjectID = 999
+	if err := s.Update(ctx, todo.ID, failed); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Fatalf("Update with unknown project error = %v, want ErrProjectNotFound", err)
+	}
+	if failed.Version != 3 {
+		t.Fatalf("Version after failed Update = %d, want it left at 3", failed.Version)
+	}
+	failed.ProjectID = 0
+	if err := s.Update(ctx, todo.ID, failed); err != nil {
+		t.Fatalf("retried Update failed: %v", err)
+	}
+}
+
+func testDelete(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	keep := mustCreate(t, s, newTodo("keep"))
+	remove := mustCreate(t, s, newTodo("remove"))
+
+	if err := s.Delete(ctx, remove.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.GetByID(ctx, remove.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Fatalf("GetByID after Delete error = %v, want ErrTodoNotFound", err)
+	}
+	if err := s.Delete(ctx, remove.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrTodoNotFound", err)
+	}
+
+	all, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != keep.ID {
+		t.Fatalf("GetAll after Delete = %v, want only todo %d", ids(all), keep.ID)
+	}
+
+	// A new todo never takes over the ID of a live one
+	next := mustCreate(t, s, newTodo("next"))
+	if next.ID == keep.ID {
+		t.Fatalf("Create reused live ID %d", next.ID)
+	}
+}
+
+func testTrash(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	keep := mustCreate(t, s, newTodo("keep"))
+	trashed := mustCreate(t, s, newTodo("trashed"))
+
+	if err := s.Delete(ctx, trashed.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Trashed todos are hidden from every read but ListTrash
+	if byStatus, err := s.GetByStatus(ctx, models.StatusPending); err != nil || !equalInts(ids(byStatus), []int{keep.ID}) {
+		t.Errorf("GetByStatus = %v, %v, want only todo %d", ids(byStatus), err, keep.ID)
+	}
+	if page := query(t, s, storage.TodoQuery{IDs: []int{trashed.ID}}); len(page.Todos) != 0 {
+		t.Errorf("Query returned trashed todo: %v", ids(page.Todos))
+	}
+	if err := s.Update(ctx, trashed.ID, newTodo("changed")); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Update of trashed todo error = %v, want ErrTodoNotFound", err)
+	}
+	if err := s.Purge(ctx, keep.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Purge of live todo error = %v, want ErrTodoNotFound", err)
+	}
+
+	trash, err := s.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != trashed.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("ListTrash = %v, want todo %d with DeletedAt set", ids(trash), trashed.ID)
+	}
+
+	restored, err := s.Restore(ctx, trashed.ID)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Title != "trashed" {
+		t.Errorf("Restore = %+v, want the original todo out of the trash", restored)
+	}
+	if restored.Version <= trashed.Version {
+		t.Errorf("Restore version = %d, want more than %d", restored.Version, trashed.Version)
+	}
+	if got := mustGet(t, s, trashed.ID); got.Version != restored.Version {
+		t.Errorf("GetByID after Restore version = %d, want %d", got.Version, restored.Version)
+	}
+	if _, err := s.Restore(ctx, trashed.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("second Restore error = %v, want ErrTodoNotFound", err)
+	}
+
+	// Purge removes a single todo for good
+	if err := s.Delete(ctx, trashed.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Purge(ctx, trashed.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if _, err := s.Restore(ctx, trashed.ID); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Restore after Purge error = %v, want ErrTodoNotFound", err)
+	}
+
+	// PurgeTrash only takes todos trashed before the cutoff
+	old := mustCreate(t, s, newTodo("old"))
+	if err := s.Delete(ctx, old.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cutoff := time.Now()
+	recent := mustCreate(t, s, newTodo("recent"))
+	if err := s.Delete(ctx, recent.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if n, err := s.PurgeTrash(ctx, cutoff); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash(cutoff) = %d, %v, want 1", n, err)
+	}
+	if trash, err := s.ListTrash(ctx); err != nil || !equalInts(ids(trash), []int{recent.ID}) {
+		t.Fatalf("ListTrash after PurgeTrash = %v, %v, want only todo %d", ids(trash), err, recent.ID)
+	}
+	if n, err := s.PurgeTrash(ctx, time.Time{}); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash(zero) = %d, %v, want 1", n, err)
+	}
+
+	all, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != keep.ID {
+		t.Fatalf("GetAll after purging = %v, want only todo %d", ids(all), keep.ID)
+	}
+}
+
+func testPurgedIDsNotReissued(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	// Purging the todos with the highest IDs, one by one or by emptying the
+	// trash, must not let a new todo take one of their IDs over
+	mustCreate(t, s, newTodo("kept"))
+	highest := 0
+	for _, purge := range []func(id int) error{
+		func(id int) error { return s.Purge(ctx, id) },
+		func(int) error { _, err := s.PurgeTrash(ctx, time.Time{}); return err },
+	} {
+		todo := mustCreate(t, s, newTodo("purged"))
+		if todo.ID <= highest {
+			t.Fatalf("Create reissued ID %d, the highest so far is %d", todo.ID, highest)
+		}
+		highest = todo.ID
+
+		if err := s.Delete(ctx, todo.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := purge(todo.ID); err != nil {
+			t.Fatalf("purge failed: %v", err)
+		}
+	}
+
+	if todo := mustCreate(t, s, newTodo("after the purges")); todo.ID <= highest {
+		t.Errorf("Create after the purges assigned ID %d, want one above %d", todo.ID, highest)
+	}
+}
+
+func testHistory(t *testing.T, s storage.TodoStorage) {
+	alice := storage.WithActor(context.Background(), "alice")
+	bob := storage.WithActor(context.Background(), "bob")
+
+	todo := newTodo("draft")
+	if err := s.Create(alice, todo); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	updated := mustGet(t, s, todo.ID)
+	updated.Title = "final"
+	if err := s.Update(bob, todo.ID, updated); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := s.Delete(alice, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.Restore(bob, todo.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if err := s.Delete(alice, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Purge(bob, todo.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	// The history outlives the todo
+	events, err := s.History(context.Background(), todo.ID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+
+	want := []struct {
+		action  models.TodoEventAction
+		actor   string
+		version int
+	}{
+		{models.EventCreated, "alice", 1},
+		{models.EventUpdated, "bob", 2},
+		{models.EventDeleted, "alice", 3},
+		{models.EventRestored, "bob", 4},
+		{models.EventDeleted, "alice", 5},
+		{models.EventPurged, "bob", 5},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("History returned %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.TodoID != todo.ID || e.Action != w.action || e.Actor != w.actor || e.Version != w.version {
+			t.Errorf("event %d = {%d %s %q v%d}, want {%d %s %q v%d}",
+				i, e.TodoID, e.Action, e.Actor, e.Version, todo.ID, w.action, w.actor, w.version)
+		}
+		if i > 0 && e.At.Before(events[i-1].At) {
+			t.Errorf("event %d at %v is before the previous one", i, e.At)
+		}
+	}
+
+	// The update records just the field that changed
+	changes := events[1].Changes
+	if len(changes) != 1 || changes[0].Field != "title" || string(changes[0].Old) != `"draft"` || string(changes[0].New) != `"final"` {
+		t.Errorf("update changes = %+v, want title from \"draft\" to \"final\"", changes)
+	}
+	if changes := events[2].Changes; len(changes) != 1 || changes[0].Field != "deleted_at" || changes[0].Old != nil {
+		t.Errorf("delete changes = %+v, want deleted_at being set", changes)
+	}
+
+	if _, err := s.History(context.Background(), todo.ID+1000); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("History(missing) error = %v, want ErrTodoNotFound", err)
+	}
+}
+
+func testStatusFilter(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	want := map[models.TodoStatus]map[int]bool{
+		models.StatusPending:   {},
+		models.StatusCompleted: {},
+	}
+	for i := 0; i < 6; i++ {
+		todo := newTodo(fmt.Sprintf("todo %d", i))
+		if i%3 == 0 {
+			todo.Status = models.StatusCompleted
+		}
+		mustCreate(t, s, todo)
+		want[todo.Status][todo.ID] = true
+	}
+
+	for status, wantIDs := range want {
+		todos, err := s.GetByStatus(ctx, status)
+		if err != nil {
+			t.Fatalf("GetByStatus(%s) failed: %v", status, err)
+		}
+		if len(todos) != len(wantIDs) {
+			t.Fatalf("GetByStatus(%s) = %v, want %d todos", status, ids(todos), len(wantIDs))
+		}
+		for _, todo := range todos {
+			if !wantIDs[todo.ID] || todo.Status != status {
+				t.Fatalf("GetByStatus(%s) returned todo %d with status %s", status, todo.ID, todo.Status)
+			}
+		}
+	}
+
+	all, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 6 {
+		t.Fatalf("GetAll returned %d todos, want 6", len(all))
+	}
+}
+
+// query runs a query and fails the test on error
+func query(t *testing.T, s storage.TodoStorage, q storage.TodoQuery) *storage.TodoPage {
+	t.Helper()
+	page, err := s.Query(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Query(%+v) failed: %v", q, err)
+	}
+	return page
+}
+
+func testQueryFilters(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	groceries := mustCreate(t, s, &models.Todo{Title: "Buy groceries", Description: "milk and bread", Status: models.StatusPending})
+	report := mustCreate(t, s, &models.Todo{Title: "Write report", Description: "quarterly numbers", Status: models.StatusCompleted, Priority: models.PriorityHigh})
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	bread := mustCreate(t, s, &models.Todo{Title: "Bake BREAD", Description: "sourdough", Status: models.StatusPending})
+
+	// Touch the first todo so its UpdatedAt moves past middle
+	update := mustGet(t, s, groceries.ID)
+	if err := s.Update(ctx, groceries.ID, update); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"all", storage.TodoQuery{}, []int{groceries.ID, report.ID, bread.ID}},
+		{"ids", storage.TodoQuery{IDs: []int{report.ID, bread.ID}}, []int{report.ID, bread.ID}},
+		{"status", storage.TodoQuery{Statuses: []models.TodoStatus{models.StatusPending}}, []int{groceries.ID, bread.ID}},
+		{"statuses", storage.TodoQuery{Statuses: []models.TodoStatus{models.StatusPending, models.StatusCompleted}}, []int{groceries.ID, report.ID, bread.ID}},
+		{"priority", storage.TodoQuery{Priorities: []models.Priority{models.PriorityHigh}}, []int{report.ID}},
+		{"unset priority is none", storage.TodoQuery{Priorities: []models.Priority{models.PriorityNone}}, []int{groceries.ID, bread.ID}},
+		{"title", storage.TodoQuery{Title: "write"}, []int{report.ID}},
+		{"description", storage.TodoQuery{Description: "BREAD"}, []int{groceries.ID}},
+		{"text", storage.TodoQuery{Text: "bread"}, []int{groceries.ID, bread.ID}},
+		{"created since", storage.TodoQuery{CreatedSince: middle}, []int{bread.ID}},
+		{"created before", storage.TodoQuery{CreatedBefore: middle}, []int{groceries.ID, report.ID}},
+		{"updated since", storage.TodoQuery{UpdatedSince: middle}, []int{groceries.ID, bread.ID}},
+		{"updated before", storage.TodoQuery{UpdatedBefore: middle}, []int{report.ID}},
+		{"combined", storage.TodoQuery{Text: "bread", CreatedBefore: middle}, []int{groceries.ID}},
+		{"no match", storage.TodoQuery{Title: "nothing like this"}, []int{}},
+	}
+
+	for _, tt := range tests {
+		page := query(t, s, tt.query)
+		if got := ids(page.Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+		if page.NextCursor != "" {
+			t.Errorf("%s: unlimited query returned a cursor", tt.name)
+		}
+	}
+
+	// Results are copies like every other read
+	page := query(t, s, storage.TodoQuery{IDs: []int{report.ID}})
+	page.Todos[0].Title = "changed after query"
+	if got := mustGet(t, s, report.ID); got.Title != "Write report" {
+		t.Fatalf("stored title = %q after changing a Query result", got.Title)
+	}
+}
+
+func testQueryFoldsText(t *testing.T, s storage.TodoStorage) {
+	transfer := mustCreate(t, s, &models.Todo{Title: "Überweisung prüfen", Description: "an die ÄRZTIN", Status: models.StatusPending})
+	cafe := mustCreate(t, s, &models.Todo{Title: "CAFÉ buchen", Status: models.StatusPending})
+
+	// Letters outside ASCII match regardless of case on every backend
+	tests := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"title", storage.TodoQuery{Title: "ÜBERWEISUNG"}, []int{transfer.ID}},
+		{"description", storage.TodoQuery{Description: "ärztin"}, []int{transfer.ID}},
+		{"text", storage.TodoQuery{Text: "café"}, []int{cafe.ID}},
+		{"no match", storage.TodoQuery{Text: "cafe"}, []int{}},
+	}
+
+	for _, tt := range tests {
+		if got := ids(query(t, s, tt.query).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testQuerySortAndPaging(t *testing.T, s storage.TodoStorage) {
+	titles := []string{"delta", "alpha", "charlie", "alpha", "echo", "bravo", "charlie"}
+	created := make([]*models.Todo, len(titles))
+	for i, title := range titles {
+		todo := newTodo(title)
+		if i%2 == 1 {
+			todo.Status = models.StatusCompleted
+		}
+		if i%3 != 0 {
+			todo.Priority = models.Priorities[i%len(models.Priorities)]
+		}
+		if i%2 == 0 {
+			due := time.Date(2030, 1, 1+len(titles)-i%4, 0, 0, 0, 0, time.UTC)
+			todo.DueAt = &due
+		}
+		created[i] = mustCreate(t, s, todo)
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, field := range storage.SortFields {
+		for _, descending := range []bool{false, true} {
+			q := storage.TodoQuery{SortBy: field, Descending: descending}
+			want := ids(query(t, s, q).Todos)
+			if len(want) != len(titles) {
+				t.Fatalf("sort %s desc=%v: got %d todos, want %d", field, descending, len(want), len(titles))
+			}
+
+			for i := 1; i < len(want); i++ {
+				a, b := mustGet(t, s, want[i-1]), mustGet(t, s, want[i])
+				if c := compareField(a, b, field); (c > 0 && !descending) || (c < 0 && descending) || (c == 0 && (a.ID > b.ID) != descending) {
+					t.Fatalf("sort %s desc=%v: todo %d is out of order before %d", field, descending, a.ID, b.ID)
+				}
+			}
+
+			// Walking the pages must produce the same order
+			for _, limit := range []int{1, 2, 3} {
+				q.Limit = limit
+				q.Cursor = ""
+				var got []int
+				for pages := 0; ; pages++ {
+					if pages > len(titles) {
+						t.Fatalf("sort %s desc=%v limit %d: paging did not terminate", field, descending, limit)
+					}
+					page := query(t, s, q)
+					if len(page.Todos) > limit {
+						t.Fatalf("sort %s desc=%v limit %d: page has %d todos", field, descending, limit, len(page.Todos))
+					}
+					got = append(got, ids(page.Todos)...)
+					if page.NextCursor == "" {
+						break
+					}
+					q.Cursor = page.NextCursor
+				}
+				if !equalInts(got, want) {
+					t.Fatalf("sort %s desc=%v limit %d: paged order %v, want %v", field, descending, limit, got, want)
+				}
+			}
+		}
+	}
+
+	// Filters apply across pages
+	q := storage.TodoQuery{Title: "charlie", Limit: 1}
+	first := query(t, s, q)
+	q.Cursor = first.NextCursor
+	second := query(t, s, q)
+	if got := append(ids(first.Todos), ids(second.Todos)...); !equalInts(got, []int{created[2].ID, created[6].ID}) || second.NextCursor != "" {
+		t.Fatalf("paged filter returned %v (next cursor %q)", got, second.NextCursor)
+	}
+}
+
+func testQueryInvalid(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	mustCreate(t, s, newTodo("one"))
+	mustCreate(t, s, newTodo("two"))
+
+	if _, err := s.Query(ctx, storage.TodoQuery{SortBy: "colour"}); !errors.Is(err, storage.ErrInvalidQuery) {
+		t.Errorf("Query with unknown sort field error = %v, want ErrInvalidQuery", err)
+	}
+	if _, err := s.Query(ctx, storage.TodoQuery{Priorities: []models.Priority{"critical"}}); !errors.Is(err, storage.ErrInvalidQuery) {
+		t.Errorf("Query with unknown priority error = %v, want ErrInvalidQuery", err)
+	}
+	if _, err := s.Query(ctx, storage.TodoQuery{Limit: -1}); !errors.Is(err, storage.ErrInvalidQuery) {
+		t.Errorf("Query with negative limit error = %v, want ErrInvalidQuery", err)
+	}
+	if _, err := s.Query(ctx, storage.TodoQuery{Cursor: "not a cursor"}); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("Query with garbage cursor error = %v, want ErrInvalidCursor", err)
+	}
+
+	page := query(t, s, storage.TodoQuery{SortBy: storage.SortByTitle, Limit: 1})
+	if _, err := s.Query(ctx, storage.TodoQuery{SortBy: storage.SortByCreatedAt, Limit: 1, Cursor: page.NextCursor}); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("Query with cursor from another sort error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testDueDates(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	// Wednesday afternoon
+	now := time.Date(2030, 5, 15, 15, 0, 0, 0, time.UTC)
+	at := func(day, hour int) *time.Time {
+		d := time.Date(2030, 5, day, hour, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	lastWeek := mustCreate(t, s, &models.Todo{Title: "last week", Status: models.StatusPending, DueAt: at(8, 9)})
+	thisMorning := mustCreate(t, s, &models.Todo{Title: "this morning", Status: models.StatusPending, StartAt: at(14, 9), DueAt: at(15, 9)})
+	tonight := mustCreate(t, s, &models.Todo{Title: "tonight", Status: models.StatusPending, DueAt: at(15, 21)})
+	done := mustCreate(t, s, &models.Todo{Title: "done today", Status: models.StatusCompleted, DueAt: at(15, 10)})
+	sunday := mustCreate(t, s, &models.Todo{Title: "sunday", Status: models.StatusPending, DueAt: at(19, 23)})
+	monday := mustCreate(t, s, &models.Todo{Title: "next monday", Status: models.StatusPending, DueAt: at(20, 0)})
+	mustCreate(t, s, newTodo("no due date"))
+
+	got := mustGet(t, s, thisMorning.ID)
+	if got.StartAt == nil || !got.StartAt.Equal(*thisMorning.StartAt) || got.DueAt == nil || !got.DueAt.Equal(*thisMorning.DueAt) {
+		t.Fatalf("stored schedule = %v to %v, want %v to %v", got.StartAt, got.DueAt, thisMorning.StartAt, thisMorning.DueAt)
+	}
+
+	views := []struct {
+		view string
+		want []int
+	}{
+		{storage.DueOverdue, []int{lastWeek.ID, thisMorning.ID}},
+		{storage.DueToday, []int{thisMorning.ID, tonight.ID}},
+		{storage.DueThisWeek, []int{thisMorning.ID, tonight.ID, sunday.ID}},
+	}
+	for _, tt := range views {
+		var q storage.TodoQuery
+		if err := q.SetDueView(tt.view, now); err != nil {
+			t.Fatalf("SetDueView(%q) failed: %v", tt.view, err)
+		}
+		if got := ids(query(t, s, q).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.view, got, tt.want)
+		}
+	}
+
+	bounded := storage.TodoQuery{DueSince: *at(15, 0), DueBefore: *at(20, 0)}
+	if got := ids(query(t, s, bounded).Todos); !equalInts(got, []int{thisMorning.ID, tonight.ID, done.ID, sunday.ID}) {
+		t.Errorf("due between: Query = %v", got)
+	}
+
+	var q storage.TodoQuery
+	if err := q.SetDueView("someday", now); !errors.Is(err, storage.ErrInvalidQuery) {
+		t.Errorf("SetDueView with unknown view error = %v, want ErrInvalidQuery", err)
+	}
+
+	// Clearing the due date takes the todo out of the views
+	update := mustGet(t, s, monday.ID)
+	update.DueAt = nil
+	if err := s.Update(ctx, monday.ID, update); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := ids(query(t, s, storage.TodoQuery{DueSince: *at(20, 0)}).Todos); len(got) != 0 {
+		t.Fatalf("Query after clearing the due date = %v, want none", got)
+	}
+}
+
+func testTags(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	tagged := func(title string, tags ...string) *models.Todo {
+		todo := newTodo(title)
+		todo.Tags = tags
+		return mustCreate(t, s, todo)
+	}
+
+	report := tagged("report", "work", "urgent")
+	groceries := tagged("groceries", "home")
+	taxes := tagged("taxes", "home", "work")
+	untagged := tagged("untagged")
+	trashed := tagged("old", "work", "archive")
+	if err := s.Delete(ctx, trashed.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	filters := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"any", storage.TodoQuery{TagsAny: []string{"urgent", "home"}}, []int{report.ID, groceries.ID, taxes.ID}},
+		{"all", storage.TodoQuery{TagsAll: []string{"home", "work"}}, []int{taxes.ID}},
+		{"none", storage.TodoQuery{TagsNone: []string{"work"}}, []int{groceries.ID, untagged.ID}},
+		{"combined", storage.TodoQuery{TagsAny: []string{"work"}, TagsNone: []string{"home"}}, []int{report.ID}},
+	}
+	for _, tt := range filters {
+		if got := ids(query(t, s, tt.query).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := s.Query(ctx, storage.TodoQuery{TagsAll: []string{"Work"}}); !errors.Is(err, storage.ErrInvalidQuery) {
+		t.Errorf("Query with unnormalized tag error = %v, want ErrInvalidQuery", err)
+	}
+
+	// Trashed todos don't count
+	wantCounts := []models.TagCount{{Tag: "home", Count: 2}, {Tag: "urgent", Count: 1}, {Tag: "work", Count: 2}}
+	counts, err := s.Tags(ctx)
+	if err != nil {
+		t.Fatalf("Tags failed: %v", err)
+	}
+	if !slices.Equal(counts, wantCounts) {
+		t.Fatalf("Tags = %v, want %v", counts, wantCounts)
+	}
+
+	// Renaming reaches the trash too
+	if _, err := s.RenameTag(ctx, "work", "home"); !errors.Is(err, storage.ErrTagExists) {
+		t.Errorf("RenameTag onto an existing tag error = %v, want ErrTagExists", err)
+	}
+	if _, err := s.RenameTag(ctx, "missing", "other"); !errors.Is(err, storage.ErrTagNotFound) {
+		t.Errorf("RenameTag of a missing tag error = %v, want ErrTagNotFound", err)
+	}
+	n, err := s.RenameTag(ctx, " Work", "job")
+	if err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("RenameTag changed %d todos, want 3", n)
+	}
+	got := mustGet(t, s, taxes.ID)
+	if !slices.Equal(got.Tags, []string{"home", "job"}) || got.Version != taxes.Version+1 {
+		t.Errorf("renamed todo has tags %v at version %d", got.Tags, got.Version)
+	}
+	restored, err := s.Restore(ctx, trashed.ID)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !slices.Equal(restored.Tags, []string{"archive", "job"}) {
+		t.Errorf("renamed trashed todo has tags %v", restored.Tags)
+	}
+
+	events, err := s.History(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if last := events[len(events)-1]; last.Action != models.EventUpdated || len(last.Changes) != 1 || last.Changes[0].Field != "tags" {
+		t.Errorf("last event after rename = %+v, want an update of tags", last)
+	}
+
+	// Merging drops the duplicate
+	if n, err := s.MergeTags(ctx, "job", "home"); err != nil || n != 3 {
+		t.Fatalf("MergeTags = %d, %v, want 3 todos changed", n, err)
+	}
+	if got := mustGet(t, s, taxes.ID); !slices.Equal(got.Tags, []string{"home"}) {
+		t.Errorf("merged todo has tags %v, want [home]", got.Tags)
+	}
+	if _, err := s.MergeTags(ctx, "home", "home"); !errors.Is(err, storage.ErrTagExists) {
+		t.Errorf("MergeTags into itself error = %v, want ErrTagExists", err)
+	}
+}
+
+func testProjects(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	mustCreateProject := func(name string) *models.Project {
+		t.Helper()
+		project := &models.Project{Name: name}
+		if err := s.CreateProject(ctx, project); err != nil {
+			t.Fatalf("CreateProject(%q) failed: %v", name, err)
+		}
+		return project
+	}
+
+	work := mustCreateProject(" Work ")
+	home := mustCreateProject("home")
+	if work.ID == 0 || work.ID == home.ID || work.Name != "Work" || work.Version != 1 {
+		t.Fatalf("created project = %+v", work)
+	}
+	if err := s.CreateProject(ctx, &models.Project{Name: "WORK"}); !errors.Is(err, storage.ErrProjectExists) {
+		t.Errorf("CreateProject with a taken name error = %v, want ErrProjectExists", err)
+	}
+	if err := s.CreateProject(ctx, &models.Project{Name: "  "}); !errors.Is(err, storage.ErrInvalidProject) {
+		t.Errorf("CreateProject without a name error = %v, want ErrInvalidProject", err)
+	}
+	if _, err := s.GetProject(ctx, 999); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Errorf("GetProject(999) error = %v, want ErrProjectNotFound", err)
+	}
+
+	inProject := func(title string, projectID int) *models.Todo {
+		todo := newTodo(title)
+		todo.ProjectID = projectID
+		return mustCreate(t, s, todo)
+	}
+	report := inProject("report", work.ID)
+	review := inProject("review", work.ID)
+	dishes := inProject("dishes", home.ID)
+	loose := inProject("loose", 0)
+
+	missing := newTodo("missing project")
+	missing.ProjectID = 999
+	if err := s.Create(ctx, missing); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Errorf("Create in a missing project error = %v, want ErrProjectNotFound", err)
+	}
+	moved := mustGet(t, s, loose.ID)
+	moved.ProjectID = 999
+	if err := s.Update(ctx, loose.ID, moved); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Errorf("Update into a missing project error = %v, want ErrProjectNotFound", err)
+	}
+
+	filters := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"project", storage.TodoQuery{ProjectID: work.ID}, []int{report.ID, review.ID}},
+		{"no project", storage.TodoQuery{ProjectID: storage.NoProject}, []int{loose.ID}},
+		{"all", storage.TodoQuery{}, []int{report.ID, review.ID, dishes.ID, loose.ID}},
+	}
+	for _, tt := range filters {
+		if got := ids(query(t, s, tt.query).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Archiving hides the project and its todos unless asked for
+	archived := *work
+	archived.Archived = true
+	if err := s.UpdateProject(ctx, work.ID, &archived); err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if archived.Version != work.Version+1 {
+		t.Errorf("archived project version = %d, want %d", archived.Version, work.Version+1)
+	}
+	stale := *work
+	stale.Description = "stale"
+	if err := s.UpdateProject(ctx, work.ID, &stale); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("UpdateProject with a stale version error = %v, want ErrVersionConflict", err)
+	}
+	renamed := archived
+	renamed.Name = "Home"
+	if err := s.UpdateProject(ctx, work.ID, &renamed); !errors.Is(err, storage.ErrProjectExists) {
+		t.Errorf("UpdateProject to a taken name error = %v, want ErrProjectExists", err)
+	}
+
+	hidden := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"default", storage.TodoQuery{}, []int{dishes.ID, loose.ID}},
+		{"include archived", storage.TodoQuery{IncludeArchived: true}, []int{report.ID, review.ID, dishes.ID, loose.ID}},
+		{"archived project", storage.TodoQuery{ProjectID: work.ID}, []int{report.ID, review.ID}},
+	}
+	for _, tt := range hidden {
+		if got := ids(query(t, s, tt.query).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	listed, err := s.ListProjects(ctx, false)
+	if err != nil {
+		t.Fatalf("ListProjects failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != home.ID {
+		t.Errorf("ListProjects(false) = %+v, want only %q", listed, home.Name)
+	}
+	listed, err = s.ListProjects(ctx, true)
+	if err != nil {
+		t.Fatalf("ListProjects failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != work.ID || !listed[0].Archived {
+		t.Errorf("ListProjects(true) = %+v, want both projects", listed)
+	}
+
+	// A project can only be deleted once no todo, trashed or not, is in it
+	if err := s.DeleteProject(ctx, home.ID); !errors.Is(err, storage.ErrProjectNotEmpty) {
+		t.Errorf("DeleteProject of a project with todos error = %v, want ErrProjectNotEmpty", err)
+	}
+	if err := s.Delete(ctx, dishes.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.DeleteProject(ctx, home.ID); !errors.Is(err, storage.ErrProjectNotEmpty) {
+		t.Errorf("DeleteProject of a project with trashed todos error = %v, want ErrProjectNotEmpty", err)
+	}
+	if err := s.Purge(ctx, dishes.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if err := s.DeleteProject(ctx, home.ID); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if _, err := s.GetProject(ctx, home.ID); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Errorf("GetProject after delete error = %v, want ErrProjectNotFound", err)
+	}
+	if err := s.DeleteProject(ctx, home.ID); !errors.Is(err, storage.ErrProjectNotFound) {
+		t.Errorf("second DeleteProject error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func testSubtasks(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	subtask := func(title string, parentID int) *models.Todo {
+		t.Helper()
+		todo := newTodo(title)
+		todo.ParentID = parentID
+		return mustCreate(t, s, todo)
+	}
+	subtaskIDs := func(id int) []int {
+		t.Helper()
+		subtasks, err := s.Subtasks(ctx, id)
+		if err != nil {
+			t.Fatalf("Subtasks(%d) failed: %v", id, err)
+		}
+		return ids(subtasks)
+	}
+
+	// release > {docs > {changelog}, tests}; other stands alone
+	release := mustCreate(t, s, newTodo("release"))
+	docs := subtask("docs", release.ID)
+	tests := subtask("tests", release.ID)
+	changelog := subtask("changelog", docs.ID)
+	other := mustCreate(t, s, newTodo("other"))
+
+	if got, want := subtaskIDs(release.ID), []int{docs.ID, tests.ID, changelog.ID}; !equalInts(got, want) {
+		t.Errorf("Subtasks(release) = %v, want %v", got, want)
+	}
+	if got := subtaskIDs(other.ID); len(got) != 0 {
+		t.Errorf("Subtasks(other) = %v, want none", got)
+	}
+	if _, err := s.Subtasks(ctx, 999); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Subtasks(999) error = %v, want ErrTodoNotFound", err)
+	}
+	filters := []struct {
+		name  string
+		query storage.TodoQuery
+		want  []int
+	}{
+		{"parent", storage.TodoQuery{ParentID: release.ID}, []int{docs.ID, tests.ID}},
+		{"top level", storage.TodoQuery{ParentID: storage.NoParent}, []int{release.ID, other.ID}},
+	}
+	for _, tt := range filters {
+		if got := ids(query(t, s, tt.query).Todos); !equalInts(got, tt.want) {
+			t.Errorf("%s: Query = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	orphan := newTodo("orphan")
+	orphan.ParentID = 999
+	if err := s.Create(ctx, orphan); !errors.Is(err, storage.ErrParentNotFound) {
+		t.Errorf("Create below a missing parent error = %v, want ErrParentNotFound", err)
+	}
+	for _, parentID := range []int{release.ID, changelog.ID} {
+		cyclic := mustGet(t, s, release.ID)
+		cyclic.ParentID = parentID
+		if err := s.Update(ctx, release.ID, cyclic); !errors.Is(err, storage.ErrParentCycle) {
+			t.Errorf("Update of release below %d error = %v, want ErrParentCycle", parentID, err)
+		}
+	}
+	moved := mustGet(t, s, other.ID)
+	moved.ParentID = docs.ID
+	if err := s.Update(ctx, other.ID, moved); err != nil {
+		t.Fatalf("Update moving other below docs failed: %v", err)
+	}
+
+	// Completing docs completes its open subtasks, but not its parent
+	completed := mustGet(t, s, docs.ID)
+	completed.Status = models.StatusCompleted
+	if err := s.Update(ctx, docs.ID, completed); err != nil {
+		t.Fatalf("Update completing docs failed: %v", err)
+	}
+	for _, id := range []int{changelog.ID, other.ID} {
+		if todo := mustGet(t, s, id); todo.Status != models.StatusCompleted {
+			t.Errorf("subtask %d status after completing its parent = %q, want completed", id, todo.Status)
+		}
+	}
+	if todo := mustGet(t, s, release.ID); todo.Status != models.StatusPending {
+		t.Errorf("parent status after completing a subtask = %q, want pending", todo.Status)
+	}
+
+	// Deleting changelog alone, then release, leaves changelog in the trash
+	// when release is restored with the rest of its subtasks
+	if err := s.Delete(ctx, changelog.ID); err != nil {
+		t.Fatalf("Delete(changelog) failed: %v", err)
+	}
+	if err := s.Delete(ctx, release.ID); err != nil {
+		t.Fatalf("Delete(release) failed: %v", err)
+	}
+	for _, id := range []int{docs.ID, tests.ID, other.ID} {
+		if _, err := s.GetByID(ctx, id); !errors.Is(err, storage.ErrTodoNotFound) {
+			t.Errorf("GetByID(%d) after deleting its ancestor error = %v, want ErrTodoNotFound", id, err)
+		}
+	}
+	if _, err := s.Restore(ctx, docs.ID); !errors.Is(err, storage.ErrParentTrashed) {
+		t.Errorf("Restore below a trashed parent error = %v, want ErrParentTrashed", err)
+	}
+	if _, err := s.Restore(ctx, release.ID); err != nil {
+		t.Fatalf("Restore(release) failed: %v", err)
+	}
+	if got, want := subtaskIDs(release.ID), []int{docs.ID, tests.ID, other.ID}; !equalInts(got, want) {
+		t.Errorf("Subtasks(release) after restore = %v, want %v", got, want)
+	}
+
+	// Purging a todo purges its subtasks from the trash too
+	if err := s.Delete(ctx, docs.ID); err != nil {
+		t.Fatalf("Delete(docs) failed: %v", err)
+	}
+	if err := s.Purge(ctx, docs.ID); err != nil {
+		t.Fatalf("Purge(docs) failed: %v", err)
+	}
+	trash, err := s.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(trash) != 0 {
+		t.Errorf("trash after purging docs = %v, want empty", ids(trash))
+	}
+	if got, want := subtaskIDs(release.ID), []int{tests.ID}; !equalInts(got, want) {
+		t.Errorf("Subtasks(release) after purge = %v, want %v", got, want)
+	}
+}
+
+func testDependencies(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	blockedBy := func(todo *models.Todo, blockers ...int) error {
+		t.Helper()
+		updated := mustGet(t, s, todo.ID)
+		updated.BlockedBy = blockers
+		return s.Update(ctx, todo.ID, updated)
+	}
+	complete := func(ctx context.Context, todo *models.Todo) error {
+		t.Helper()
+		updated := mustGet(t, s, todo.ID)
+		updated.Status = models.StatusCompleted
+		return s.Update(ctx, todo.ID, updated)
+	}
+	ready := func() []int {
+		t.Helper()
+		return ids(query(t, s, storage.TodoQuery{Ready: true}).Todos)
+	}
+
+	// deploy is blocked by build, which is blocked by design
+	design := mustCreate(t, s, newTodo("design"))
+	build := newTodo("build")
+	build.BlockedBy = []int{design.ID}
+	build = mustCreate(t, s, build)
+	deploy := mustCreate(t, s, newTodo("deploy"))
+	if err := blockedBy(deploy, build.ID); err != nil {
+		t.Fatalf("Update blocking deploy by build failed: %v", err)
+	}
+	if got, want := ready(), []int{design.ID}; !equalInts(got, want) {
+		t.Errorf("ready todos = %v, want %v", got, want)
+	}
+
+	missing := newTodo("missing")
+	missing.BlockedBy = []int{999}
+	if err := s.Create(ctx, missing); !errors.Is(err, storage.ErrBlockerNotFound) {
+		t.Errorf("Create blocked by a missing todo error = %v, want ErrBlockerNotFound", err)
+	}
+	for _, blockerID := range []int{design.ID, deploy.ID} {
+		if err := blockedBy(design, blockerID); !errors.Is(err, storage.ErrDependencyCycle) {
+			t.Errorf("Update blocking design by %d error = %v, want ErrDependencyCycle", blockerID, err)
+		}
+	}
+
+	// build can't be completed before design unless forced
+	if err := complete(ctx, build); !errors.Is(err, storage.ErrBlocked) {
+		t.Errorf("completing a blocked todo error = %v, want ErrBlocked", err)
+	}
+	if err := complete(storage.WithForce(ctx), build); err != nil {
+		t.Errorf("forced completion of a blocked todo failed: %v", err)
+	}
+	if got, want := ready(), []int{design.ID, deploy.ID}; !equalInts(got, want) {
+		t.Errorf("ready todos after completing build = %v, want %v", got, want)
+	}
+
+	// Trashed blockers don't block, and purged ones are forgotten
+	if err := blockedBy(design, deploy.ID); !errors.Is(err, storage.ErrDependencyCycle) {
+		t.Errorf("Update closing a cycle through a completed todo error = %v, want ErrDependencyCycle", err)
+	}
+	if err := blockedBy(deploy, build.ID, design.ID); err != nil {
+		t.Fatalf("Update blocking deploy by design too failed: %v", err)
+	}
+	if err := s.Delete(ctx, design.ID); err != nil {
+		t.Fatalf("Delete(design) failed: %v", err)
+	}
+	if got, want := ready(), []int{deploy.ID}; !equalInts(got, want) {
+		t.Errorf("ready todos after trashing design = %v, want %v", got, want)
+	}
+	if err := s.Purge(ctx, design.ID); err != nil {
+		t.Fatalf("Purge(design) failed: %v", err)
+	}
+	for _, todo := range []*models.Todo{build, deploy} {
+		if got := mustGet(t, s, todo.ID); slices.Contains(got.BlockedBy, design.ID) {
+			t.Errorf("todo %d still blocked by purged design: %v", todo.ID, got.BlockedBy)
+		}
+	}
+	if got := mustGet(t, s, deploy.ID).BlockedBy; !equalInts(got, []int{build.ID}) {
+		t.Errorf("deploy blockers after purge = %v, want [%d]", got, build.ID)
+	}
+}
+
+func testRecurrence(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	recurring := func(title string, due time.Time, rule models.Recurrence) *models.Todo {
+		t.Helper()
+		todo := newTodo(title)
+		todo.DueAt = &due
+		todo.Recurrence = &rule
+		return mustCreate(t, s, todo)
+	}
+	setStatus := func(todo *models.Todo, status models.TodoStatus) *models.Todo {
+		t.Helper()
+		updated := mustGet(t, s, todo.ID)
+		updated.Status = status
+		if err := s.Update(ctx, todo.ID, updated); err != nil {
+			t.Fatalf("Update of todo %d to %s failed: %v", todo.ID, status, err)
+		}
+		return updated
+	}
+
+	// Monday 7 January 2030
+	monday := time.Date(2030, time.January, 7, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		due  time.Time
+		rule models.Recurrence
+		want time.Time
+	}{
+		{"daily", monday, models.Recurrence{Frequency: models.FrequencyDaily, Interval: 2}, monday.AddDate(0, 0, 2)},
+		{"weekly", monday, models.Recurrence{Frequency: models.FrequencyWeekly, Weekdays: []string{"monday", "thursday"}}, monday.AddDate(0, 0, 3)},
+		{"every other week", monday, models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 2, Weekdays: []string{"monday"}}, monday.AddDate(0, 0, 14)},
+		{"monthly", monday, models.Recurrence{Frequency: models.FrequencyMonthly, MonthDay: 31}, time.Date(2030, time.January, 31, 17, 0, 0, 0, time.UTC)},
+		{"short month", time.Date(2030, time.January, 31, 17, 0, 0, 0, time.UTC), models.Recurrence{Frequency: models.FrequencyMonthly, MonthDay: 31}, time.Date(2030, time.February, 28, 17, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		todo := recurring(tt.name, tt.due, tt.rule)
+		completed := setStatus(todo, models.StatusCompleted)
+		if completed.NextID == 0 {
+			t.Errorf("%s: completed todo has no NextID", tt.name)
+			continue
+		}
+		next := mustGet(t, s, completed.NextID)
+		if next.Status != models.StatusPending || next.Title != tt.name || next.Recurrence == nil {
+			t.Errorf("%s: next occurrence = %+v, want a pending copy with the rule", tt.name, next)
+		}
+		if next.DueAt == nil || !next.DueAt.Equal(tt.want) {
+			t.Errorf("%s: next occurrence due %v, want %v", tt.name, next.DueAt, tt.want)
+		}
+	}
+
+	// Occurrences missed while overdue are skipped, and completing again
+	// after reopening creates no second occurrence
+	overdue := recurring("overdue", time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC), models.Recurrence{Frequency: models.FrequencyDaily})
+	completed := setStatus(overdue, models.StatusCompleted)
+	if next := mustGet(t, s, completed.NextID); !next.DueAt.After(completed.UpdatedAt) {
+		t.Errorf("next occurrence of an overdue todo due %v, want after its completion at %v", next.DueAt, completed.UpdatedAt)
+	}
+	before := len(query(t, s, storage.TodoQuery{}).Todos)
+	setStatus(overdue, models.StatusPending)
+	if again := setStatus(overdue, models.StatusCompleted); again.NextID != completed.NextID {
+		t.Errorf("NextID after completing again = %d, want %d", again.NextID, completed.NextID)
+	}
+	if after := len(query(t, s, storage.TodoQuery{}).Todos); after != before {
+		t.Errorf("todo count after completing again = %d, want %d", after, before)
+	}
+
+	// Todos without a rule don't recur
+	plain := mustCreate(t, s, newTodo("plain"))
+	if completed := setStatus(plain, models.StatusCompleted); completed.NextID != 0 {
+		t.Errorf("completing a todo without a rule set NextID to %d", completed.NextID)
+	}
+}
+
+// reviewWorkflow is the workflow of the Workflow test, with two done
+// states and a final one
+var reviewWorkflow = &models.Workflow{
+	Initial: "backlog",
+	States: []models.State{
+		{Name: "backlog", Next: []models.TodoStatus{"in_progress", "cancelled"}},
+		{Name: "in_progress", Next: []models.TodoStatus{"done", "cancelled"}},
+		{Name: "done", Done: true},
+		{Name: "cancelled", Done: true, Next: []models.TodoStatus{"backlog"}},
+	},
+}
+
+func testWorkflow(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	if err := reviewWorkflow.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	moveTo := func(ctx context.Context, id int, status models.TodoStatus) error {
+		todo := mustGet(t, s, id)
+		todo.Status = status
+		return s.Update(ctx, id, todo)
+	}
+	create := func(title string, status models.TodoStatus) *models.Todo {
+		todo := newTodo(title)
+		todo.Status = status
+		return mustCreate(t, s, todo)
+	}
+
+	// Todos follow the transitions, skipping none
+	todo := create("task", "backlog")
+	if err := moveTo(ctx, todo.ID, "done"); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("moving from backlog to done: err = %v, want ErrInvalidTransition", err)
+	}
+	if err := moveTo(ctx, todo.ID, "archived"); !errors.Is(err, models.ErrUnknownStatus) {
+		t.Errorf("moving to an unknown status: err = %v, want ErrUnknownStatus", err)
+	}
+	for _, status := range []models.TodoStatus{"in_progress", "done"} {
+		if err := moveTo(ctx, todo.ID, status); err != nil {
+			t.Fatalf("moving to %s failed: %v", status, err)
+		}
+	}
+	if err := moveTo(ctx, todo.ID, "in_progress"); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("moving out of a final state: err = %v, want ErrInvalidTransition", err)
+	}
+	if err := moveTo(storage.WithForce(ctx), todo.ID, "backlog"); err != nil {
+		t.Errorf("forced move out of a final state failed: %v", err)
+	}
+
+	// Todos in a status the workflow doesn't know can move anywhere
+	legacy := create("legacy", models.StatusPending)
+	if err := moveTo(ctx, legacy.ID, "in_progress"); err != nil {
+		t.Errorf("moving a todo out of an unknown status failed: %v", err)
+	}
+
+	// Every done state closes a todo: it stops blocking others and counts
+	// as completed for its subtasks, which move into the same state
+	parent := create("parent", "backlog")
+	child := create("child", "backlog")
+	child.ParentID = parent.ID
+	if err := s.Update(ctx, child.ID, child); err != nil {
+		t.Fatalf("Update of child failed: %v", err)
+	}
+	blocked := create("blocked", "backlog")
+	blocked.BlockedBy = []int{parent.ID}
+	if err := s.Update(ctx, blocked.ID, blocked); err != nil {
+		t.Fatalf("Update of blocked failed: %v", err)
+	}
+	if err := moveTo(ctx, parent.ID, "cancelled"); err != nil {
+		t.Fatalf("cancelling parent failed: %v", err)
+	}
+	if got := mustGet(t, s, child.ID).Status; got != "cancelled" {
+		t.Errorf("subtask status after cancelling its parent = %s, want cancelled", got)
+	}
+	for _, q := range []storage.TodoQuery{{Open: true}, {Ready: true}} {
+		var ids []int
+		for _, todo := range query(t, s, q).Todos {
+			ids = append(ids, todo.ID)
+		}
+		if slices.Contains(ids, parent.ID) || !slices.Contains(ids, blocked.ID) {
+			t.Errorf("Query(%+v) IDs = %v, want %d but not %d", q, ids, blocked.ID, parent.ID)
+		}
+	}
+}
+
+func testTimeTracking(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	start := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	todo := newTodo("timed")
+	todo.EstimateMinutes = 90
+	mustCreate(t, s, todo)
+	other := mustCreate(t, s, newTodo("untimed"))
+
+	// A timer and a logged entry, each stored with a change of the activity
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		_, err := activity.StartTimer(start, "agent", "first pass")
+		return err
+	}); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		_, err := activity.StartTimer(start, "agent", "")
+		return err
+	}); !errors.Is(err, models.ErrTimerRunning) {
+		t.Errorf("second StartTimer: err = %v, want ErrTimerRunning", err)
+	}
+	activity, err := s.Activity(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if timer := activity.RunningTimer(); timer == nil || !timer.Start.Equal(start) || timer.Note != "first pass" {
+		t.Fatalf("RunningTimer() = %+v, want the stored timer", timer)
+	}
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		if _, err := activity.StopTimer(start.Add(45 * time.Minute)); err != nil {
+			return err
+		}
+		_, err := activity.LogTime(start.AddDate(0, 0, -1), start.AddDate(0, 0, -1).Add(time.Hour), "agent", "before")
+		return err
+	}); err != nil {
+		t.Fatalf("StopTimer and LogTime failed: %v", err)
+	}
+
+	// Tracking time leaves the todo's version alone, so it never conflicts
+	// with an edit of the todo
+	if got := mustGet(t, s, todo.ID); got.Version != todo.Version {
+		t.Errorf("Version = %d after tracking time, want %d", got.Version, todo.Version)
+	}
+	todo.Description = "edited while timed"
+	if err := s.Update(ctx, todo.ID, todo); err != nil {
+		t.Errorf("Update after tracking time failed: %v", err)
+	}
+
+	activity, err = s.Activity(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if len(activity.TimeEntries) != 2 || activity.TimeEntries[0].Note != "before" || activity.RunningTimer() != nil {
+		t.Fatalf("TimeEntries = %+v, want the logged entry then the stopped timer", activity.TimeEntries)
+	}
+	if tracked := activity.TrackedMinutes(); tracked != 105 {
+		t.Errorf("TrackedMinutes() = %d, want 105", tracked)
+	}
+	summary := activity.TimeSummary(todo.EstimateMinutes, start.AddDate(0, 0, 1))
+	if summary.SpentMinutes != 105 || summary.RemainingMinutes == nil || *summary.RemainingMinutes != -15 {
+		t.Errorf("TimeSummary() = %+v, want 105 minutes spent, 15 over the estimate", summary)
+	}
+
+	// Reports only count the time within their period
+	activities, err := s.Activities(ctx)
+	if err != nil || len(activities) != 1 || activities[0].TodoID != todo.ID {
+		t.Fatalf("Activities() = %+v, %v, want the timed todo's only", activities, err)
+	}
+	report := models.BuildTimeReport([]*models.Todo{todo, other}, activities, models.StartOfWeek(start), start.AddDate(0, 0, 7), start)
+	if report.TotalMinutes != 45 || len(report.Todos) != 1 || report.Todos[0].TodoID != todo.ID || report.Todos[0].TrackedMinutes != 105 {
+		t.Errorf("BuildTimeReport() = %+v, want the 45 minutes of this week", report)
+	}
+
+	if !activity.RemoveTimeEntry(activity.TimeEntries[0].ID) || activity.TrackedMinutes() != 45 {
+		t.Errorf("after RemoveTimeEntry TrackedMinutes() = %d, want 45", activity.TrackedMinutes())
+	}
+
+	// Removing the newest entry doesn't free its ID for the next one
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		if !activity.RemoveTimeEntry(2) {
+			return models.ErrTimeEntryNotFound
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("RemoveTimeEntry failed: %v", err)
+	}
+	var logged *models.TimeEntry
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		logged, err = activity.LogTime(start.Add(time.Hour), start.Add(2*time.Hour), "agent", "after")
+		return err
+	}); err != nil || logged.ID != 3 {
+		t.Errorf("LogTime after removing the newest entry = %+v, %v, want ID 3", logged, err)
+	}
+
+	// Trashed todos drop out of the report's activities
+	if err := s.Delete(ctx, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if activities, err := s.Activities(ctx); err != nil || len(activities) != 0 {
+		t.Errorf("Activities() after a delete = %+v, %v, want none", activities, err)
+	}
+}
+
+func testComments(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	at := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	todo := newTodo("discussed")
+	todo.Description = "written by a human"
+	mustCreate(t, s, todo)
+
+	for i, body := range []string{"started", "  halfway  ", "done"} {
+		if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+			_, err := activity.AddComment(at.Add(time.Duration(i)*time.Minute), "agent", body)
+			return err
+		}); err != nil {
+			t.Fatalf("AddComment(%q) failed: %v", body, err)
+		}
+	}
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		_, err := activity.AddComment(at, "agent", " ")
+		return err
+	}); !errors.Is(err, models.ErrEmptyComment) {
+		t.Errorf("AddComment with a blank body: err = %v, want ErrEmptyComment", err)
+	}
+
+	activity, err := s.Activity(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if len(activity.Comments) != 3 || activity.Comments[1].Body != "halfway" || activity.Comments[1].Author != "agent" || !activity.Comments[2].CreatedAt.Equal(at.Add(2*time.Minute)) {
+		t.Fatalf("Comments = %+v, want the three comments in order", activity.Comments)
+	}
+
+	// Comments live apart from the todo, so an edit based on the version
+	// read before them still applies
+	got := mustGet(t, s, todo.ID)
+	if got.Version != todo.Version || got.Description != "written by a human" {
+		t.Errorf("todo = version %d, %q, want version %d untouched", got.Version, got.Description, todo.Version)
+	}
+	todo.Title = "discussed at length"
+	if err := s.Update(ctx, todo.ID, todo); err != nil {
+		t.Errorf("Update after commenting failed: %v", err)
+	}
+	if events, err := s.History(ctx, todo.ID); err != nil || len(events) != 2 {
+		t.Errorf("History() = %d events, %v, want the create and update only", len(events), err)
+	}
+
+	// Edits are marked, deleted comments leave the thread but keep their ID
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		if _, err := activity.EditComment(1, at.Add(time.Hour), "started over"); err != nil {
+			return err
+		}
+		if err := activity.DeleteComment(3, at.Add(time.Hour)); err != nil {
+			return err
+		}
+		_, err := activity.AddComment(at.Add(2*time.Hour), "alice", "thanks")
+		return err
+	}); err != nil {
+		t.Fatalf("editing comments failed: %v", err)
+	}
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(activity *models.Activity) error {
+		return activity.DeleteComment(3, at.Add(time.Hour))
+	}); !errors.Is(err, models.ErrCommentNotFound) {
+		t.Errorf("second DeleteComment: err = %v, want ErrCommentNotFound", err)
+	}
+
+	activity, err = s.Activity(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	thread := activity.Thread()
+	if len(thread) != 3 || thread[0].Body != "started over" || thread[0].EditedAt == nil || thread[2].ID != 4 || thread[2].Author != "alice" {
+		t.Errorf("Thread() = %+v, want the edited, untouched and new comments", thread)
+	}
+
+	// The thread is hidden with the todo in the trash, comes back with it
+	// and goes when it is purged
+	if _, err := s.Activity(ctx, 999); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("Activity of a missing todo: err = %v, want ErrTodoNotFound", err)
+	}
+	if err := s.Delete(ctx, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.ChangeActivity(ctx, todo.ID, func(*models.Activity) error { return nil }); !errors.Is(err, storage.ErrTodoNotFound) {
+		t.Errorf("ChangeActivity of a trashed todo: err = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := s.Restore(ctx, todo.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if activity, err := s.Activity(ctx, todo.ID); err != nil || len(activity.Thread()) != 3 {
+		t.Errorf("Activity after a restore = %+v, %v, want the thread back", activity, err)
+	}
+	if err := s.Delete(ctx, todo.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Purge(ctx, todo.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	next := mustCreate(t, s, newTodo("fresh"))
+	if activity, err := s.Activity(ctx, next.ID); err != nil || len(activity.Comments) != 0 {
+		t.Errorf("Activity of a new todo after a purge = %+v, %v, want it empty", activity, err)
+	}
+}
+
+func testAttachments(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	at := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	todo := mustCreate(t, s, newTodo("with files"))
+	hash := strings.Repeat("ab", 32)
+	first := todo.AddAttachment(models.Attachment{Name: "../../logs/build.log", MediaType: "text/plain; charset=utf-8", Size: 13, SHA256: hash, CreatedAt: at})
+	if first.ID != 1 || first.Name != "build.log" {
+		t.Errorf("AddAttachment() = %+v, want ID 1 named build.log", first)
+	}
+	todo.AddAttachment(models.Attachment{Name: "", MediaType: "image/png", Size: 8, SHA256: hash, CreatedAt: at})
+	if err := s.Update(ctx, todo.ID, todo); err != nil {
+		t.Fatalf("Update with attachments failed: %v", err)
+	}
+
+	got := mustGet(t, s, todo.ID)
+	if len(got.Attachments) != 2 || got.Attachments[1].ID != 2 || got.Attachments[1].Name != "attachment" || got.Attachments[0].SHA256 != hash || !got.Attachments[0].CreatedAt.Equal(at) {
+		t.Fatalf("Attachments = %+v, want both attachments", got.Attachments)
+	}
+	if !got.RemoveAttachment(1) || got.RemoveAttachment(1) {
+		t.Errorf("RemoveAttachment(1) twice, want it removed once")
+	}
+	if _, err := got.Attachment(1); !errors.Is(err, models.ErrAttachmentNotFound) {
+		t.Errorf("Attachment of a removed ID: err = %v, want ErrAttachmentNotFound", err)
+	}
+}
+
+func testChecklist(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+
+	todo := newTodo("release")
+	due := time.Date(2030, time.January, 7, 17, 0, 0, 0, time.UTC)
+	todo.DueAt = &due
+	todo.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly}
+	mustCreate(t, s, todo)
+
+	for _, text := range []string{"tag", "build", "announce"} {
+		if _, err := todo.AddChecklistItem(text, 0); err != nil {
+			t.Fatalf("AddChecklistItem(%q) failed: %v", text, err)
+		}
+	}
+	if _, err := todo.AddChecklistItem("test", 2); err != nil {
+		t.Fatalf("AddChecklistItem at position 2 failed: %v", err)
+	}
+	if _, err := todo.AddChecklistItem("  ", 0); !errors.Is(err, models.ErrEmptyChecklistItem) {
+		t.Errorf("AddChecklistItem with blank text: err = %v, want ErrEmptyChecklistItem", err)
+	}
+	// tag, test, build, announce: move build before test
+	if _, err := todo.MoveChecklistItem(2, 2); err != nil {
+		t.Fatalf("MoveChecklistItem failed: %v", err)
+	}
+	if _, err := todo.CheckChecklistItem(1, true); err != nil {
+		t.Fatalf("CheckChecklistItem failed: %v", err)
+	}
+	if err := todo.RemoveChecklistItem(3); err != nil {
+		t.Fatalf("RemoveChecklistItem failed: %v", err)
+	}
+	if _, err := todo.CheckChecklistItem(3, true); !errors.Is(err, models.ErrChecklistItemNotFound) {
+		t.Errorf("CheckChecklistItem of a removed item: err = %v, want ErrChecklistItemNotFound", err)
+	}
+	if err := s.Update(ctx, todo.ID, todo); err != nil {
+		t.Fatalf("Update with a checklist failed: %v", err)
+	}
+
+	got := mustGet(t, s, todo.ID)
+	var texts []string
+	for _, item := range got.Checklist {
+		texts = append(texts, item.Text)
+	}
+	if strings.Join(texts, ",") != "tag,build,test" || !got.Checklist[0].Done || got.Checklist[1].ID != 2 {
+		t.Fatalf("Checklist = %+v, want tag (done), build, test", got.Checklist)
+	}
+	if progress := got.ChecklistProgress(); progress == nil || progress.Completed != 1 || progress.Total != 3 || progress.Percent != 33 {
+		t.Errorf("ChecklistProgress() = %+v, want 1 of 3", progress)
+	}
+
+	// Removing the newest item doesn't free its ID for the next one
+	if err := got.RemoveChecklistItem(4); err != nil {
+		t.Fatalf("RemoveChecklistItem failed: %v", err)
+	}
+	if err := s.Update(ctx, got.ID, got); err != nil {
+		t.Fatalf("Update without the newest item failed: %v", err)
+	}
+	got = mustGet(t, s, todo.ID)
+	if item, err := got.AddChecklistItem("notes", 0); err != nil || item.ID != 5 {
+		t.Errorf("AddChecklistItem after removing the newest item = %+v, %v, want ID 5", item, err)
+	}
+
+	// The next occurrence starts with the same steps, none done
+	got.Status = models.StatusCompleted
+	if err := s.Update(ctx, got.ID, got); err != nil {
+		t.Fatalf("Update to completed failed: %v", err)
+	}
+	next := mustGet(t, s, mustGet(t, s, todo.ID).NextID)
+	if len(next.Checklist) != 3 || next.Checklist[0].Done || next.ChecklistProgress().Completed != 0 {
+		t.Errorf("next occurrence Checklist = %+v, want the items unchecked", next.Checklist)
+	}
+}
+
+func testCancelledContext(t *testing.T, s storage.TodoStorage) {
+	todo := mustCreate(t, s, newTodo("existing"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Create(ctx, newTodo("cancelled")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.GetByID(ctx, todo.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByID with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.GetAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAll with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.Query(ctx, storage.TodoQuery{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query with cancelled context error = %v, want context.Canceled", err)
+	}
+	if err := s.Delete(ctx, todo.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("Delete with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.ListTrash(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListTrash with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.PurgeTrash(ctx, time.Time{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("PurgeTrash with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.History(ctx, todo.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("History with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.Tags(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Tags with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.RenameTag(ctx, "a", "b"); !errors.Is(err, context.Canceled) {
+		t.Errorf("RenameTag with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.MergeTags(ctx, "a", "b"); !errors.Is(err, context.Canceled) {
+		t.Errorf("MergeTags with cancelled context error = %v, want context.Canceled", err)
+	}
+	if err := s.CreateProject(ctx, &models.Project{Name: "cancelled"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateProject with cancelled context error = %v, want context.Canceled", err)
+	}
+	if _, err := s.ListProjects(ctx, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListProjects with cancelled context error = %v, want context.Canceled", err)
+	}
+
+	all, err := s.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("cancelled calls changed the store: GetAll = %v", ids(all))
+	}
+}
+
+func testConcurrency(t *testing.T, s storage.TodoStorage) {
+	ctx := context.Background()
+	const workers = 8
+	const perWorker = 10
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	created := make(map[int]bool)
+	errs := make(chan error, workers*perWorker*3)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				todo := newTodo(fmt.Sprintf("worker %d todo %d", w, i))
+				if err := s.Create(ctx, todo); err != nil {
+					errs <- fmt.Errorf("Create: %w", err)
+					continue
+				}
+
+				mutex.Lock()
+				duplicate := created[todo.ID]
+				created[todo.ID] = true
+				mutex.Unlock()
+				if duplicate {
+					errs <- fmt.Errorf("duplicate ID %d", todo.ID)
+				}
+
+				got, err := s.GetByID(ctx, todo.ID)
+				if err != nil {
+					errs <- fmt.Errorf("GetByID: %w", err)
+					continue
+				}
+				got.Status = models.StatusCompleted
+				if err := s.Update(ctx, todo.ID, got); err != nil {
+					errs <- fmt.Errorf("Update: %w", err)
+				}
+				if _, err := s.GetAll(ctx); err != nil {
+					errs <- fmt.Errorf("GetAll: %w", err)
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	completed, err := s.GetByStatus(ctx, models.StatusCompleted)
+	if err != nil {
+		t.Fatalf("GetByStatus failed: %v", err)
+	}
+	if len(completed) != workers*perWorker {
+		t.Fatalf("got %d completed todos, want %d", len(completed), workers*perWorker)
+	}
+}
+
+// compareField compares a and b on a sort field only
+func compareField(a, b *models.Todo, field string) int {
+	switch field {
+	case storage.SortByTitle:
+		return strings.Compare(a.Title, b.Title)
+	case storage.SortByStatus:
+		return strings.Compare(string(a.Status), string(b.Status))
+	case storage.SortByCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case storage.SortByUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case storage.SortByPriority:
+		return a.Priority.Rank() - b.Priority.Rank()
+	case storage.SortByDueAt:
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+			return 0
+		case a.DueAt == nil:
+			return 1
+		case b.DueAt == nil:
+			return -1
+		}
+		return a.DueAt.Compare(*b.DueAt)
+	}
+	return 0
+}
+
+// equalInts reports whether a and b hold the same values in the same order
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ids returns the IDs of todos, for failure messages
+func ids(todos []*models.Todo) []int {
+	result := make([]int, len(todos))
+	for i, todo := range todos {
+		result[i] = todo.ID
+	}
+	return result
+}
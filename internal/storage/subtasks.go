@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrParentNotFound = errors.New("parent todo not found")
+	ErrParentCycle    = errors.New("a todo can't be a subtask of itself or of its own subtasks")
+	ErrParentTrashed  = errors.New("parent todo is in the trash")
+)
+
+// NoParent is the TodoQuery.ParentID selecting top-level todos
+const NoParent = -1
+
+// checkParent fails with ErrParentNotFound unless parentID is zero or names
+// a todo outside the trash, and with ErrParentCycle if that todo is the one
+// with the given ID or one of its subtasks. The ID is zero for a todo yet
+// to be created. todos must hold the parent and its ancestors.
+func checkParent(todos map[int]*models.Todo, id, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	parent, exists := todos[parentID]
+	if !exists || parent.DeletedAt != nil {
+		return fmt.Errorf("%w: %d", ErrParentNotFound, parentID)
+	}
+
+	// Live todos only have live ancestors, so walking up ends at a
+	// top-level todo; seen guards against cycles in corrupt data
+	seen := make(map[int]bool)
+	for ancestor := parent; ancestor != nil && !seen[ancestor.ID]; ancestor = todos[ancestor.ParentID] {
+		if ancestor.ID == id {
+			return ErrParentCycle
+		}
+		seen[ancestor.ID] = true
+	}
+	return nil
+}
+
+// checkRestore fails with ErrParentTrashed if the parent of a trashed todo
+// is in the trash too, so that live todos never hang below trashed ones
+func checkRestore(todos map[int]*models.Todo, todo *models.Todo) error {
+	if parent, exists := todos[todo.ParentID]; exists && parent.DeletedAt != nil {
+		return fmt.Errorf("%w: restore todo %d first", ErrParentTrashed, parent.ID)
+	}
+	return nil
+}
+
+// descendants returns the subtasks of the todo with the given ID, their
+// subtasks and so on, ordered by ID. Only subtasks for which follow
+// returns true are returned and searched for subtasks of their own.
+func descendants(todos map[int]*models.Todo, id int, follow func(*models.Todo) bool) []*models.Todo {
+	children := make(map[int][]*models.Todo)
+	for _, todo := range todos {
+		if todo.ParentID != 0 && todo.ParentID != todo.ID {
+			children[todo.ParentID] = append(children[todo.ParentID], todo)
+		}
+	}
+
+	var result []*models.Todo
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		for _, child := range children[parentID] {
+			if seen[child.ID] || !follow(child) {
+				continue
+			}
+			seen[child.ID] = true
+			result = append(result, child)
+			queue = append(queue, child.ID)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func isLive(todo *models.Todo) bool {
+	return todo.DeletedAt == nil
+}
+
+func isTrashed(todo *models.Todo) bool {
+	return todo.DeletedAt != nil
+}
+
+// liveSubtasks returns the subtasks of a todo at any depth outside the
+// trash: the ones trashed along with it
+func liveSubtasks(todos map[int]*models.Todo, id int) []*models.Todo {
+	return descendants(todos, id, isLive)
+}
+
+// trashedSubtasks returns the subtasks of a trashed todo at any depth,
+// which are all in the trash as well: the ones purged along with it
+func trashedSubtasks(todos map[int]*models.Todo, id int) []*models.Todo {
+	return descendants(todos, id, isTrashed)
+}
+
+// restoredSubtasks returns the subtasks that were trashed along with a
+// trashed todo: the ones restored along with it
+func restoredSubtasks(todos map[int]*models.Todo, todo *models.Todo) []*models.Todo {
+	return descendants(todos, todo.ID, func(subtask *models.Todo) bool {
+		return subtask.DeletedAt != nil && subtask.DeletedAt.Equal(*todo.DeletedAt)
+	})
+}
+
+// completedSubtasks returns the subtasks at any depth that an update from
+// before to after completes along with the todo: none unless it completes
+// the todo, and otherwise every one outside the trash that isn't done.
+// They move to the todo's new state whatever transitions their own state
+// allows.
+func completedSubtasks(w *models.Workflow, todos map[int]*models.Todo, before, after *models.Todo) []*models.Todo {
+	if !completes(w, before, after) {
+		return nil
+	}
+
+	var open []*models.Todo
+	for _, subtask := range liveSubtasks(todos, before.ID) {
+		if !w.Done(subtask.Status) {
+			open = append(open, subtask)
+		}
+	}
+	return open
+}
+
+// carriedChange is the state of a todo before and after a change of
+// another todo carried it along
+type carriedChange struct {
+	before, after *models.Todo
+}
+
+// completes reports whether an update from before to after completes the
+// todo: moves it into a done state of w from one that isn't
+func completes(w *models.Workflow, before, after *models.Todo) bool {
+	return !w.Done(before.Status) && w.Done(after.Status)
+}
+
+// completedCopy returns a copy of todo moved to the done state status at
+// the given time
+func completedCopy(todo *models.Todo, status models.TodoStatus, at time.Time) *models.Todo {
+	todoCopy := todo.Clone()
+	todoCopy.Status = status
+	todoCopy.UpdatedAt = at
+	todoCopy.Version++
+	return todoCopy
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// normalizeRetag normalizes the tags of a rename or merge and checks that
+// they differ
+func normalizeRetag(from, to string) (string, string, error) {
+	from, err := models.NormalizeTag(from)
+	if err != nil {
+		return "", "", err
+	}
+	to, err = models.NormalizeTag(to)
+	if err != nil {
+		return "", "", err
+	}
+	if from == to {
+		return "", "", fmt.Errorf("%w: %q", ErrTagExists, to)
+	}
+	return from, to, nil
+}
+
+// planRetag checks a rename, or with merge a merge, of the normalized tag
+// from into to against every todo, trash included, and returns the todos
+// carrying from in ID order
+func planRetag(todos map[int]*models.Todo, from, to string, merge bool) ([]*models.Todo, error) {
+	var targets []*models.Todo
+	for _, todo := range todos {
+		if todo.HasTag(to) && !merge {
+			return nil, fmt.Errorf("%w: %q", ErrTagExists, to)
+		}
+		if todo.HasTag(from) {
+			targets = append(targets, todo)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrTagNotFound, from)
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	return targets, nil
+}
+
+// retaggedCopy returns a copy of todo with the tag from replaced by to,
+// changed at the given time
+func retaggedCopy(todo *models.Todo, from, to string, at time.Time) *models.Todo {
+	todoCopy := todo.Clone()
+	for i, tag := range todoCopy.Tags {
+		if tag == from {
+			todoCopy.Tags[i] = to
+		}
+	}
+	slices.Sort(todoCopy.Tags)
+	todoCopy.Tags = slices.Compact(todoCopy.Tags)
+	todoCopy.UpdatedAt = at
+	todoCopy.Version++
+	return todoCopy
+}
+
+// countTags counts the tags of the todos outside the trash, ordered by tag
+func countTags(todos map[int]*models.Todo) []models.TagCount {
+	counts := make(map[string]int)
+	for _, todo := range todos {
+		if todo.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range todo.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	return result
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// trashedCopy returns a copy of todo moved to the trash at the given time
+func trashedCopy(todo *models.Todo, at time.Time) *models.Todo {
+	todoCopy := todo.Clone()
+	todoCopy.DeletedAt = &at
+	todoCopy.UpdatedAt = at
+	todoCopy.Version++
+	return todoCopy
+}
+
+// restoredCopy returns a copy of todo taken out of the trash at the given
+// time
+func restoredCopy(todo *models.Todo, at time.Time) *models.Todo {
+	todoCopy := todo.Clone()
+	todoCopy.DeletedAt = nil
+	todoCopy.UpdatedAt = at
+	todoCopy.Version++
+	return todoCopy
+}
+
+// inTrashBefore reports whether todo is in the trash and was put there
+// before the cutoff. A zero cutoff matches everything in the trash.
+func inTrashBefore(todo *models.Todo, before time.Time) bool {
+	if todo.DeletedAt == nil {
+		return false
+	}
+	return before.IsZero() || todo.DeletedAt.Before(before)
+}
+
+// PurgeTrashPeriodically permanently deletes todos that have been in the
+// trash for longer than retention, checking every interval until ctx is
+// done. It is meant to run in its own goroutine.
+func PurgeTrashPeriodically(ctx context.Context, s TodoStorage, retention, interval time.Duration) {
+	ctx = WithActor(ctx, "trash-retention")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("storage: failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("storage: purged %d todos from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,963 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// Log operations recorded by WALStorage. Trash and restore entries carry
+// the whole todo like updates; delete entries remove it permanently.
+// Project entries carry the whole project as created or updated, and their
+// ID is the project's. Activity entries carry the whole activity of the
+// todo with their ID as changed; delete entries remove it along with the
+// todo. Batch entries carry the entries of one mutation touching several
+// todos, such as a parent and its subtasks, on a single line, so that a
+// crash leaves either all of them in the log or none.
+const (
+	LogOpCreate        = "create"
+	LogOpUpdate        = "update"
+	LogOpTrash         = "trash"
+	LogOpRestore       = "restore"
+	LogOpDelete        = "delete"
+	LogOpProject       = "project"
+	LogOpDeleteProject = "delete_project"
+	LogOpActivity      = "activity"
+	LogOpBatch         = "batch"
+)
+
+// defaultCompactEvery is the number of log entries after which WALStorage
+// folds the log into a snapshot
+const defaultCompactEvery = 1000
+
+// LogEntry is a single mutation recorded in the write-ahead log
+type LogEntry struct {
+	Seq      uint64           `json:"seq"`
+	Op       string           `json:"op"`
+	ID       int              `json:"id"`
+	Todo     *models.Todo     `json:"todo,omitempty"`
+	Project  *models.Project  `json:"project,omitempty"`
+	Activity *models.Activity `json:"activity,omitempty"`
+	Entries  []LogEntry       `json:"entries,omitempty"` // of a batch, without seq and time of their own
+	At       time.Time        `json:"at,omitzero"`
+}
+
+// walSnapshot is the compacted state written next to the log
+type walSnapshot struct {
+	Seq        uint64                   `json:"seq"`
+	NextID     int                      `json:"next_id"`
+	Todos      map[int]*models.Todo     `json:"todos"`
+	Projects   map[int]*models.Project  `json:"projects,omitempty"`
+	Activities map[int]*models.Activity `json:"activities,omitempty"`
+}
+
+// WALOptions configures a WALStorage
+type WALOptions struct {
+	// CompactEvery is the number of log entries after which the log is
+	// folded into the snapshot. Zero selects the default.
+	CompactEvery int
+
+	// Archive keeps each compacted log segment as <log>.<first>-<last>
+	// instead of discarding it, preserving the full mutation history.
+	Archive bool
+}
+
+// WALStorage implements BasicStorage as an append-only log of JSON lines,
+// periodically compacted into a snapshot. Each mutation costs one appended
+// and fsynced line, and the state is rebuilt on startup by loading the
+// snapshot and replaying the log. It is meant for use by a single process.
+type WALStorage struct {
+	logPath      string
+	snapshotPath string
+	workflow     *models.Workflow
+	history      historyFile
+	options      WALOptions
+
+	mutex      sync.RWMutex
+	logFile    *os.File
+	todos      map[int]*models.Todo
+	nextID     int
+	projects   map[int]*models.Project
+	activities map[int]*models.Activity
+	seq        uint64
+	snapshotAt uint64
+	logEntries int
+}
+
+// NewWALStorage opens the log at logPath, replaying the snapshot and log to
+// rebuild the current state. Its todos follow workflow, or
+// models.DefaultWorkflow if it is nil.
+func NewWALStorage(logPath string, workflow *models.Workflow, options WALOptions) (*WALStorage, error) {
+	if options.CompactEvery <= 0 {
+		options.CompactEvery = defaultCompactEvery
+	}
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	w := &WALStorage{
+		logPath:      logPath,
+		snapshotPath: logPath + ".snapshot",
+		workflow:     models.WorkflowOrDefault(workflow),
+		history:      historyFile{path: logPath + ".history"},
+		options:      options,
+		todos:        make(map[int]*models.Todo),
+		projects:     make(map[int]*models.Project),
+		activities:   make(map[int]*models.Activity),
+	}
+
+	if err := w.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := w.replayLog(); err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	w.logFile = logFile
+	if next := nextTodoID(w.todos); next > w.nextID {
+		w.nextID = next
+	}
+
+	return w, nil
+}
+
+// Close closes the log file
+func (w *WALStorage) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.logFile.Close()
+}
+
+// loadSnapshot reads the compacted state, if any
+func (w *WALStorage) loadSnapshot() error {
+	data, err := os.ReadFile(w.snapshotPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot walSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrCorruptFile, w.snapshotPath, err)
+	}
+
+	if snapshot.Todos != nil {
+		w.todos = snapshot.Todos
+	}
+	if snapshot.Projects != nil {
+		w.projects = snapshot.Projects
+	}
+	if snapshot.Activities != nil {
+		w.activities = snapshot.Activities
+	}
+	w.nextID = snapshot.NextID
+	w.seq = snapshot.Seq
+	w.snapshotAt = snapshot.Seq
+	return nil
+}
+
+// replayLog applies the log entries newer than the snapshot. A torn last
+// line left by a crash during an append is cut off; damage anywhere else is
+// reported as ErrCorruptFile.
+func (w *WALStorage) replayLog() error {
+	file, err := os.OpenFile(w.logPath, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("failed to read log: %w", readErr)
+		}
+
+		complete := readErr == nil
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry LogEntry
+			if err := json.Unmarshal(line, &entry); err != nil || !complete {
+				if _, peekErr := reader.Peek(1); complete && peekErr == nil {
+					return fmt.Errorf("%w: %s at offset %d: invalid entry", ErrCorruptFile, w.logPath, offset)
+				}
+				// Torn final entry from an interrupted append
+				if err := file.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate torn log entry: %w", err)
+				}
+				break
+			}
+
+			if !entry.complete() {
+				return fmt.Errorf("%w: %s at offset %d: %s entry without its record", ErrCorruptFile, w.logPath, offset, entry.Op)
+			}
+
+			if entry.Seq > w.seq {
+				w.apply(entry)
+				w.seq = entry.Seq
+			}
+			w.logEntries++
+		}
+
+		offset += int64(len(line))
+		if !complete {
+			break
+		}
+	}
+
+	return nil
+}
+
+// complete reports whether the entry carries the todo, project or
+// activity its operation needs, or for a batch, the entries it groups
+func (e *LogEntry) complete() bool {
+	switch e.Op {
+	case LogOpBatch:
+		if len(e.Entries) == 0 {
+			return false
+		}
+		for i := range e.Entries {
+			if e.Entries[i].Op == LogOpBatch || !e.Entries[i].complete() {
+				return false
+			}
+		}
+	case LogOpCreate, LogOpUpdate, LogOpTrash, LogOpRestore:
+		return e.Todo != nil
+	case LogOpProject:
+		return e.Project != nil
+	case LogOpActivity:
+		return e.Activity != nil
+	}
+	return true
+}
+
+// apply updates the in-memory state with a log entry. Todo IDs are never
+// reused, even after the todo holding the highest one is deleted.
+func (w *WALStorage) apply(entry LogEntry) {
+	switch entry.Op {
+	case LogOpBatch:
+		for _, batched := range entry.Entries {
+			w.apply(batched)
+		}
+		return
+	case LogOpProject:
+		w.projects[entry.ID] = entry.Project.Clone()
+		return
+	case LogOpDeleteProject:
+		delete(w.projects, entry.ID)
+		return
+	case LogOpActivity:
+		w.activities[entry.ID] = entry.Activity.Clone()
+		return
+	}
+
+	if entry.ID >= w.nextID {
+		w.nextID = entry.ID + 1
+	}
+
+	switch entry.Op {
+	case LogOpCreate, LogOpUpdate, LogOpTrash, LogOpRestore:
+		todoCopy := entry.Todo.Clone()
+		w.todos[entry.ID] = todoCopy
+	case LogOpDelete:
+		delete(w.todos, entry.ID)
+		delete(w.activities, entry.ID)
+	}
+}
+
+// appendEntry durably writes an entry to the log and applies it. Callers
+// must hold the write lock.
+func (w *WALStorage) appendEntry(op string, id int, todo *models.Todo) error {
+	return w.write(LogEntry{Op: op, ID: id, Todo: todo})
+}
+
+// appendBatch durably writes the entries of one mutation to the log as a
+// single batch entry and applies them. A lone entry is written as is.
+// Callers must hold the write lock.
+func (w *WALStorage) appendBatch(entries []LogEntry) error {
+	switch len(entries) {
+	case 0:
+		return nil
+	case 1:
+		return w.write(entries[0])
+	}
+	return w.write(LogEntry{Op: LogOpBatch, ID: entries[0].ID, Entries: entries})
+}
+
+// appendProjectEntry durably writes a project entry to the log and applies
+// it. Callers must hold the write lock.
+func (w *WALStorage) appendProjectEntry(op string, id int, project *models.Project) error {
+	return w.write(LogEntry{Op: op, ID: id, Project: project})
+}
+
+// write numbers, timestamps and appends an entry, then applies it
+func (w *WALStorage) write(entry LogEntry) error {
+	entry.Seq = w.seq + 1
+	entry.At = time.Now()
+
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := w.logFile.Write(data); err != nil {
+		return fmt.Errorf("failed to append to log: %w", err)
+	}
+	if err := w.logFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+
+	w.apply(entry)
+	w.seq = entry.Seq
+	w.logEntries++
+
+	// The mutation is durable at this point, so a failed compaction is
+	// only logged and retried after the next append
+	if w.logEntries >= w.options.CompactEvery {
+		if err := w.compact(); err != nil {
+			log.Printf("storage: failed to compact %s: %v", w.logPath, err)
+		}
+	}
+	return nil
+}
+
+// Compact folds the log into the snapshot and starts a new log
+func (w *WALStorage) Compact() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.compact()
+}
+
+// compact writes the snapshot and then clears the log. A crash in between
+// is harmless because replay skips entries already in the snapshot.
+// Callers must hold the write lock.
+func (w *WALStorage) compact() error {
+	data, err := json.Marshal(walSnapshot{Seq: w.seq, NextID: w.nextID, Todos: w.todos, Projects: w.projects, Activities: w.activities})
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := writeFileAtomic(w.snapshotPath, data, 0644); err != nil {
+		return err
+	}
+
+	var archivePath string
+	if w.options.Archive {
+		archivePath = fmt.Sprintf("%s.%d-%d", w.logPath, w.snapshotAt+1, w.seq)
+		if err := os.Rename(w.logPath, archivePath); err != nil {
+			return fmt.Errorf("failed to archive log: %w", err)
+		}
+	}
+
+	// Open the new log before giving up the old one so a failure leaves
+	// appends going to the live log
+	logFile, err := os.OpenFile(w.logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		if archivePath != "" {
+			os.Rename(archivePath, w.logPath)
+		}
+		return fmt.Errorf("failed to reopen log: %w", err)
+	}
+
+	w.logFile.Close()
+	w.logFile = logFile
+	w.snapshotAt = w.seq
+	w.logEntries = 0
+
+	return nil
+}
+
+// Replay calls fn for every entry in the current log, oldest first. The
+// entries of a batch are passed one by one, with the batch's seq and time.
+// With archiving enabled, older history is in the archived segments.
+func (w *WALStorage) Replay(fn func(LogEntry) error) error {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	file, err := os.Open(w.logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrCorruptFile, w.logPath, err)
+		}
+		if entry.Op != LogOpBatch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, batched := range entry.Entries {
+			batched.Seq, batched.At = entry.Seq, entry.At
+			if err := fn(batched); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read log: %w", err)
+	}
+
+	return nil
+}
+
+// Create creates a new todo item
+func (w *WALStorage) Create(todo *models.Todo) error {
+	return w.createAs("", todo)
+}
+
+func (w *WALStorage) createAs(actor string, todo *models.Todo) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := checkProjectRef(w.projects, todo.ProjectID); err != nil {
+		return err
+	}
+	if err := checkParent(w.todos, 0, todo.ParentID); err != nil {
+		return err
+	}
+	if err := checkBlockers(w.todos, 0, todo.BlockedBy); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored := *todo
+	stored.ID = w.nextID
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	if err := w.appendEntry(LogOpCreate, stored.ID, &stored); err != nil {
+		return err
+	}
+	w.history.record(newTodoEvent(models.EventCreated, actor, nil, &stored))
+
+	*todo = stored
+	return nil
+}
+
+// GetByID retrieves a todo by its ID
+func (w *WALStorage) GetByID(id int) (*models.Todo, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt != nil {
+		return nil, ErrTodoNotFound
+	}
+
+	// Return a copy to avoid race conditions
+	todoCopy := todo.Clone()
+	return todoCopy, nil
+}
+
+// GetAll retrieves all todos
+func (w *WALStorage) GetAll() ([]*models.Todo, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	result := make([]*models.Todo, 0, len(w.todos))
+	for _, todo := range w.todos {
+		if todo.DeletedAt != nil {
+			continue
+		}
+		// Add a copy to avoid race conditions
+		todoCopy := todo.Clone()
+		result = append(result, todoCopy)
+	}
+
+	return result, nil
+}
+
+// Update updates an existing todo
+func (w *WALStorage) Update(id int, updatedTodo *models.Todo) error {
+	return w.updateAs("", id, updatedTodo, false)
+}
+
+func (w *WALStorage) updateAs(actor string, id int, updatedTodo *models.Todo, force bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt != nil {
+		return ErrTodoNotFound
+	}
+	if updatedTodo.ProjectID != todo.ProjectID {
+		if err := checkProjectRef(w.projects, updatedTodo.ProjectID); err != nil {
+			return err
+		}
+	}
+	if updatedTodo.ParentID != todo.ParentID {
+		if err := checkParent(w.todos, id, updatedTodo.ParentID); err != nil {
+			return err
+		}
+	}
+	if !slices.Equal(updatedTodo.BlockedBy, todo.BlockedBy) {
+		if err := checkBlockers(w.todos, id, updatedTodo.BlockedBy); err != nil {
+			return err
+		}
+	}
+	if err := checkTransition(w.workflow, todo, updatedTodo, force); err != nil {
+		return err
+	}
+	if err := checkCompletion(w.workflow, w.todos, todo, updatedTodo, force); err != nil {
+		return err
+	}
+
+	stored, err := prepareUpdate(todo, updatedTodo)
+	if err != nil {
+		return err
+	}
+
+	subtasks := completedSubtasks(w.workflow, w.todos, todo, stored)
+	next := nextOccurrence(w.workflow, todo, stored)
+	if next != nil {
+		linkOccurrence(stored, next, w.nextID)
+	}
+	entries := []LogEntry{{Op: LogOpUpdate, ID: id, Todo: stored}}
+	events := []*models.TodoEvent{newTodoEvent(models.EventUpdated, actor, todo, stored)}
+	for _, subtask := range subtasks {
+		completed := completedCopy(subtask, stored.Status, stored.UpdatedAt)
+		entries = append(entries, LogEntry{Op: LogOpUpdate, ID: subtask.ID, Todo: completed})
+		events = append(events, newTodoEvent(models.EventUpdated, actor, subtask, completed))
+	}
+	if next != nil {
+		entries = append(entries, LogEntry{Op: LogOpCreate, ID: next.ID, Todo: next})
+		events = append(events, newTodoEvent(models.EventCreated, actor, nil, next))
+	}
+
+	if err := w.appendBatch(entries); err != nil {
+		return err
+	}
+	w.recordAll(events)
+	*updatedTodo = *stored
+	return nil
+}
+
+// Delete moves a todo to the trash
+func (w *WALStorage) Delete(id int) error {
+	return w.deleteAs("", id)
+}
+
+func (w *WALStorage) deleteAs(actor string, id int) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt != nil {
+		return ErrTodoNotFound
+	}
+
+	now := time.Now()
+	var entries []LogEntry
+	var events []*models.TodoEvent
+	for _, subtask := range append([]*models.Todo{todo}, liveSubtasks(w.todos, id)...) {
+		trashed := trashedCopy(subtask, now)
+		entries = append(entries, LogEntry{Op: LogOpTrash, ID: subtask.ID, Todo: trashed})
+		events = append(events, newTodoEvent(models.EventDeleted, actor, subtask, trashed))
+	}
+
+	if err := w.appendBatch(entries); err != nil {
+		return err
+	}
+	w.recordAll(events)
+	return nil
+}
+
+// GetByStatus retrieves todos by status
+func (w *WALStorage) GetByStatus(status models.TodoStatus) ([]*models.Todo, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var result []*models.Todo
+	for _, todo := range w.todos {
+		if todo.Status == status && todo.DeletedAt == nil {
+			// Add a copy to avoid race conditions
+			todoCopy := todo.Clone()
+			result = append(result, todoCopy)
+		}
+	}
+
+	return result, nil
+}
+
+// Query retrieves one page of the todos selected by the query
+func (w *WALStorage) Query(query TodoQuery) (*TodoPage, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	page, err := ApplyQuery(w.workflow, queryCandidates(w.workflow, w.todos, w.projects, query), query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return copies to avoid race conditions
+	for i, todo := range page.Todos {
+		todoCopy := todo.Clone()
+		page.Todos[i] = todoCopy
+	}
+	return page, nil
+}
+
+// ListTrash retrieves the todos in the trash
+func (w *WALStorage) ListTrash() ([]*models.Todo, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	result := make([]*models.Todo, 0)
+	for _, todo := range w.todos {
+		if todo.DeletedAt != nil {
+			// Add a copy to avoid race conditions
+			todoCopy := todo.Clone()
+			result = append(result, todoCopy)
+		}
+	}
+
+	return result, nil
+}
+
+// Restore moves a todo out of the trash
+func (w *WALStorage) Restore(id int) (*models.Todo, error) {
+	return w.restoreAs("", id)
+}
+
+func (w *WALStorage) restoreAs(actor string, id int) (*models.Todo, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt == nil {
+		return nil, ErrTodoNotFound
+	}
+	if err := checkRestore(w.todos, todo); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	subtasks := restoredSubtasks(w.todos, todo)
+	restored := restoredCopy(todo, now)
+	entries := []LogEntry{{Op: LogOpRestore, ID: id, Todo: restored}}
+	events := []*models.TodoEvent{newTodoEvent(models.EventRestored, actor, todo, restored)}
+	for _, subtask := range subtasks {
+		restoredSubtask := restoredCopy(subtask, now)
+		entries = append(entries, LogEntry{Op: LogOpRestore, ID: subtask.ID, Todo: restoredSubtask})
+		events = append(events, newTodoEvent(models.EventRestored, actor, subtask, restoredSubtask))
+	}
+
+	if err := w.appendBatch(entries); err != nil {
+		return nil, err
+	}
+	w.recordAll(events)
+	return restored, nil
+}
+
+// Purge permanently deletes a todo from the trash
+func (w *WALStorage) Purge(id int) error {
+	return w.purgeAs("", id)
+}
+
+func (w *WALStorage) purgeAs(actor string, id int) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt == nil {
+		return ErrTodoNotFound
+	}
+
+	return w.purge(actor, append([]*models.Todo{todo}, trashedSubtasks(w.todos, id)...))
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff, or
+// the whole trash if the cutoff is zero
+func (w *WALStorage) PurgeTrash(before time.Time) (int, error) {
+	return w.purgeTrashAs("", before)
+}
+
+func (w *WALStorage) purgeTrashAs(actor string, before time.Time) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var trashed []*models.Todo
+	for _, todo := range w.todos {
+		if inTrashBefore(todo, before) {
+			trashed = append(trashed, todo)
+		}
+	}
+
+	if err := w.purge(actor, trashed); err != nil {
+		return 0, err
+	}
+	return len(trashed), nil
+}
+
+// purge permanently deletes the todos in one batch, which also removes
+// them from the blockers of the remaining ones
+func (w *WALStorage) purge(actor string, purged []*models.Todo) error {
+	var entries []LogEntry
+	var events []*models.TodoEvent
+	for _, gone := range purged {
+		entries = append(entries, LogEntry{Op: LogOpDelete, ID: gone.ID})
+		events = append(events, newTodoEvent(models.EventPurged, actor, gone, nil))
+	}
+	for _, change := range unblockedTodos(w.todos, purged, time.Now()) {
+		entries = append(entries, LogEntry{Op: LogOpUpdate, ID: change.after.ID, Todo: change.after})
+		events = append(events, newTodoEvent(models.EventUpdated, actor, change.before, change.after))
+	}
+
+	if err := w.appendBatch(entries); err != nil {
+		return err
+	}
+	w.recordAll(events)
+	return nil
+}
+
+// recordAll adds the events of a mutation to the history
+func (w *WALStorage) recordAll(events []*models.TodoEvent) {
+	for _, event := range events {
+		w.history.record(event)
+	}
+}
+
+// History retrieves the changes made to a todo, oldest first
+func (w *WALStorage) History(id int) ([]*models.TodoEvent, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	events, err := w.history.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		if _, exists := w.todos[id]; !exists {
+			return nil, ErrTodoNotFound
+		}
+		events = make([]*models.TodoEvent, 0)
+	}
+
+	return events, nil
+}
+
+// Tags lists the tags of the todos outside the trash with their counts
+func (w *WALStorage) Tags() ([]models.TagCount, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return countTags(w.todos), nil
+}
+
+// RenameTag renames a tag on every todo carrying it
+func (w *WALStorage) RenameTag(from, to string) (int, error) {
+	return w.retagAs("", from, to, false)
+}
+
+// MergeTags folds the tag from into an existing tag
+func (w *WALStorage) MergeTags(from, into string) (int, error) {
+	return w.retagAs("", from, into, true)
+}
+
+func (w *WALStorage) retagAs(actor, from, to string, merge bool) (int, error) {
+	from, to, err := normalizeRetag(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	targets, err := planRetag(w.todos, from, to, merge)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var entries []LogEntry
+	var events []*models.TodoEvent
+	for _, todo := range targets {
+		retagged := retaggedCopy(todo, from, to, now)
+		entries = append(entries, LogEntry{Op: LogOpUpdate, ID: todo.ID, Todo: retagged})
+		events = append(events, newTodoEvent(models.EventUpdated, actor, todo, retagged))
+	}
+
+	if err := w.appendBatch(entries); err != nil {
+		return 0, err
+	}
+	w.recordAll(events)
+	return len(targets), nil
+}
+
+// Subtasks retrieves the subtasks of a todo at any depth
+func (w *WALStorage) Subtasks(id int) ([]*models.Todo, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	todo, exists := w.todos[id]
+	if !exists || todo.DeletedAt != nil {
+		return nil, ErrTodoNotFound
+	}
+
+	result := make([]*models.Todo, 0)
+	for _, subtask := range liveSubtasks(w.todos, id) {
+		// Add a copy to avoid race conditions
+		result = append(result, subtask.Clone())
+	}
+
+	return result, nil
+}
+
+// CreateProject creates a new project
+func (w *WALStorage) CreateProject(project *models.Project) error {
+	if err := normalizeProject(project); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := checkProjectName(w.projects, 0, project.Name); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored := *project
+	stored.ID = nextProjectID(w.projects)
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	if err := w.appendProjectEntry(LogOpProject, stored.ID, &stored); err != nil {
+		return err
+	}
+
+	*project = stored
+	return nil
+}
+
+// GetProject retrieves a project by its ID
+func (w *WALStorage) GetProject(id int) (*models.Project, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	project, exists := w.projects[id]
+	if !exists {
+		return nil, ErrProjectNotFound
+	}
+	return project.Clone(), nil
+}
+
+// ListProjects retrieves the projects ordered by ID
+func (w *WALStorage) ListProjects(includeArchived bool) ([]*models.Project, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return listProjects(w.projects, includeArchived), nil
+}
+
+// UpdateProject updates an existing project
+func (w *WALStorage) UpdateProject(id int, updatedProject *models.Project) error {
+	if err := normalizeProject(updatedProject); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	project, exists := w.projects[id]
+	if !exists {
+		return ErrProjectNotFound
+	}
+	if err := checkProjectName(w.projects, id, updatedProject.Name); err != nil {
+		return err
+	}
+	if err := prepareProjectUpdate(project, updatedProject); err != nil {
+		return err
+	}
+
+	return w.appendProjectEntry(LogOpProject, id, updatedProject)
+}
+
+// DeleteProject permanently deletes a project no todo belongs to
+func (w *WALStorage) DeleteProject(id int) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, exists := w.projects[id]; !exists {
+		return ErrProjectNotFound
+	}
+	if projectInUse(w.todos, id) {
+		return ErrProjectNotEmpty
+	}
+
+	return w.appendProjectEntry(LogOpDeleteProject, id, nil)
+}
+
+// Activity retrieves the activity of a todo outside the trash
+func (w *WALStorage) Activity(id int) (*models.Activity, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if todo, exists := w.todos[id]; !exists || todo.DeletedAt != nil {
+		return nil, ErrTodoNotFound
+	}
+	return activityOf(w.activities, id), nil
+}
+
+// ChangeActivity applies change to the activity of a todo outside the
+// trash, logging the result as an activity entry
+func (w *WALStorage) ChangeActivity(id int, change func(*models.Activity) error) (*models.Activity, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if todo, exists := w.todos[id]; !exists || todo.DeletedAt != nil {
+		return nil, ErrTodoNotFound
+	}
+
+	activity := activityOf(w.activities, id)
+	if err := change(activity); err != nil {
+		return nil, err
+	}
+	if err := w.write(LogEntry{Op: LogOpActivity, ID: id, Activity: activity}); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// Activities retrieves the activity stored for the todos outside the trash
+func (w *WALStorage) Activities() ([]*models.Activity, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return listActivities(w.activities, w.todos), nil
+}
@@ -0,0 +1,112 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+	"github.com/shghadge/todo_mcp/internal/storage"
+	"github.com/shghadge/todo_mcp/internal/storage/storagetest"
+)
+
+func TestWALStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, workflow *models.Workflow) storage.TodoStorage {
+		// Compact often so the suite also exercises snapshots
+		s, err := storage.NewWALStorage(filepath.Join(t.TempDir(), "todos.log"), workflow, storage.WALOptions{CompactEvery: 7})
+		if err != nil {
+			t.Fatalf("NewWALStorage failed: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return storage.WithContext(s)
+	})
+}
+
+func TestWALStorageEntryWithoutRecord(t *testing.T) {
+	for _, entry := range []string{
+		`{"seq":2,"op":"update","id":1}`,
+		`{"seq":2,"op":"project","id":1}`,
+		`{"seq":2,"op":"activity","id":1}`,
+	} {
+		path := filepath.Join(t.TempDir(), "todos.log")
+		log := `{"seq":1,"op":"create","id":1,"todo":{"id":1,"title":"Write report"}}` + "\n" + entry + "\n"
+		if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+
+		if _, err := storage.NewWALStorage(path, nil, storage.WALOptions{}); !errors.Is(err, storage.ErrCorruptFile) {
+			t.Errorf("NewWALStorage with %s: err = %v, want ErrCorruptFile", entry, err)
+		}
+	}
+}
+
+func TestWALStorageTornBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.log")
+	s, err := storage.NewWALStorage(path, nil, storage.WALOptions{})
+	if err != nil {
+		t.Fatalf("NewWALStorage failed: %v", err)
+	}
+	parent := &models.Todo{Title: "Plan trip"}
+	if err := s.Create(parent); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for _, title := range []string{"Book flights", "Book hotel"} {
+		if err := s.Create(&models.Todo{Title: title, ParentID: parent.ID}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if err := s.Delete(parent.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	s.Close()
+
+	// Cut the log halfway into the batch trashing the parent and its
+	// subtasks, as a crash during the append would
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if err := os.Truncate(path, before.Size()+(after.Size()-before.Size())/2); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	s, err = storage.NewWALStorage(path, nil, storage.WALOptions{})
+	if err != nil {
+		t.Fatalf("NewWALStorage after a torn batch failed: %v", err)
+	}
+
+	todos, err := s.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(todos) != 3 {
+		t.Errorf("GetAll() returned %d todos, want all 3 back from the torn batch", len(todos))
+	}
+	trash, err := s.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(trash) != 0 {
+		t.Errorf("ListTrash() returned %d todos, want none", len(trash))
+	}
+
+	// The torn batch is gone, so the next mutation appends after the last
+	// whole entry
+	if err := s.Delete(parent.ID); err != nil {
+		t.Fatalf("Delete after a torn batch failed: %v", err)
+	}
+	s.Close()
+	s, err = storage.NewWALStorage(path, nil, storage.WALOptions{})
+	if err != nil {
+		t.Fatalf("NewWALStorage failed: %v", err)
+	}
+	defer s.Close()
+	if trash, err := s.ListTrash(); err != nil || len(trash) != 3 {
+		t.Errorf("ListTrash() = %d todos, %v, want 3", len(trash), err)
+	}
+}
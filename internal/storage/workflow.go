@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/shghadge/todo_mcp/internal/models"
+)
+
+// checkTransition fails with models.ErrUnknownStatus unless the update
+// from before to after leaves the todo in a state of w, and with
+// models.ErrInvalidTransition unless w lets the todo move there or the
+// update is forced
+func checkTransition(w *models.Workflow, before, after *models.Todo, force bool) error {
+	err := w.CheckTransition(before.Status, after.Status)
+	if force && errors.Is(err, models.ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}